@@ -0,0 +1,33 @@
+package main
+
+import (
+	"backendGoAuth/internal/config"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+const configUsage = `Usage: goAuth [flags] config <command>
+
+Commands:
+  print    write the effective configuration as YAML, with secrets masked,
+           then report whatever makes it invalid
+`
+
+// runConfigCommand runs a configuration command and returns the process exit code.
+func runConfigCommand(cfg *config.Config, args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprint(os.Stderr, configUsage)
+		return 2
+	}
+
+	if err := cfg.Print(os.Stdout); err != nil {
+		slog.Error("Error printing configuration", "error", err)
+		return 1
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid configuration:", err)
+		return 1
+	}
+	return 0
+}
@@ -1,122 +1,106 @@
 package main
 
 import (
-	"backendGoAuth/internal/controllers"
+	"backendGoAuth/internal/app"
+	"backendGoAuth/internal/config"
 	"backendGoAuth/internal/database"
-	"backendGoAuth/internal/metrics"
-	"backendGoAuth/internal/middlewares"
-	"backendGoAuth/internal/repositories"
-	"backendGoAuth/internal/services"
-	"backendGoAuth/internal/utils"
+	"backendGoAuth/internal/logging"
+	"backendGoAuth/internal/tracing"
+	"context"
 	"fmt"
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
-	"github.com/prometheus/client_golang/prometheus"
 	"log"
+	"log/slog"
 	"os"
+	"time"
 )
 
 func main() {
-	// Load environment variables from .env file
-	if err := godotenv.Load(".env"); err != nil {
-		log.Println("Error loading .env file:", err)
+	// Load the configuration from defaults, the config file, the environment and flags
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Println("Error loading configuration:", err)
+		os.Exit(2)
+	}
+	// The configuration is printed even when it is invalid, which helps finding out why
+	if len(args) > 0 && args[0] == "config" {
+		os.Exit(runConfigCommand(cfg, args[1:]))
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Println("Invalid configuration:", err)
+		os.Exit(2)
+	}
+
+	// Log structured records through slog, which the standard log package writes to as well,
+	// with secrets redacted
+	logger, err := newLogger(cfg)
+	if err != nil {
+		log.Println("Error configuring logging:", err)
+		return
+	}
+	slog.SetDefault(logger)
+
+	// Run a maintenance command instead of the server when one is given
+	if len(args) > 0 {
+		os.Exit(runCommand(cfg, args))
 	}
 
 	// Connect to the database
-	if err := database.ConnectDB(); err != nil {
-		log.Println("Error connecting to the database:", err)
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		slog.Error("Error connecting to the database", "error", err)
 		return
 	}
 
 	defer func() {
-		if err := database.GetDB().Close(); err != nil {
-			log.Println("Error closing the database connection:", err)
+		if err := db.Close(); err != nil {
+			slog.Error("Error closing the database connection", "error", err)
 		}
 	}()
 
-	// Initialize Prometheus metrics registry
-	reg := prometheus.NewRegistry()
-
-	// Register Prometheus metrics
-	metrics.RegisterMetrics(reg)
-
-	// Create Gin router
-	router := setupRouter()
-
-	// Start HTTP server
-	startServer(router)
-}
-
-// setupRouter initializes and configures the Gin router
-func setupRouter() *gin.Engine {
-	router := gin.Default()
-
-	config := cors.Config{
-		AllowOrigins:     []string{"http://localhost:5173"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Browser", "X-Device"},
-		AllowCredentials: true,
+	// Export traces when an exporter is configured, flushing the last spans once the servers have stopped
+	shutdownTracing, err := tracing.Setup(context.Background(), "goauth", cfg.Tracing.Exporter)
+	if err != nil {
+		slog.Error("Error setting up tracing", "error", err)
+		return
 	}
-
-	router.Use(cors.New(config))
-
-	// Apply middleware to track request duration
-	router.Use(metrics.InstrumentHandler())
-
-	// Get the database instance
-	db := database.GetDB()
-
-	// Instantiate repositories and services
-	sessionRepo := repositories.NewSessionRepository(db)
-	sessionService := services.NewSessionService(sessionRepo)
-	userRepo := repositories.NewUserRepository(db)
-	authService := services.NewAuthService(userRepo, sessionService)
-
-	// Initialize the session service in the utils package
-	utils.SetSessionService(sessionRepo)
-
-	// Initialize JWT middleware with the secret and JWT service
-	jwtMiddleware := middlewares.NewJWTMiddleware(os.Getenv("JWT_SECRET"))
-
-	// Instantiate controllers
-	authController := controllers.NewAuthController(authService, sessionService)
-	adminController := controllers.NewAdminController(userRepo)
-
-	// Define routes
-	api := router.Group("/api")
-	{
-		api.POST("/login", authController.Login)
-		api.POST("/register", authController.Register)
-
-		authGroup := api.Group("/auth", jwtMiddleware.MiddlewareFunc()) // Apply JWT middleware here
-		{
-			authGroup.POST("/logout", authController.RevokeCurrentSession)
-			authGroup.POST("/revokeSession", authController.RevokeSession)
-			authGroup.GET("/activeSessions", authController.GetActiveSessions)
-			authGroup.GET("/secure", authController.SecureEndpoint)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("Error flushing traces", "error", err)
 		}
+	}()
 
-		adminGroup := api.Group("/admin", jwtMiddleware.MiddlewareFunc()) // Apply JWT middleware here
-		{
-			adminGroup.GET("/users", adminController.GetAllUsers)
-		}
+	// Build the app on Postgres and start the background workers it depends on
+	a, err := app.New(cfg, db, app.NewPostgresRepositories(db), logger)
+	if err != nil {
+		slog.Error("Error building the app", "error", err)
+		return
 	}
+	a.Start()
 
-	// Register Prometheus metrics endpoint
-	router.GET("/metrics", metrics.MetricsHandler())
-
-	return router
+	// Serve the API, and metrics, pprof and health checks on their own listener away from API
+	// traffic, until the process is asked to stop
+	runServers(cfg.Server, newHTTPServer(cfg, a.Router), newAdminServer(cfg, a.Registry, a.Health), a.Health, a)
 }
 
-// startServer starts the HTTP server
-func startServer(router *gin.Engine) {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080" // Default port if not specified
+// runCommand runs a command-line subcommand and returns the process exit code.
+func runCommand(cfg *config.Config, args []string) int {
+	switch args[0] {
+	case "audit":
+		return runAuditCommand(cfg, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\nUsage: goAuth [flags] [audit <command> | config print]\n", args[0])
+		return 2
 	}
-	fmt.Printf("Server is running on port %s...\n", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Println("Error starting the server:", err)
+}
+
+// newLogger creates the logger configured by cfg, scrubbing its secrets wherever they show up in logs.
+func newLogger(cfg *config.Config) (*slog.Logger, error) {
+	opts, err := logging.ParseOptions(cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level: %w", err)
 	}
+	opts.Secrets = cfg.Secrets()
+	return logging.New(os.Stdout, opts), nil
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"backendGoAuth/internal/admin"
+	"backendGoAuth/internal/app"
+	"backendGoAuth/internal/config"
+	"backendGoAuth/internal/health"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// newHTTPServer creates the API server on the configured port with its timeouts.
+func newHTTPServer(cfg *config.Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              cfg.APIAddr(),
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+	}
+}
+
+// newAdminServer creates the server for the admin router on the admin address, which defaults to localhost only.
+// It has no write timeout since CPU profiles and traces stream for as long as they were asked to run.
+func newAdminServer(cfg *config.Config, reg *prometheus.Registry, healthChecker *health.Checker) *http.Server {
+	router := admin.NewRouter(admin.Options{
+		Registry:          reg,
+		Health:            healthChecker,
+		BearerToken:       cfg.Admin.BearerToken,
+		BasicAuthUser:     cfg.Admin.BasicAuthUser,
+		BasicAuthPassword: cfg.Admin.BasicAuthPassword,
+	})
+	return &http.Server{
+		Addr:              cfg.Admin.Addr,
+		Handler:           router,
+		ReadHeaderTimeout: 5 * time.Second,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+}
+
+// listen serves server in the background, over TLS when a certificate and key are
+// configured and tls is true. Errors other than a shutdown are sent on errs.
+func listen(cfg config.ServerConfig, server *http.Server, name string, tls bool, errs chan<- error) {
+	certFile, keyFile := cfg.TLSCertFile, cfg.TLSKeyFile
+	tls = tls && certFile != "" && keyFile != ""
+
+	go func() {
+		slog.Info("Server is running", "server", name, "addr", server.Addr, "tls", tls)
+		var err error
+		if tls {
+			err = server.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- fmt.Errorf("%s: %w", name, err)
+		}
+	}()
+}
+
+// runServers serves the API and admin servers until SIGINT or SIGTERM, then shuts down
+// gracefully: it reports not ready, waits for load balancers to notice, stops accepting
+// requests and drains in-flight ones, then stops the background workers. Everything after
+// the signal must finish within the shutdown timeout.
+func runServers(cfg config.ServerConfig, server, adminServer *http.Server, healthChecker *health.Checker, a *app.App) {
+	signals, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 2)
+	listen(cfg, server, "Server", true, errs)
+	listen(cfg, adminServer, "Admin server", false, errs)
+
+	select {
+	case <-signals.Done():
+		slog.Info("Shutting down...")
+	case err := <-errs:
+		slog.Error("Error running the server, shutting down", "error", err)
+	}
+	// A second signal kills the process right away
+	stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	healthChecker.MarkShuttingDown()
+	if delay := cfg.ShutdownReadinessDelay; delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+	}
+
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("Error draining in-flight requests", "error", err)
+	}
+	if err := a.Stop(ctx); err != nil {
+		slog.Error("Error waiting for background workers to stop", "error", err)
+	}
+	if err := adminServer.Shutdown(ctx); err != nil {
+		slog.Error("Error shutting down the admin server", "error", err)
+	}
+	slog.Info("Shutdown complete")
+}
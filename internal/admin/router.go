@@ -0,0 +1,87 @@
+// Package admin serves the operational endpoints (metrics, pprof and health checks) on a
+// listener separate from the public API, so production traffic never reaches them.
+// Health checks are left unprotected so orchestrators can probe them.
+package admin
+
+import (
+	"backendGoAuth/internal/health"
+	"backendGoAuth/internal/metrics"
+	"crypto/subtle"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+)
+
+// Options configures the admin router. Requests other than health checks must present the
+// bearer token or the basic auth credentials when they're set; with neither set the endpoints
+// are unprotected.
+type Options struct {
+	Registry          *prometheus.Registry
+	Health            *health.Checker
+	BearerToken       string
+	BasicAuthUser     string
+	BasicAuthPassword string
+}
+
+// NewRouter creates the router served on the admin listener.
+func NewRouter(opts Options) *gin.Engine {
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	// Registered before the credentials middleware, which only applies to later routes
+	router.GET("/healthz", opts.Health.LivenessHandler())
+	router.GET("/readyz", opts.Health.ReadinessHandler())
+
+	if opts.BearerToken == "" && opts.BasicAuthUser == "" {
+		slog.Warn("Admin endpoints are not protected, set ADMIN_BEARER_TOKEN or ADMIN_BASIC_AUTH_USER to protect them")
+	} else {
+		router.Use(requireCredentials(opts))
+	}
+
+	router.GET("/metrics", metrics.MetricsHandler(opts.Registry))
+
+	debug := router.Group("/debug/pprof")
+	{
+		debug.GET("/", gin.WrapF(pprof.Index))
+		debug.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+		debug.GET("/profile", gin.WrapF(pprof.Profile))
+		debug.GET("/symbol", gin.WrapF(pprof.Symbol))
+		debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+		debug.GET("/trace", gin.WrapF(pprof.Trace))
+		// allocs, block, goroutine, heap, mutex, threadcreate
+		debug.GET("/:profile", func(c *gin.Context) {
+			pprof.Handler(c.Param("profile")).ServeHTTP(c.Writer, c.Request)
+		})
+	}
+
+	return router
+}
+
+// requireCredentials rejects requests that present neither the bearer token nor the basic auth credentials.
+func requireCredentials(opts Options) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if opts.BearerToken != "" {
+			if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && secureEqual(token, opts.BearerToken) {
+				c.Next()
+				return
+			}
+		}
+		if opts.BasicAuthUser != "" {
+			if user, password, ok := c.Request.BasicAuth(); ok &&
+				secureEqual(user, opts.BasicAuthUser) && secureEqual(password, opts.BasicAuthPassword) {
+				c.Next()
+				return
+			}
+			c.Header("WWW-Authenticate", `Basic realm="admin"`)
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+	}
+}
+
+// secureEqual compares secrets in constant time.
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,269 @@
+// Package app builds the service from its configuration: the repositories, services and
+// controllers, the router serving them and the background workers they depend on.
+package app
+
+import (
+	"backendGoAuth/internal/cache"
+	"backendGoAuth/internal/config"
+	"backendGoAuth/internal/database"
+	"backendGoAuth/internal/health"
+	"backendGoAuth/internal/logging"
+	"backendGoAuth/internal/metrics"
+	"backendGoAuth/internal/payments"
+	"backendGoAuth/internal/repositories"
+	"backendGoAuth/internal/services"
+	"backendGoAuth/internal/utils"
+	"context"
+	"database/sql"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"log/slog"
+	"strconv"
+)
+
+// Repositories are the repositories the services are built on, which can be given other
+// implementations, such as in-memory ones or fakes in tests. Users, Sessions, Outbox, Audit
+// and Permissions are always needed; the others only by the routes using them.
+type Repositories struct {
+	Users       repositories.UserRepositoryInterface
+	Sessions    repositories.SessionRepositoryInterface
+	Outbox      repositories.OutboxRepositoryInterface
+	Audit       repositories.AuditRepositoryInterface
+	Permissions repositories.PermissionRepositoryInterface
+	Webhooks    repositories.WebhookRepositoryInterface
+	Products    repositories.ProductRepositoryInterface
+	Carts       repositories.CartRepositoryInterface
+	Addresses   repositories.AddressRepositoryInterface
+	Inventory   repositories.InventoryRepositoryInterface
+	Orders      repositories.OrderRepositoryInterface
+	Payments    repositories.PaymentRepositoryInterface
+	Vendors     repositories.VendorRepositoryInterface
+	Privacy     repositories.PrivacyRepositoryInterface
+}
+
+// NewPostgresRepositories creates the repositories backed by db.
+func NewPostgresRepositories(db *sql.DB) Repositories {
+	return Repositories{
+		Users:       repositories.NewUserRepository(db),
+		Sessions:    repositories.NewSessionRepository(db),
+		Outbox:      repositories.NewOutboxRepository(db),
+		Audit:       repositories.NewAuditRepository(db),
+		Permissions: repositories.NewPermissionRepository(db),
+		Webhooks:    repositories.NewWebhookRepository(db),
+		Products:    repositories.NewProductRepository(db),
+		Carts:       repositories.NewCartRepository(db),
+		Addresses:   repositories.NewAddressRepository(db),
+		Inventory:   repositories.NewInventoryRepository(db),
+		Orders:      repositories.NewOrderRepository(db),
+		Payments:    repositories.NewPaymentRepository(db),
+		Vendors:     repositories.NewVendorRepository(db),
+		Privacy:     repositories.NewPrivacyRepository(db),
+	}
+}
+
+// Services are the services the routes are served by, which tests can replace with fakes.
+type Services struct {
+	Auth          services.AuthServiceInterface
+	Sessions      services.SessionServiceInterface
+	Audit         services.AuditServiceInterface
+	Permissions   services.PermissionServiceInterface
+	Impersonation services.ImpersonationServiceInterface
+	Webhooks      services.WebhookServiceInterface
+	Products      services.ProductServiceInterface
+	Inventory     services.InventoryServiceInterface
+	Carts         services.CartServiceInterface
+	Addresses     services.AddressServiceInterface
+	Orders        services.OrderServiceInterface
+	Payments      services.PaymentServiceInterface
+	Vendors       services.VendorServiceInterface
+	Privacy       services.PrivacyServiceInterface
+	Admin         services.AdminService
+}
+
+// App is one instance of the service. Nothing is shared between instances, not even metrics
+// or the secrets redacted from the logs, so several can run side by side, such as in tests.
+type App struct {
+	Config       *config.Config
+	DB           *sql.DB // nil when the app isn't backed by Postgres
+	Repositories Repositories
+	Services     Services
+	Tokens       *utils.TokenManager
+	Registry     *prometheus.Registry
+	Metrics      *metrics.Metrics
+	Health       *health.Checker
+	Router       *gin.Engine
+
+	workers           *backgroundWorkers
+	runners           []func(ctx context.Context)
+	activityTracker   *services.SessionActivityTracker
+	permissionService *services.PermissionService // cached once the app is started
+}
+
+// New builds the app described by cfg on repos. db, which may be nil, is only used for
+// health checks, metrics and the caches started with the app. Background workers only
+// run once the app is started.
+func New(cfg *config.Config, db *sql.DB, repos Repositories, logger *slog.Logger) (*App, error) {
+	a := &App{
+		Config:       cfg,
+		DB:           db,
+		Repositories: repos,
+		Registry:     prometheus.NewRegistry(),
+		Health:       health.NewChecker(cfg.Health.CheckTimeout),
+		workers:      newBackgroundWorkers(),
+	}
+	// Register Prometheus metrics, served by the admin listener
+	a.Metrics = metrics.New(a.Registry, db, repos.Sessions.CountActiveSessions)
+	a.Tokens = utils.NewTokenManager(cfg.JWT, repos.Sessions, a.Metrics)
+	if err := a.buildServices(); err != nil {
+		return nil, err
+	}
+	a.Router = a.newRouter(logger)
+
+	// Readiness depends on the database, its schema and being able to sign tokens
+	if db != nil {
+		a.Health.Register("postgres", db.PingContext)
+		a.Health.Register("migrations", func(ctx context.Context) error { return database.CheckMigrations(ctx, db) })
+	}
+	a.Health.Register("signing_key", func(context.Context) error { return a.Tokens.CheckSigningKey() })
+	return a, nil
+}
+
+// buildServices instantiates the services on the repositories.
+func (a *App) buildServices() error {
+	cfg, repos := a.Config, a.Repositories
+	s := &a.Services
+
+	auditService := services.NewAuditService(repos.Audit)
+	s.Audit = auditService
+	if auditRepo, ok := repos.Audit.(repositories.AuditChainRepositoryInterface); ok {
+		auditChain, err := services.NewAuditChain(auditRepo, []byte(cfg.Audit.HMACKey))
+		if err != nil {
+			return err
+		}
+		// Entries are sealed in batches in the background rather than on every write
+		a.runners = append(a.runners, func(ctx context.Context) { auditChain.Run(ctx, cfg.Audit.SealInterval) })
+	}
+
+	a.permissionService = services.NewPermissionService(repos.Permissions)
+	s.Permissions = a.permissionService
+	sessionService := services.NewSessionService(repos.Sessions, auditService, a.permissionService, repos.Outbox, a.Tokens, a.Metrics)
+	s.Sessions = sessionService
+	a.activityTracker = services.NewSessionActivityTracker(repos.Sessions, cfg.Sessions.ActivityFlush, cfg.Sessions.ActivityHistoryLimit)
+	a.runners = append(a.runners, a.activityTracker.Run)
+
+	authService := services.NewAuthService(repos.Users, sessionService, auditService, repos.Outbox, a.Tokens, a.Metrics)
+	authService.MaxLoginAttempts = cfg.Auth.MaxLoginAttempts
+	s.Auth = authService
+
+	s.Webhooks = services.NewWebhookService(repos.Webhooks)
+	webhookDispatcher := services.NewWebhookDispatcher(repos.Webhooks, cfg.Webhooks.PollInterval, cfg.Webhooks.MaxAttempts)
+	a.runners = append(a.runners, webhookDispatcher.Run)
+
+	impersonationService := services.NewImpersonationService(repos.Users, sessionService, auditService, a.Tokens)
+	s.Impersonation = impersonationService
+	a.runners = append(a.runners, func(ctx context.Context) { impersonationService.Run(ctx, cfg.JWT.ImpersonationSweep) })
+	s.Admin = services.NewAdminService(repos.Users, repos.Sessions, repos.Outbox, a.Metrics)
+
+	productService := services.NewProductService(repos.Products, a.permissionService)
+	s.Products = productService
+	s.Carts = services.NewCartService(repos.Carts, repos.Products)
+	s.Addresses = services.NewAddressService(repos.Addresses)
+	s.Inventory = services.NewInventoryService(repos.Inventory, productService)
+
+	orderService := services.NewOrderService(repos.Orders, repos.Carts, repos.Addresses, repos.Inventory, a.permissionService)
+	orderService.ReservationTTL = cfg.Orders.ReservationTTL
+	s.Orders = orderService
+	stockReaper := services.NewStockReservationReaper(orderService, repos.Inventory, cfg.Orders.ReservationReaperTick)
+	a.runners = append(a.runners, stockReaper.Run)
+
+	s.Payments = services.NewPaymentService(repos.Payments, orderService, newPaymentProvider(cfg))
+
+	s.Vendors = services.NewVendorService(repos.Vendors, repos.Users, a.permissionService, auditService, repos.Outbox)
+	privacyService := services.NewPrivacyService(repos.Privacy, repos.Orders, repos.Vendors, repos.Sessions, a.permissionService, auditService, repos.Outbox, a.Metrics)
+	privacyService.GracePeriod = cfg.Privacy.ErasureGracePeriod
+	s.Privacy = privacyService
+	erasureWorker := services.NewErasureWorker(privacyService, cfg.Privacy.ErasureSweep)
+	a.runners = append(a.runners, erasureWorker.Run)
+	return nil
+}
+
+// newPaymentProvider returns the configured payment provider, which Validate ensures is known
+func newPaymentProvider(cfg *config.Config) payments.Provider {
+	return payments.NewFakeProvider(
+		cfg.Payments.WebhookSecret,
+		cfg.PaymentCallbackURL(),
+		cfg.Payments.FakeCallbackDelay,
+	)
+}
+
+// Start runs the background workers and, on Postgres, caches sessions and permissions,
+// kept coherent with the other instances through notifications.
+func (a *App) Start() {
+	if a.DB != nil {
+		a.startCaches()
+	}
+	for _, run := range a.runners {
+		a.workers.Go(run)
+	}
+}
+
+// Stop stops the background workers, waiting for them to finish until ctx expires.
+func (a *App) Stop(ctx context.Context) error {
+	return a.workers.Stop(ctx)
+}
+
+// startCaches puts the session and permission caches in front of their repositories, unless
+// the notifications invalidating them can't be received.
+func (a *App) startCaches() {
+	if sessionRepo, ok := a.Repositories.Sessions.(*repositories.SessionRepository); ok {
+		sessionCache := cache.NewSessionCache(a.Config.Sessions.CacheSize, a.Config.Sessions.CacheTTL)
+		if err := a.listenForSessionRevocations(sessionCache); err != nil {
+			// Without notifications other instances' revocations would go unnoticed
+			slog.Error("Error listening for session revocations, session cache disabled", "error", err)
+		} else {
+			sessionRepo.WithCache(sessionCache)
+		}
+	}
+
+	permissionCache := cache.NewPermissionCache(a.Config.Permissions.CacheSize, a.Config.Permissions.CacheTTL)
+	if err := a.listenForPermissionChanges(permissionCache); err != nil {
+		// Without notifications role changes on other instances would go unnoticed
+		slog.Error("Error listening for permission changes, permission cache disabled", "error", err)
+	} else {
+		a.permissionService.Cache = permissionCache
+	}
+}
+
+// listenForSessionRevocations keeps the session cache coherent with the other
+// instances by dropping sessions they revoke.
+func (a *App) listenForSessionRevocations(sessionCache *cache.SessionCache) error {
+	ctx := a.workers.ctx
+	return database.Listen(ctx, a.Config.Database, repositories.SessionRevokedChannel,
+		func(payload string) {
+			sessionID, err := strconv.Atoi(payload)
+			if err != nil {
+				logging.FromContext(ctx).Warn("Invalid session revocation payload", "payload", payload, "error", err)
+				return
+			}
+			sessionCache.Invalidate(sessionID)
+		},
+		sessionCache.Purge,
+	)
+}
+
+// listenForPermissionChanges keeps the permission cache coherent with the other
+// instances by dropping users whose roles they change.
+func (a *App) listenForPermissionChanges(permissionCache *cache.PermissionCache) error {
+	ctx := a.workers.ctx
+	return database.Listen(ctx, a.Config.Database, repositories.PermissionsChangedChannel,
+		func(payload string) {
+			userID, err := strconv.Atoi(payload)
+			if err != nil {
+				logging.FromContext(ctx).Warn("Invalid permission change payload", "payload", payload, "error", err)
+				return
+			}
+			permissionCache.Invalidate(userID)
+		},
+		permissionCache.Purge,
+	)
+}
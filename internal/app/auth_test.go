@@ -0,0 +1,317 @@
+package app_test
+
+import (
+	"backendGoAuth/internal/config"
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/repositories"
+	"backendGoAuth/internal/services"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	ts := newTestServer(t, nil)
+
+	userID := ts.register("alice", "correct horse")
+
+	user, err := ts.users.GetUserByUsername(context.Background(), "alice")
+	if err != nil || user == nil {
+		t.Fatalf("registered user not stored: %v", err)
+	}
+	if user.ID != userID || user.Email != "alice@example.com" {
+		t.Fatalf("got user %+v, want ID %d and email alice@example.com", user, userID)
+	}
+	if user.Password == "correct horse" {
+		t.Fatal("password stored in clear")
+	}
+	if hasRole, _ := ts.permissions.UserHasRole(userID, services.DefaultUserRole); !hasRole {
+		t.Fatalf("registered user doesn't have the %s role", services.DefaultUserRole)
+	}
+}
+
+func TestRegisterRejectsDuplicatesAndInvalidRequests(t *testing.T) {
+	ts := newTestServer(t, nil)
+	ts.register("alice", "correct horse")
+
+	tests := []struct {
+		name string
+		body map[string]string
+		code goAuthException.ErrorCode
+	}{
+		{"taken username", map[string]string{"username": "alice", "password": "pw", "email": "other@example.com"}, goAuthException.ErrorCodeUsernameTaken},
+		{"taken email", map[string]string{"username": "bob", "password": "pw", "email": "alice@example.com"}, goAuthException.ErrorCodeEmailTaken},
+		{"invalid email", map[string]string{"username": "bob", "password": "pw", "email": "not-an-email"}, goAuthException.ErrorCodeValidationFailed},
+		{"missing password", map[string]string{"username": "bob", "email": "bob@example.com"}, goAuthException.ErrorCodeValidationFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := ts.do(ts.client(), http.MethodPost, "/api/register", tt.body)
+			expectProblem(t, resp, body, http.StatusBadRequest, tt.code)
+		})
+	}
+}
+
+func TestLoginSetsAccessTokenCookie(t *testing.T) {
+	ts := newTestServer(t, nil)
+	userID := ts.register("alice", "correct horse")
+
+	for _, identifier := range []string{"alice", "alice@example.com"} {
+		t.Run(identifier, func(t *testing.T) {
+			resp, body := ts.do(ts.client(), http.MethodPost, "/api/login", map[string]string{
+				"identifier": identifier,
+				"password":   "correct horse",
+			})
+			expectStatus(t, resp, body, http.StatusOK)
+
+			var cookie *http.Cookie
+			for _, c := range resp.Cookies() {
+				if c.Name == "access_token" {
+					cookie = c
+				}
+			}
+			if cookie == nil || cookie.Value == "" {
+				t.Fatal("login didn't set the access_token cookie")
+			}
+			if !cookie.HttpOnly || cookie.Path != "/" || cookie.MaxAge <= 0 {
+				t.Fatalf("got cookie %+v, want an HttpOnly cookie on / with a max age", cookie)
+			}
+			if !strings.Contains(string(body), fmt.Sprintf(`"id":%d`, userID)) {
+				t.Fatalf("login response doesn't describe the user: %s", body)
+			}
+		})
+	}
+}
+
+func TestLoginFailures(t *testing.T) {
+	ts := newTestServer(t, func(cfg *config.Config) { cfg.Auth.MaxLoginAttempts = 3 })
+	ts.register("alice", "correct horse")
+
+	resp, body := ts.do(ts.client(), http.MethodPost, "/api/login", map[string]string{"identifier": "nobody", "password": "pw"})
+	expectProblem(t, resp, body, http.StatusNotFound, goAuthException.ErrorCodeUserNotFound)
+
+	resp, body = ts.do(ts.client(), http.MethodPost, "/api/login", map[string]string{"identifier": "alice"})
+	expectProblem(t, resp, body, http.StatusBadRequest, goAuthException.ErrorCodeValidationFailed)
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		resp, body = ts.do(ts.client(), http.MethodPost, "/api/login", map[string]string{"identifier": "alice", "password": "wrong"})
+		expectProblem(t, resp, body, http.StatusUnauthorized, goAuthException.ErrorCodeInvalidCredentials)
+		if cookies := resp.Cookies(); len(cookies) > 0 {
+			t.Fatalf("failed login set cookies %v", cookies)
+		}
+	}
+
+	// The account is locked, even with the right password, but only that password tells it is
+	resp, body = ts.do(ts.client(), http.MethodPost, "/api/login", map[string]string{"identifier": "alice", "password": "wrong"})
+	expectProblem(t, resp, body, http.StatusUnauthorized, goAuthException.ErrorCodeInvalidCredentials)
+	resp, body = ts.do(ts.client(), http.MethodPost, "/api/login", map[string]string{"identifier": "alice", "password": "correct horse"})
+	expectProblem(t, resp, body, http.StatusForbidden, goAuthException.ErrorCodeAccountLocked)
+
+	if events := ts.outboxEventTypes(); countOf(events, services.EventUserLocked) != 1 {
+		t.Fatalf("got outbox events %v, want one %s", events, services.EventUserLocked)
+	}
+}
+
+func TestAuthRoutesRequireValidToken(t *testing.T) {
+	ts := newTestServer(t, nil)
+
+	resp, body := ts.do(ts.client(), http.MethodGet, "/api/auth/secure", nil)
+	expectProblem(t, resp, body, http.StatusUnauthorized, goAuthException.ErrorCodeUnauthorized)
+
+	client := ts.client()
+	setCookie(t, ts, client, "access_token", "not-a-jwt")
+	resp, body = ts.do(client, http.MethodGet, "/api/auth/secure", nil)
+	expectProblem(t, resp, body, http.StatusUnauthorized, goAuthException.ErrorCodeInvalidToken)
+
+	// Tokens signed by another server's secret are rejected
+	other := newTestServer(t, func(cfg *config.Config) { cfg.JWT.Secret = "other-secret" })
+	other.register("alice", "correct horse")
+	otherClient := other.client()
+	other.login(otherClient, "alice", "correct horse")
+	for _, cookie := range otherClient.Jar.Cookies(mustParseURL(t, other.server.URL)) {
+		setCookie(t, ts, client, cookie.Name, cookie.Value)
+	}
+	resp, body = ts.do(client, http.MethodGet, "/api/auth/secure", nil)
+	expectProblem(t, resp, body, http.StatusUnauthorized, goAuthException.ErrorCodeInvalidToken)
+}
+
+func TestSecureEndpoint(t *testing.T) {
+	ts := newTestServer(t, nil)
+	userID := ts.register("alice", "correct horse")
+	client := ts.client()
+	ts.login(client, "alice", "correct horse")
+
+	resp, body := ts.do(client, http.MethodGet, "/api/auth/secure", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+
+	var secure struct {
+		UserID       int  `json:"user_id"`
+		Impersonated bool `json:"impersonated"`
+	}
+	decode(t, body, &secure)
+	if secure.UserID != userID || secure.Impersonated {
+		t.Fatalf("got %+v, want user %d not impersonated", secure, userID)
+	}
+}
+
+func TestActiveSessionsAndRevocation(t *testing.T) {
+	ts := newTestServer(t, nil)
+	ts.register("alice", "correct horse")
+
+	laptop, phone := ts.client(), ts.client()
+	ts.login(laptop, "alice", "correct horse")
+	ts.login(phone, "alice", "correct horse")
+
+	// Registering creates a session too, which never gets a cookie
+	sessionIDs := ts.activeSessionIDs(laptop)
+	if len(sessionIDs) != 3 {
+		t.Fatalf("got active sessions %v, want 3", sessionIDs)
+	}
+	phoneSessionID := sessionIDs[2]
+
+	resp, body := ts.do(laptop, http.MethodPost, fmt.Sprintf("/api/auth/revokeSession?session_id=%d", phoneSessionID), nil)
+	expectStatus(t, resp, body, http.StatusOK)
+
+	// The phone's token is rejected as soon as its session is revoked
+	resp, body = ts.do(phone, http.MethodGet, "/api/auth/secure", nil)
+	expectProblem(t, resp, body, http.StatusUnauthorized, goAuthException.ErrorCodeInvalidToken)
+	if sessionIDs := ts.activeSessionIDs(laptop); len(sessionIDs) != 2 || contains(sessionIDs, phoneSessionID) {
+		t.Fatalf("got active sessions %v after revoking %d", sessionIDs, phoneSessionID)
+	}
+
+	resp, body = ts.do(laptop, http.MethodPost, "/api/auth/revokeSession?session_id=999", nil)
+	expectProblem(t, resp, body, http.StatusNotFound, goAuthException.ErrorCodeSessionNotFound)
+	resp, body = ts.do(laptop, http.MethodPost, "/api/auth/revokeSession", nil)
+	expectProblem(t, resp, body, http.StatusBadRequest, goAuthException.ErrorCodeBadRequest)
+
+	if events := ts.outboxEventTypes(); countOf(events, services.EventSessionRevoked) != 1 {
+		t.Fatalf("got outbox events %v, want one %s", events, services.EventSessionRevoked)
+	}
+}
+
+func TestRevokingAnotherUsersSession(t *testing.T) {
+	ts := newTestServer(t, nil)
+	_, admin := ts.registerAdmin("root", "admin password")
+	ts.register("alice", "correct horse")
+	ts.register("bob", "battery staple")
+	alice, bob := ts.client(), ts.client()
+	ts.login(alice, "alice", "correct horse")
+	ts.login(bob, "bob", "battery staple")
+	aliceSessionIDs := ts.activeSessionIDs(alice)
+	aliceSessionID := aliceSessionIDs[len(aliceSessionIDs)-1]
+
+	// Other users' sessions can't be told apart from ones that don't exist
+	resp, body := ts.do(bob, http.MethodPost, fmt.Sprintf("/api/auth/revokeSession?session_id=%d", aliceSessionID), nil)
+	expectProblem(t, resp, body, http.StatusNotFound, goAuthException.ErrorCodeSessionNotFound)
+	resp, body = ts.do(alice, http.MethodGet, "/api/auth/secure", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	if events := ts.outboxEventTypes(); countOf(events, services.EventSessionRevoked) != 0 {
+		t.Fatalf("got outbox events %v, want no %s", events, services.EventSessionRevoked)
+	}
+
+	// Admins can revoke anyone's session
+	resp, body = ts.do(admin, http.MethodPost, fmt.Sprintf("/api/auth/revokeSession?session_id=%d", aliceSessionID), nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	resp, body = ts.do(alice, http.MethodGet, "/api/auth/secure", nil)
+	expectProblem(t, resp, body, http.StatusUnauthorized, goAuthException.ErrorCodeInvalidToken)
+}
+
+func TestLogout(t *testing.T) {
+	ts := newTestServer(t, nil)
+	userID := ts.register("alice", "correct horse")
+	client := ts.client()
+	ts.login(client, "alice", "correct horse")
+
+	resp, body := ts.do(client, http.MethodPost, "/api/auth/logout", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+
+	resp, body = ts.do(client, http.MethodGet, "/api/auth/secure", nil)
+	expectProblem(t, resp, body, http.StatusUnauthorized, goAuthException.ErrorCodeInvalidToken)
+
+	logs, _, err := ts.audit.QueryAuditLogs(repositories.AuditLogFilter{TargetID: userID, Action: services.AuditActionLogout, Limit: 10})
+	if err != nil || len(logs) != 1 {
+		t.Fatalf("got logout audit logs %v (%v), want one", logs, err)
+	}
+}
+
+func TestAdminRoutes(t *testing.T) {
+	ts := newTestServer(t, nil)
+	adminID, admin := ts.registerAdmin("root", "admin password")
+	userID := ts.register("alice", "correct horse")
+	customer := ts.client()
+	ts.login(customer, "alice", "correct horse")
+
+	// Customers are turned away from every admin route, before anything is done on the admin
+	edit := map[string]interface{}{"username": "alice2", "email": "alice2@example.com", "is_blocked": true}
+	adminRoutes := 0
+	for _, route := range ts.app.Router.Routes() {
+		if !strings.HasPrefix(route.Path, "/api/admin/") {
+			continue
+		}
+		adminRoutes++
+		path := strings.ReplaceAll(route.Path, ":id", strconv.Itoa(adminID))
+		resp, body := ts.do(customer, route.Method, path, edit)
+		expectProblem(t, resp, body, http.StatusForbidden, goAuthException.ErrorCodeForbidden)
+	}
+	if adminRoutes == 0 {
+		t.Fatal("no admin routes found")
+	}
+	if user, _ := ts.users.GetUserByID(context.Background(), adminID); user.Username != "root" || user.IsBlocked || !user.IsActive {
+		t.Fatalf("got admin %+v after the customer's requests, want them unchanged", user)
+	}
+
+	// Nor can they end the admin's sessions
+	adminSessionIDs := ts.activeSessionIDs(admin)
+	resp, body := ts.do(customer, http.MethodPost, fmt.Sprintf("/api/auth/revokeSession?session_id=%d", adminSessionIDs[len(adminSessionIDs)-1]), nil)
+	expectProblem(t, resp, body, http.StatusNotFound, goAuthException.ErrorCodeSessionNotFound)
+	if sessionIDs := ts.activeSessionIDs(admin); len(sessionIDs) != len(adminSessionIDs) {
+		t.Fatalf("got admin sessions %v after the customer's revocation, want %v", sessionIDs, adminSessionIDs)
+	}
+
+	resp, body = ts.do(ts.client(), http.MethodGet, "/api/admin/users", nil)
+	expectProblem(t, resp, body, http.StatusUnauthorized, goAuthException.ErrorCodeUnauthorized)
+
+	resp, body = ts.do(admin, http.MethodGet, "/api/admin/users", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	if !strings.Contains(string(body), `"username":"alice"`) {
+		t.Fatalf("user list doesn't include alice: %s", body)
+	}
+
+	resp, body = ts.do(admin, http.MethodPut, fmt.Sprintf("/api/admin/users/%d", userID), edit)
+	expectStatus(t, resp, body, http.StatusOK)
+	user, _ := ts.users.GetUserByID(context.Background(), userID)
+	if user.Username != "alice2" || user.Email != "alice2@example.com" || !user.IsBlocked {
+		t.Fatalf("got user %+v after editing", user)
+	}
+	resp, body = ts.do(admin, http.MethodPut, "/api/admin/users/999", edit)
+	expectProblem(t, resp, body, http.StatusNotFound, goAuthException.ErrorCodeNotFound)
+
+	if events := ts.outboxEventTypes(); countOf(events, services.EventUserLocked) != 1 || countOf(events, services.EventSessionRevoked) != 2 {
+		t.Fatalf("got outbox events %v, want one %s and alice's two sessions revoked", events, services.EventUserLocked)
+	}
+
+	// Blocking ends the sessions the user is already logged in with
+	resp, body = ts.do(customer, http.MethodGet, "/api/auth/secure", nil)
+	expectProblem(t, resp, body, http.StatusUnauthorized, goAuthException.ErrorCodeInvalidToken)
+
+	// Blocked users can't log in anymore
+	resp, body = ts.do(ts.client(), http.MethodPost, "/api/login", map[string]string{"identifier": "alice2", "password": "correct horse"})
+	expectProblem(t, resp, body, http.StatusForbidden, goAuthException.ErrorCodeAccountLocked)
+
+	resp, body = ts.do(admin, http.MethodDelete, fmt.Sprintf("/api/admin/users/%d", userID), nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	if user, _ := ts.users.GetUserByID(context.Background(), userID); user.IsActive {
+		t.Fatal("deleted user is still active")
+	}
+
+	resp, body = ts.do(admin, http.MethodGet, fmt.Sprintf("/api/admin/audit?target_id=%d", userID), nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	for _, action := range []string{services.AuditActionUserUpdated, services.AuditActionUserDeleted} {
+		if !strings.Contains(string(body), `"action":"`+action+`"`) {
+			t.Fatalf("audit log doesn't include %s: %s", action, body)
+		}
+	}
+}
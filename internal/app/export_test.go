@@ -0,0 +1,11 @@
+package app
+
+import (
+	"log/slog"
+)
+
+// RebuildRouter builds the router again, serving the services as they are now, such as
+// after replacing some with fakes.
+func (a *App) RebuildRouter(logger *slog.Logger) {
+	a.Router = a.newRouter(logger)
+}
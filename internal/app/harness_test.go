@@ -0,0 +1,252 @@
+package app_test
+
+import (
+	"backendGoAuth/internal/app"
+	"backendGoAuth/internal/config"
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/repositories/memory"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// testServer serves the full router over HTTP, on in-memory repositories.
+type testServer struct {
+	t           *testing.T
+	app         *app.App
+	server      *httptest.Server
+	users       *memory.UserRepository
+	sessions    *memory.SessionRepository
+	outbox      *memory.OutboxRepository
+	audit       *memory.AuditRepository
+	permissions *memory.PermissionRepository
+}
+
+// newTestServer starts a server configured with the defaults, adjusted by configure if given.
+func newTestServer(t *testing.T, configure func(cfg *config.Config)) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Default()
+	cfg.JWT.Secret = "test-secret"
+	if configure != nil {
+		configure(cfg)
+	}
+
+	users := memory.NewUserRepository()
+	ts := &testServer{
+		t:           t,
+		users:       users,
+		sessions:    memory.NewSessionRepository(),
+		outbox:      memory.NewOutboxRepository(),
+		audit:       memory.NewAuditRepository(),
+		permissions: memory.NewPermissionRepository(users),
+	}
+	repos := app.Repositories{
+		Users:       ts.users,
+		Sessions:    ts.sessions,
+		Outbox:      ts.outbox,
+		Audit:       ts.audit,
+		Permissions: ts.permissions,
+	}
+
+	var err error
+	ts.app, err = app.New(cfg, nil, repos, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.server = httptest.NewServer(ts.app.Router)
+	t.Cleanup(ts.server.Close)
+	return ts
+}
+
+// replaceServices lets replace swap services for fakes, serving them from the next request on.
+func (ts *testServer) replaceServices(replace func(s *app.Services)) {
+	replace(&ts.app.Services)
+	ts.app.RebuildRouter(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ts.server.Config.Handler = ts.app.Router
+}
+
+// client returns a client keeping its own cookies, like a browser.
+func (ts *testServer) client() *http.Client {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	return &http.Client{Jar: jar}
+}
+
+// do sends a request with body encoded as JSON, if not nil, and returns the response with its body read.
+func (ts *testServer) do(client *http.Client, method, path string, body interface{}) (*http.Response, []byte) {
+	ts.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			ts.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, ts.server.URL+path, reader)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	return resp, data
+}
+
+// register registers a user, failing the test if that doesn't succeed, and returns their ID.
+func (ts *testServer) register(username, password string) int {
+	ts.t.Helper()
+
+	resp, body := ts.do(ts.client(), http.MethodPost, "/api/register", map[string]string{
+		"username": username,
+		"password": password,
+		"email":    username + "@example.com",
+	})
+	expectStatus(ts.t, resp, body, http.StatusOK)
+
+	var registered struct {
+		User struct {
+			ID int `json:"id"`
+		} `json:"user"`
+	}
+	decode(ts.t, body, &registered)
+	return registered.User.ID
+}
+
+// login logs client in, failing the test if that doesn't succeed.
+func (ts *testServer) login(client *http.Client, identifier, password string) {
+	ts.t.Helper()
+
+	resp, body := ts.do(client, http.MethodPost, "/api/login", map[string]string{
+		"identifier": identifier,
+		"password":   password,
+	})
+	expectStatus(ts.t, resp, body, http.StatusOK)
+}
+
+// registerAdmin registers a user with the Admin role and returns a client logged in as them.
+func (ts *testServer) registerAdmin(username, password string) (int, *http.Client) {
+	ts.t.Helper()
+
+	userID := ts.register(username, password)
+	if err := ts.users.AssignRole(context.Background(), nil, userID, "Admin"); err != nil {
+		ts.t.Fatal(err)
+	}
+	client := ts.client()
+	ts.login(client, username, password)
+	return userID, client
+}
+
+// activeSessionIDs lists the IDs of the active sessions of the user client is logged in as.
+func (ts *testServer) activeSessionIDs(client *http.Client) []int {
+	ts.t.Helper()
+
+	resp, body := ts.do(client, http.MethodGet, "/api/auth/activeSessions", nil)
+	expectStatus(ts.t, resp, body, http.StatusOK)
+
+	var sessions []struct {
+		ID int `json:"id"`
+	}
+	decode(ts.t, body, &sessions)
+	ids := make([]int, len(sessions))
+	for i, session := range sessions {
+		ids[i] = session.ID
+	}
+	return ids
+}
+
+// outboxEventTypes lists the types of the events written to the outbox, in order.
+func (ts *testServer) outboxEventTypes() []string {
+	var eventTypes []string
+	for _, event := range ts.outbox.Events() {
+		eventTypes = append(eventTypes, event.EventType)
+	}
+	return eventTypes
+}
+
+// setCookie stores a cookie for the server in client's jar, as if the server had set it.
+func setCookie(t *testing.T, ts *testServer, client *http.Client, name, value string) {
+	t.Helper()
+	client.Jar.SetCookies(mustParseURL(t, ts.server.URL), []*http.Cookie{{Name: name, Value: value, Path: "/"}})
+}
+
+func mustParseURL(t *testing.T, rawURL string) *url.URL {
+	t.Helper()
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func countOf(values []string, value string) int {
+	count := 0
+	for _, v := range values {
+		if v == value {
+			count++
+		}
+	}
+	return count
+}
+
+func contains(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// expectStatus fails the test if the response doesn't have the status.
+func expectStatus(t *testing.T, resp *http.Response, body []byte, status int) {
+	t.Helper()
+	if resp.StatusCode != status {
+		t.Fatalf("%s %s: got status %d, want %d: %s", resp.Request.Method, resp.Request.URL.Path, resp.StatusCode, status, body)
+	}
+}
+
+// expectProblem fails the test if the response isn't problem details with the status and code.
+func expectProblem(t *testing.T, resp *http.Response, body []byte, status int, code goAuthException.ErrorCode) {
+	t.Helper()
+	expectStatus(t, resp, body, status)
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != goAuthException.ProblemContentType {
+		t.Fatalf("got content type %q, want %q", contentType, goAuthException.ProblemContentType)
+	}
+	var problem goAuthException.Problem
+	decode(t, body, &problem)
+	if problem.Code != code {
+		t.Fatalf("got problem code %q, want %q: %s", problem.Code, code, body)
+	}
+}
+
+func decode(t *testing.T, body []byte, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(body, v); err != nil {
+		t.Fatalf("decoding %s: %v", body, err)
+	}
+}
@@ -0,0 +1,72 @@
+package app_test
+
+import (
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/repositories"
+	"backendGoAuth/internal/repositories/memory"
+	"backendGoAuth/internal/services"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestImpersonationExpiryIsAudited(t *testing.T) {
+	ts := newTestServer(t, nil)
+	adminID, admin := ts.registerAdmin("root", "admin password")
+	userID := ts.register("alice", "correct horse")
+
+	resp, body := ts.do(admin, http.MethodPost, fmt.Sprintf("/api/admin/impersonate/%d", userID), nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	resp, body = ts.do(admin, http.MethodGet, "/api/auth/secure", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	if !strings.Contains(string(body), `"impersonated":true`) {
+		t.Fatalf("not impersonating after starting: %s", body)
+	}
+
+	// Every impersonation session started so far is past its lifetime
+	ts.app.Tokens.ImpersonationDuration = 0
+	expired, err := ts.app.Services.Impersonation.ExpireImpersonations(context.Background())
+	if err != nil || expired != 1 {
+		t.Fatalf("got %d expired impersonations (%v), want 1", expired, err)
+	}
+	if expired, _ := ts.app.Services.Impersonation.ExpireImpersonations(context.Background()); expired != 0 {
+		t.Fatalf("expired %d impersonations again", expired)
+	}
+
+	resp, body = ts.do(admin, http.MethodGet, "/api/auth/secure", nil)
+	expectProblem(t, resp, body, http.StatusUnauthorized, goAuthException.ErrorCodeInvalidToken)
+
+	logs, _, err := ts.audit.QueryAuditLogs(repositories.AuditLogFilter{TargetID: userID, Action: services.AuditActionImpersonationStop, Limit: 10})
+	if err != nil || len(logs) != 1 {
+		t.Fatalf("got impersonation stop audit logs %v (%v), want one", logs, err)
+	}
+	if entry := logs[0]; entry.ActorID == nil || *entry.ActorID != adminID || !strings.Contains(string(entry.NewData), "expired") {
+		t.Fatalf("got audit log %+v, want the admin ending the impersonation on expiry", entry)
+	}
+}
+
+func TestImpersonatorWithoutManageUsersCanStop(t *testing.T) {
+	memory.RolePermissions["Support"] = []string{services.PermissionImpersonateUsers}
+	t.Cleanup(func() { delete(memory.RolePermissions, "Support") })
+
+	ts := newTestServer(t, nil)
+	supportID := ts.register("support", "support password")
+	if err := ts.users.AssignRole(context.Background(), nil, supportID, "Support"); err != nil {
+		t.Fatal(err)
+	}
+	support := ts.client()
+	ts.login(support, "support", "support password")
+	userID := ts.register("alice", "correct horse")
+
+	resp, body := ts.do(support, http.MethodPost, fmt.Sprintf("/api/admin/impersonate/%d", userID), nil)
+	expectStatus(t, resp, body, http.StatusOK)
+
+	// Stopping only needs the impersonation session to be theirs, not MANAGE_USERS
+	resp, body = ts.do(support, http.MethodPost, "/api/auth/impersonation/stop", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	if events := ts.outboxEventTypes(); countOf(events, services.EventSessionRevoked) != 1 {
+		t.Fatalf("got outbox events %v, want one %s", events, services.EventSessionRevoked)
+	}
+}
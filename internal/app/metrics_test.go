@@ -0,0 +1,37 @@
+package app_test
+
+import (
+	"testing"
+)
+
+// counterValue returns the value of the unlabeled counter name registered on ts's app.
+func counterValue(t *testing.T, ts *testServer, name string) float64 {
+	t.Helper()
+	families, err := ts.app.Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() == name && len(family.GetMetric()) == 1 {
+			return family.GetMetric()[0].GetCounter().GetValue()
+		}
+	}
+	t.Fatalf("counter %s not registered", name)
+	return 0
+}
+
+func TestMetricsArePerApp(t *testing.T) {
+	ts := newTestServer(t, nil)
+	other := newTestServer(t, nil)
+
+	ts.register("alice", "Password1!")
+	ts.register("bob", "Password1!")
+	other.register("carol", "Password1!")
+
+	if got := counterValue(t, ts, "auth_registrations_total"); got != 2 {
+		t.Errorf("got %v registrations on the first app, want 2", got)
+	}
+	if got := counterValue(t, other, "auth_registrations_total"); got != 1 {
+		t.Errorf("got %v registrations on the second app, want 1", got)
+	}
+}
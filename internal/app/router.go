@@ -0,0 +1,162 @@
+package app
+
+import (
+	"backendGoAuth/internal/controllers"
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/middlewares"
+	"backendGoAuth/internal/services"
+	"backendGoAuth/internal/tracing"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"log/slog"
+)
+
+// newRouter creates the Gin router serving the API.
+func (a *App) newRouter(logger *slog.Logger) *gin.Engine {
+	// Requests are logged by the access log instead of gin's logger, which would include query strings
+	router := gin.New()
+	router.Use(middlewares.Recovery())
+
+	corsConfig := cors.Config{
+		AllowOrigins:     a.Config.CORS.AllowedOrigins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Browser", "X-Device", "If-Match", "Idempotency-Key", "traceparent", "tracestate", middlewares.RequestIDHeader},
+		ExposeHeaders:    []string{"ETag", middlewares.RequestIDHeader},
+		AllowCredentials: true,
+	}
+
+	router.Use(cors.New(corsConfig))
+
+	// Trace each request, before timing it so its duration can be linked to the trace
+	router.Use(tracing.Middleware())
+
+	// Tag each request with an ID, carried by its logger, and log it once served
+	router.Use(middlewares.RequestID(logger))
+	router.Use(middlewares.AccessLog())
+
+	// Apply middleware to track request duration
+	router.Use(a.Metrics.InstrumentHandler())
+
+	// Render the errors handlers record as problem details, inside the middlewares reporting the status
+	router.Use(middlewares.ErrorHandler())
+	router.NoRoute(middlewares.NoRoute)
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(goAuthException.FieldName)
+	}
+
+	// Initialize JWT middleware with the secret and JWT service
+	jwtMiddleware := middlewares.NewJWTMiddleware(a.Tokens)
+	sessionActivityMiddleware := middlewares.SessionActivityMiddleware(a.activityTracker)
+	denyImpersonation := middlewares.DenyImpersonation()
+	requireManageUsers := middlewares.RequirePermission(a.Services.Permissions, services.PermissionManageUsers)
+
+	// Instantiate controllers
+	authController := controllers.NewAuthController(a.Services.Auth, a.Services.Sessions, a.Services.Carts)
+	adminController := controllers.NewAdminController(a.Services.Admin, a.Services.Audit)
+	auditController := controllers.NewAuditController(a.Services.Audit)
+	impersonationController := controllers.NewImpersonationController(a.Services.Impersonation, a.Tokens)
+	webhookController := controllers.NewWebhookController(a.Services.Webhooks)
+	productController := controllers.NewProductController(a.Services.Products, a.Services.Inventory)
+	cartController := controllers.NewCartController(a.Services.Carts)
+	orderController := controllers.NewOrderController(a.Services.Orders)
+	paymentController := controllers.NewPaymentController(a.Services.Payments)
+	addressController := controllers.NewAddressController(a.Services.Addresses)
+	vendorController := controllers.NewVendorController(a.Services.Vendors)
+	permissionController := controllers.NewPermissionController(a.Services.Permissions)
+	privacyController := controllers.NewPrivacyController(a.Services.Privacy)
+
+	// Define routes
+	api := router.Group("/api")
+	{
+		api.POST("/login", authController.Login)
+		api.POST("/register", authController.Register)
+
+		authGroup := api.Group("/auth", jwtMiddleware.MiddlewareFunc(), sessionActivityMiddleware) // Apply JWT middleware here
+		{
+			authGroup.POST("/logout", authController.RevokeCurrentSession)
+			authGroup.POST("/revokeSession", denyImpersonation, authController.RevokeSession)
+			authGroup.GET("/activeSessions", authController.GetActiveSessions)
+			authGroup.GET("/secure", authController.SecureEndpoint)
+			authGroup.POST("/impersonation/stop", impersonationController.StopImpersonation)
+			authGroup.GET("/addresses", addressController.GetAddresses)
+			authGroup.POST("/addresses", addressController.CreateAddress)
+			authGroup.GET("/addresses/:id", addressController.GetAddress)
+			authGroup.PUT("/addresses/:id", addressController.UpdateAddress)
+			authGroup.DELETE("/addresses/:id", addressController.DeleteAddress)
+			authGroup.GET("/permissions", permissionController.GetMyPermissions)
+			authGroup.GET("/account/export", denyImpersonation, privacyController.ExportData)
+			authGroup.GET("/account/erasure", privacyController.GetErasureRequest)
+			authGroup.POST("/account/erasure", denyImpersonation, privacyController.RequestErasure)
+			authGroup.DELETE("/account/erasure", denyImpersonation, privacyController.CancelErasure)
+		}
+
+		productGroup := api.Group("/products", jwtMiddleware.MiddlewareFunc(), sessionActivityMiddleware)
+		{
+			productGroup.GET("", middlewares.RequirePermission(a.Services.Permissions, services.PermissionViewProduct), productController.ListProducts)
+			productGroup.GET("/search", middlewares.RequirePermission(a.Services.Permissions, services.PermissionViewProduct), productController.SearchProducts)
+			productGroup.GET("/suggest", middlewares.RequirePermission(a.Services.Permissions, services.PermissionViewProduct), productController.SuggestProducts)
+			productGroup.GET("/:id", middlewares.RequirePermission(a.Services.Permissions, services.PermissionViewProduct), productController.GetProduct)
+			productGroup.POST("", middlewares.RequirePermission(a.Services.Permissions, services.PermissionCreateProduct), productController.CreateProduct)
+			productGroup.PUT("/:id", middlewares.RequirePermission(a.Services.Permissions, services.PermissionCreateProduct), productController.UpdateProduct)
+			productGroup.DELETE("/:id", middlewares.RequirePermission(a.Services.Permissions, services.PermissionDeleteProduct), productController.DeleteProduct)
+			productGroup.PUT("/:id/stock", middlewares.RequirePermission(a.Services.Permissions, services.PermissionCreateProduct), productController.SetStock)
+		}
+		api.GET("/categories", jwtMiddleware.MiddlewareFunc(), middlewares.RequirePermission(a.Services.Permissions, services.PermissionViewProduct), productController.GetCategories)
+
+		cartGroup := api.Group("/cart", jwtMiddleware.MiddlewareFunc(), sessionActivityMiddleware)
+		{
+			cartGroup.GET("", cartController.GetCart)
+			cartGroup.DELETE("", cartController.ClearCart)
+			cartGroup.POST("/items", cartController.AddItem)
+			cartGroup.PUT("/items/:product_id", cartController.UpdateItem)
+			cartGroup.DELETE("/items/:product_id", cartController.RemoveItem)
+		}
+
+		orderGroup := api.Group("/orders", jwtMiddleware.MiddlewareFunc(), sessionActivityMiddleware)
+		{
+			orderGroup.POST("/checkout", middlewares.RequirePermission(a.Services.Permissions, services.PermissionPlaceOrder), orderController.Checkout)
+			orderGroup.GET("", orderController.GetOrders)
+			orderGroup.GET("/:id", orderController.GetOrder)
+			orderGroup.POST("/:id/status", orderController.UpdateOrderStatus)
+			orderGroup.POST("/:id/pay", middlewares.RequirePermission(a.Services.Permissions, services.PermissionPlaceOrder), paymentController.PayOrder)
+			orderGroup.GET("/:id/payments", paymentController.GetOrderPayments)
+			orderGroup.POST("/:id/refund", requireManageUsers, paymentController.RefundOrder)
+		}
+		// Called by the payment provider, authenticated by its signature
+		api.POST("/payments/webhook", paymentController.Webhook)
+		vendorApplicationGroup := api.Group("/vendor-applications", jwtMiddleware.MiddlewareFunc(), sessionActivityMiddleware)
+		{
+			vendorApplicationGroup.POST("", vendorController.SubmitApplication)
+			vendorApplicationGroup.GET("/me", vendorController.GetMyApplications)
+		}
+		vendorGroup := api.Group("/vendor", jwtMiddleware.MiddlewareFunc(), sessionActivityMiddleware, middlewares.RequirePermission(a.Services.Permissions, services.PermissionCreateProduct))
+		{
+			vendorGroup.GET("/orders", orderController.GetVendorOrders)
+			vendorGroup.GET("/inventory/low-stock", productController.GetLowStockProducts)
+			vendorGroup.GET("/dashboard", vendorController.GetDashboard)
+		}
+
+		// Admins can't use admin routes while impersonating someone
+		adminGroup := api.Group("/admin", jwtMiddleware.MiddlewareFunc(), sessionActivityMiddleware, denyImpersonation) // Apply JWT middleware here
+		{
+			adminGroup.GET("/users", requireManageUsers, adminController.GetAllUsers)
+			adminGroup.PUT("/users/:id", requireManageUsers, adminController.EditUser)
+			adminGroup.DELETE("/users/:id", requireManageUsers, adminController.DeleteUser)
+			adminGroup.POST("/users/:id/erase", requireManageUsers, privacyController.EraseUser)
+			adminGroup.GET("/audit", requireManageUsers, auditController.GetAuditLogs)
+			adminGroup.POST("/webhooks", requireManageUsers, webhookController.RegisterEndpoint)
+			adminGroup.GET("/webhooks", requireManageUsers, webhookController.GetEndpoints)
+			adminGroup.DELETE("/webhooks/:id", requireManageUsers, webhookController.DeleteEndpoint)
+			adminGroup.GET("/webhooks/deliveries", requireManageUsers, webhookController.GetDeliveries)
+			adminGroup.POST("/webhooks/deliveries/:id/replay", requireManageUsers, webhookController.ReplayDelivery)
+			adminGroup.GET("/vendor-applications", requireManageUsers, vendorController.GetApplications)
+			adminGroup.POST("/vendor-applications/:id/approve", requireManageUsers, vendorController.ApproveApplication)
+			adminGroup.POST("/vendor-applications/:id/reject", requireManageUsers, vendorController.RejectApplication)
+			adminGroup.POST("/impersonate/:id", middlewares.RequirePermission(a.Services.Permissions, services.PermissionImpersonateUsers), impersonationController.StartImpersonation)
+		}
+	}
+
+	return router
+}
@@ -0,0 +1,52 @@
+package app_test
+
+import (
+	"backendGoAuth/internal/app"
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/services"
+	"net/http"
+	"testing"
+)
+
+// fakeWebhooks serves the webhook routes without a database.
+type fakeWebhooks struct {
+	services.WebhookServiceInterface
+	endpoints []entities.WebhookEndpoint
+	replayed  []int64
+}
+
+func (f *fakeWebhooks) GetEndpoints() ([]entities.WebhookEndpoint, error) {
+	return f.endpoints, nil
+}
+
+func (f *fakeWebhooks) ReplayDelivery(deliveryID int64) error {
+	if deliveryID != 7 {
+		return goAuthException.NewCustomError(goAuthException.NotFoundCode, "Webhook delivery not found")
+	}
+	f.replayed = append(f.replayed, deliveryID)
+	return nil
+}
+
+func TestAdminWebhookRoutes(t *testing.T) {
+	ts := newTestServer(t, nil)
+	webhooks := &fakeWebhooks{endpoints: []entities.WebhookEndpoint{{ID: 1, URL: "https://hooks.example.com", IsActive: true}}}
+	ts.replaceServices(func(s *app.Services) { s.Webhooks = webhooks })
+	_, admin := ts.registerAdmin("admin", "Password1!")
+
+	resp, body := ts.do(admin, http.MethodGet, "/api/admin/webhooks", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	var endpoints []entities.WebhookEndpoint
+	decode(t, body, &endpoints)
+	if len(endpoints) != 1 || endpoints[0].URL != "https://hooks.example.com" {
+		t.Fatalf("got endpoints %s, want the one registered", body)
+	}
+
+	resp, body = ts.do(admin, http.MethodPost, "/api/admin/webhooks/deliveries/7/replay", nil)
+	expectStatus(t, resp, body, http.StatusAccepted)
+	resp, body = ts.do(admin, http.MethodPost, "/api/admin/webhooks/deliveries/8/replay", nil)
+	expectProblem(t, resp, body, http.StatusNotFound, goAuthException.ErrorCodeNotFound)
+	if len(webhooks.replayed) != 1 {
+		t.Fatalf("got %d deliveries replayed, want 1", len(webhooks.replayed))
+	}
+}
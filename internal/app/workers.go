@@ -0,0 +1,47 @@
+package app
+
+import (
+	"context"
+	"sync"
+)
+
+// backgroundWorkers runs the long-lived goroutines (trackers, dispatchers, reapers and
+// listeners) under one context so shutdown can stop them and wait for them to finish.
+type backgroundWorkers struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// newBackgroundWorkers creates a new instance of backgroundWorkers.
+func newBackgroundWorkers() *backgroundWorkers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &backgroundWorkers{ctx: ctx, cancel: cancel}
+}
+
+// Go runs fn in a goroutine until the workers are stopped.
+func (w *backgroundWorkers) Go(fn func(ctx context.Context)) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		fn(w.ctx)
+	}()
+}
+
+// Stop cancels every worker and waits for them to return, or for ctx to expire.
+func (w *backgroundWorkers) Stop(ctx context.Context) error {
+	w.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
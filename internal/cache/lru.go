@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lru is a bounded LRU cache with a TTL, keyed by ID. The caches are built on it.
+type lru[V any] struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	items    map[int]*list.Element
+	order    *list.List // front = most recently used
+}
+
+type lruEntry[V any] struct {
+	key       int
+	value     V
+	expiresAt time.Time
+}
+
+// newLRU creates a new lru holding at most capacity entries, each valid for ttl.
+func newLRU[V any](capacity int, ttl time.Duration) *lru[V] {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &lru[V]{
+		capacity: capacity,
+		ttl:      ttl,
+		items:    make(map[int]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// get returns the cached value of a key and whether it was found.
+func (c *lru[V]) get(key int) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	elem, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+
+	entry := elem.Value.(*lruEntry[V])
+	if time.Now().After(entry.expiresAt) {
+		c.removeElement(elem)
+		return zero, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// set stores the value of a key, evicting the least recently used entry when the cache is full.
+func (c *lru[V]) set(key int, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry[V])
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	elem := c.order.PushFront(&lruEntry[V]{key: key, value: value, expiresAt: expiresAt})
+	c.items[key] = elem
+
+	if c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// invalidate drops a key from the cache.
+func (c *lru[V]) invalidate(key int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// purge drops every entry from the cache.
+func (c *lru[V]) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[int]*list.Element)
+	c.order.Init()
+}
+
+// len returns the number of cached entries.
+func (c *lru[V]) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *lru[V]) removeElement(elem *list.Element) {
+	entry := elem.Value.(*lruEntry[V])
+	delete(c.items, entry.key)
+	c.order.Remove(elem)
+}
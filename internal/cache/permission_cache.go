@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"time"
+)
+
+// PermissionCache holds each user's permission set for a short TTL, so permission
+// checks on every request don't each query the roles tables. Entries are dropped
+// when the user's roles change.
+type PermissionCache struct {
+	entries *lru[map[string]bool]
+}
+
+// NewPermissionCache creates a new PermissionCache holding at most capacity users,
+// each valid for ttl.
+func NewPermissionCache(capacity int, ttl time.Duration) *PermissionCache {
+	return &PermissionCache{entries: newLRU[map[string]bool](capacity, ttl)}
+}
+
+// Get returns the cached permissions of a user and whether they were found.
+func (c *PermissionCache) Get(userID int) (map[string]bool, bool) {
+	return c.entries.get(userID)
+}
+
+// Set stores the permissions of a user, evicting the least recently used user when
+// the cache is full.
+func (c *PermissionCache) Set(userID int, permissions map[string]bool) {
+	c.entries.set(userID, permissions)
+}
+
+// Invalidate removes a user from the cache.
+func (c *PermissionCache) Invalidate(userID int) {
+	c.entries.invalidate(userID)
+}
+
+// Purge removes every user from the cache.
+func (c *PermissionCache) Purge() {
+	c.entries.purge()
+}
@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"time"
+)
+
+// SessionCache is a bounded LRU cache of session activity state with a TTL.
+// It sits in front of the session repository so authenticated requests don't
+// hit Postgres on every call.
+type SessionCache struct {
+	entries *lru[bool]
+}
+
+// NewSessionCache creates a new SessionCache holding at most capacity entries,
+// each valid for ttl.
+func NewSessionCache(capacity int, ttl time.Duration) *SessionCache {
+	return &SessionCache{entries: newLRU[bool](capacity, ttl)}
+}
+
+// Get returns the cached activity state of a session and whether it was found.
+func (c *SessionCache) Get(sessionID int) (bool, bool) {
+	return c.entries.get(sessionID)
+}
+
+// Set stores the activity state of a session, evicting the least recently used
+// entry when the cache is full.
+func (c *SessionCache) Set(sessionID int, isActive bool) {
+	c.entries.set(sessionID, isActive)
+}
+
+// Invalidate drops a session from the cache.
+func (c *SessionCache) Invalidate(sessionID int) {
+	c.entries.invalidate(sessionID)
+}
+
+// Purge drops every entry from the cache.
+func (c *SessionCache) Purge() {
+	c.entries.purge()
+}
+
+// Len returns the number of cached sessions.
+func (c *SessionCache) Len() int {
+	return c.entries.len()
+}
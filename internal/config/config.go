@@ -0,0 +1,367 @@
+// Package config loads the service configuration. Every setting has a default, which a YAML
+// file, then environment variables (read from .env as well), then command-line flags override.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// MaxImpersonationDuration is the hard upper bound for impersonation tokens, whatever the
+// configuration says.
+const MaxImpersonationDuration = time.Hour
+
+// Masked replaces secrets when the configuration is shown.
+const Masked = "********"
+
+// Config is the whole service configuration.
+type Config struct {
+	Environment string           `yaml:"environment"`
+	Server      ServerConfig     `yaml:"server"`
+	Admin       AdminConfig      `yaml:"admin"`
+	Database    DatabaseConfig   `yaml:"database"`
+	JWT         JWTConfig        `yaml:"jwt"`
+	CORS        CORSConfig       `yaml:"cors"`
+	Log         LogConfig        `yaml:"log"`
+	Tracing     TracingConfig    `yaml:"tracing"`
+	Health      HealthConfig     `yaml:"health"`
+	Auth        AuthConfig       `yaml:"auth"`
+	Sessions    SessionConfig    `yaml:"sessions"`
+	Permissions PermissionConfig `yaml:"permissions"`
+	Audit       AuditConfig      `yaml:"audit"`
+	Webhooks    WebhookConfig    `yaml:"webhooks"`
+	Orders      OrderConfig      `yaml:"orders"`
+	Payments    PaymentConfig    `yaml:"payments"`
+	Privacy     PrivacyConfig    `yaml:"privacy"`
+}
+
+// ServerConfig configures the API server and its shutdown.
+type ServerConfig struct {
+	Port                   int           `yaml:"port"`
+	ReadHeaderTimeout      time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout            time.Duration `yaml:"read_timeout"`
+	WriteTimeout           time.Duration `yaml:"write_timeout"`
+	IdleTimeout            time.Duration `yaml:"idle_timeout"`
+	MaxHeaderBytes         int           `yaml:"max_header_bytes"`
+	TLSCertFile            string        `yaml:"tls_cert_file"`
+	TLSKeyFile             string        `yaml:"tls_key_file"`
+	ShutdownTimeout        time.Duration `yaml:"shutdown_timeout"`
+	ShutdownReadinessDelay time.Duration `yaml:"shutdown_readiness_delay"`
+}
+
+// AdminConfig configures the listener serving metrics, pprof and health checks.
+type AdminConfig struct {
+	Addr              string `yaml:"addr"`
+	BearerToken       string `yaml:"bearer_token"`
+	BasicAuthUser     string `yaml:"basic_auth_user"`
+	BasicAuthPassword string `yaml:"basic_auth_password"`
+}
+
+// DatabaseConfig configures the Postgres connection.
+type DatabaseConfig struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+}
+
+// JWTConfig configures the tokens issued to users.
+type JWTConfig struct {
+	Secret                string        `yaml:"secret"`
+	Duration              time.Duration `yaml:"duration"`
+	ImpersonationDuration time.Duration `yaml:"impersonation_duration"`
+	ImpersonationSweep    time.Duration `yaml:"impersonation_sweep"` // how often expired impersonation sessions are ended
+}
+
+// CORSConfig configures the origins browsers may call the API from.
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+// LogConfig configures the logger.
+type LogConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// TracingConfig configures tracing. The exporter itself is configured with the standard
+// OTEL_EXPORTER_OTLP_* variables.
+type TracingConfig struct {
+	Exporter string `yaml:"exporter"`
+}
+
+// HealthConfig configures the readiness checks.
+type HealthConfig struct {
+	CheckTimeout time.Duration `yaml:"check_timeout"`
+}
+
+// AuthConfig configures logins.
+type AuthConfig struct {
+	MaxLoginAttempts int `yaml:"max_login_attempts"`
+}
+
+// SessionConfig configures the session cache and activity tracking.
+type SessionConfig struct {
+	CacheSize            int           `yaml:"cache_size"`
+	CacheTTL             time.Duration `yaml:"cache_ttl"`
+	ActivityFlush        time.Duration `yaml:"activity_flush"`
+	ActivityHistoryLimit int           `yaml:"activity_history_limit"`
+}
+
+// PermissionConfig configures the permission cache.
+type PermissionConfig struct {
+	CacheSize int           `yaml:"cache_size"`
+	CacheTTL  time.Duration `yaml:"cache_ttl"`
+}
+
+// AuditConfig configures the audit log hash chain.
+type AuditConfig struct {
+	HMACKey      string        `yaml:"hmac_key"`
+	SealInterval time.Duration `yaml:"seal_interval"` // how often new entries are sealed into the chain
+}
+
+// WebhookConfig configures webhook delivery.
+type WebhookConfig struct {
+	PollInterval time.Duration `yaml:"poll_interval"`
+	MaxAttempts  int           `yaml:"max_attempts"`
+}
+
+// OrderConfig configures stock reservations of unpaid orders.
+type OrderConfig struct {
+	ReservationTTL        time.Duration `yaml:"reservation_ttl"`
+	ReservationReaperTick time.Duration `yaml:"reservation_reaper_tick"`
+}
+
+// PaymentConfig configures the payment provider.
+type PaymentConfig struct {
+	Provider          string        `yaml:"provider"`
+	WebhookSecret     string        `yaml:"webhook_secret"`
+	CallbackURL       string        `yaml:"callback_url"`
+	FakeCallbackDelay time.Duration `yaml:"fake_callback_delay"`
+}
+
+// PrivacyConfig configures account erasure.
+type PrivacyConfig struct {
+	ErasureGracePeriod time.Duration `yaml:"erasure_grace_period"`
+	ErasureSweep       time.Duration `yaml:"erasure_sweep"`
+}
+
+// Default returns the configuration used for every setting that isn't set.
+func Default() *Config {
+	return &Config{
+		Environment: "development",
+		Server: ServerConfig{
+			Port:              8080,
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			MaxHeaderBytes:    1 << 20,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Admin:    AdminConfig{Addr: "127.0.0.1:9091"},
+		Database: DatabaseConfig{Host: "localhost", Port: "5432"},
+		JWT: JWTConfig{
+			Duration:              24 * time.Hour,
+			ImpersonationDuration: 15 * time.Minute,
+			ImpersonationSweep:    time.Minute,
+		},
+		CORS:        CORSConfig{AllowedOrigins: []string{"http://localhost:5173"}},
+		Log:         LogConfig{Level: "info", Format: "json"},
+		Health:      HealthConfig{CheckTimeout: 2 * time.Second},
+		Audit:       AuditConfig{SealInterval: 5 * time.Second},
+		Auth:        AuthConfig{MaxLoginAttempts: 5},
+		Sessions:    SessionConfig{CacheSize: 10000, CacheTTL: 30 * time.Second, ActivityFlush: 10 * time.Second, ActivityHistoryLimit: 20},
+		Permissions: PermissionConfig{CacheSize: 10000, CacheTTL: 30 * time.Second},
+		Webhooks:    WebhookConfig{PollInterval: 5 * time.Second, MaxAttempts: 8},
+		Orders:      OrderConfig{ReservationTTL: 15 * time.Minute, ReservationReaperTick: time.Minute},
+		Payments:    PaymentConfig{Provider: "fake", FakeCallbackDelay: 2 * time.Second},
+		Privacy:     PrivacyConfig{ErasureGracePeriod: 30 * 24 * time.Hour, ErasureSweep: time.Hour},
+	}
+}
+
+// Load reads the configuration from the file named by -config or CONFIG_FILE, the environment
+// and the flags in args. It doesn't validate it, so an invalid configuration can still be
+// printed; call Validate before using it. It returns the arguments left after the flags,
+// which name a command to run instead of the server.
+func Load(args []string) (*Config, []string, error) {
+	// A missing .env is fine: the variables may be set in the environment already
+	if err := godotenv.Load(".env"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("reading .env: %w", err)
+	}
+
+	cfg := Default()
+	flags := flag.NewFlagSet("goAuth", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML configuration file")
+	port := flags.Int("port", 0, "API server port")
+	adminAddr := flags.String("admin-addr", "", "admin listener address")
+	logLevel := flags.String("log-level", "", "log level: debug, info, warn or error")
+	logFormat := flags.String("log-format", "", "log format: json or text")
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := cfg.loadEnv(os.LookupEnv); err != nil {
+		return nil, nil, err
+	}
+
+	// Only the flags given on the command line override the file and environment
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			cfg.Server.Port = *port
+		case "admin-addr":
+			cfg.Admin.Addr = *adminAddr
+		case "log-level":
+			cfg.Log.Level = *logLevel
+		case "log-format":
+			cfg.Log.Format = *logFormat
+		}
+	})
+
+	return cfg, flags.Args(), nil
+}
+
+// loadFile overrides the settings present in a YAML file.
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate checks that the configuration is complete and consistent, reporting every problem at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port < 65536, "server.port must be between 1 and 65535, got %d", c.Server.Port)
+	check((c.Server.TLSCertFile == "") == (c.Server.TLSKeyFile == ""), "server.tls_cert_file and server.tls_key_file must be set together")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Admin.Addr != "", "admin.addr is required")
+	check((c.Admin.BasicAuthUser == "") == (c.Admin.BasicAuthPassword == ""), "admin.basic_auth_user and admin.basic_auth_password must be set together")
+	check(c.Database.Host != "", "database.host is required")
+	check(c.Database.User != "", "database.user is required")
+	check(c.Database.Name != "", "database.name is required")
+	check(c.JWT.Secret != "", "jwt.secret is required")
+	check(c.JWT.Duration > 0, "jwt.duration must be positive")
+	check(c.JWT.ImpersonationDuration > 0 && c.JWT.ImpersonationDuration <= MaxImpersonationDuration,
+		"jwt.impersonation_duration must be positive and at most %s", MaxImpersonationDuration)
+	check(c.JWT.ImpersonationSweep > 0, "jwt.impersonation_sweep must be positive")
+	check(len(c.CORS.AllowedOrigins) > 0, "cors.allowed_origins must list at least one origin")
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level %q is invalid", c.Log.Level)
+	check(c.Log.Format == "json" || c.Log.Format == "text", "log.format must be json or text, got %q", c.Log.Format)
+	check(c.Tracing.Exporter == "" || c.Tracing.Exporter == "none" || c.Tracing.Exporter == "otlp",
+		"tracing.exporter must be otlp or none, got %q", c.Tracing.Exporter)
+	check(c.Health.CheckTimeout > 0, "health.check_timeout must be positive")
+	check(c.Audit.HMACKey != "", "audit.hmac_key is required")
+	check(c.Audit.SealInterval > 0, "audit.seal_interval must be positive")
+	check(c.Auth.MaxLoginAttempts > 0, "auth.max_login_attempts must be positive")
+	check(c.Sessions.CacheSize > 0 && c.Sessions.CacheTTL > 0, "sessions.cache_size and sessions.cache_ttl must be positive")
+	check(c.Sessions.ActivityFlush > 0, "sessions.activity_flush must be positive")
+	check(c.Permissions.CacheSize > 0 && c.Permissions.CacheTTL > 0, "permissions.cache_size and permissions.cache_ttl must be positive")
+	check(c.Webhooks.PollInterval > 0 && c.Webhooks.MaxAttempts > 0, "webhooks.poll_interval and webhooks.max_attempts must be positive")
+	check(c.Orders.ReservationTTL > 0 && c.Orders.ReservationReaperTick > 0, "orders.reservation_ttl and orders.reservation_reaper_tick must be positive")
+	check(c.Payments.Provider == "fake", "payments.provider %q is unknown", c.Payments.Provider)
+	check(c.Payments.WebhookSecret != "", "payments.webhook_secret is required")
+	check(c.Privacy.ErasureGracePeriod >= 0, "privacy.erasure_grace_period can't be negative")
+	check(c.Privacy.ErasureSweep > 0, "privacy.erasure_sweep must be positive")
+	return errors.Join(errs...)
+}
+
+// Secrets returns the secret settings that are set, so they can be scrubbed from logs.
+func (c *Config) Secrets() []string {
+	var secrets []string
+	for _, secret := range c.secretFields() {
+		if *secret != "" {
+			secrets = append(secrets, *secret)
+		}
+	}
+	return secrets
+}
+
+// Masked returns a copy of the configuration with its secrets masked, safe to show.
+func (c *Config) Masked() *Config {
+	masked := *c
+	masked.CORS.AllowedOrigins = append([]string(nil), c.CORS.AllowedOrigins...)
+	for _, secret := range masked.secretFields() {
+		if *secret != "" {
+			*secret = Masked
+		}
+	}
+	return &masked
+}
+
+// secretFields points to every secret setting.
+func (c *Config) secretFields() []*string {
+	return []*string{
+		&c.Database.Password,
+		&c.JWT.Secret,
+		&c.Admin.BearerToken,
+		&c.Admin.BasicAuthPassword,
+		&c.Audit.HMACKey,
+		&c.Payments.WebhookSecret,
+	}
+}
+
+// APIAddr is the address the API server listens on.
+func (c *Config) APIAddr() string {
+	return fmt.Sprintf(":%d", c.Server.Port)
+}
+
+// PaymentCallbackURL is where the fake payment provider posts its webhooks, the API's own
+// webhook endpoint unless set.
+func (c *Config) PaymentCallbackURL() string {
+	if c.Payments.CallbackURL != "" {
+		return c.Payments.CallbackURL
+	}
+	return fmt.Sprintf("http://localhost:%d/api/payments/webhook", c.Server.Port)
+}
+
+// Print writes the configuration as YAML with its secrets masked.
+func (c *Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c.Masked()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
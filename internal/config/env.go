@@ -0,0 +1,133 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// envLoader overrides settings with the environment variables that are set, collecting
+// the values that can't be parsed.
+type envLoader struct {
+	lookup func(key string) (string, bool)
+	errs   []error
+}
+
+// loadEnv overrides the settings whose environment variables are set. Durations keep the
+// units their variables have always had, given by the variable name.
+func (c *Config) loadEnv(lookup func(key string) (string, bool)) error {
+	env := &envLoader{lookup: lookup}
+
+	env.string(&c.Environment, "ENVIRONMENT")
+
+	env.int(&c.Server.Port, "PORT")
+	env.duration(&c.Server.ReadHeaderTimeout, "HTTP_READ_HEADER_TIMEOUT_SECONDS", time.Second)
+	env.duration(&c.Server.ReadTimeout, "HTTP_READ_TIMEOUT_SECONDS", time.Second)
+	env.duration(&c.Server.WriteTimeout, "HTTP_WRITE_TIMEOUT_SECONDS", time.Second)
+	env.duration(&c.Server.IdleTimeout, "HTTP_IDLE_TIMEOUT_SECONDS", time.Second)
+	env.int(&c.Server.MaxHeaderBytes, "HTTP_MAX_HEADER_BYTES")
+	env.string(&c.Server.TLSCertFile, "TLS_CERT_FILE")
+	env.string(&c.Server.TLSKeyFile, "TLS_KEY_FILE")
+	env.duration(&c.Server.ShutdownTimeout, "SHUTDOWN_TIMEOUT_SECONDS", time.Second)
+	env.duration(&c.Server.ShutdownReadinessDelay, "SHUTDOWN_READINESS_DELAY_SECONDS", time.Second)
+
+	env.string(&c.Admin.Addr, "ADMIN_ADDR")
+	env.string(&c.Admin.BearerToken, "ADMIN_BEARER_TOKEN")
+	env.string(&c.Admin.BasicAuthUser, "ADMIN_BASIC_AUTH_USER")
+	env.string(&c.Admin.BasicAuthPassword, "ADMIN_BASIC_AUTH_PASSWORD")
+
+	env.string(&c.Database.Host, "DB_HOST")
+	env.string(&c.Database.Port, "DB_PORT")
+	env.string(&c.Database.User, "DB_USER")
+	env.string(&c.Database.Password, "DB_PASSWORD")
+	env.string(&c.Database.Name, "DB_NAME")
+
+	env.string(&c.JWT.Secret, "JWT_SECRET")
+	env.duration(&c.JWT.Duration, "JWT_DURATION_HOURS", time.Hour)
+	env.duration(&c.JWT.ImpersonationDuration, "IMPERSONATION_DURATION_MINUTES", time.Minute)
+	env.duration(&c.JWT.ImpersonationSweep, "IMPERSONATION_SWEEP_SECONDS", time.Second)
+
+	env.list(&c.CORS.AllowedOrigins, "CORS_ALLOWED_ORIGINS")
+
+	env.string(&c.Log.Level, "LOG_LEVEL")
+	env.string(&c.Log.Format, "LOG_FORMAT")
+
+	env.string(&c.Tracing.Exporter, "OTEL_TRACES_EXPORTER")
+
+	env.duration(&c.Health.CheckTimeout, "HEALTH_CHECK_TIMEOUT_SECONDS", time.Second)
+
+	env.int(&c.Auth.MaxLoginAttempts, "MAX_LOGIN_ATTEMPTS")
+
+	env.int(&c.Sessions.CacheSize, "SESSION_CACHE_SIZE")
+	env.duration(&c.Sessions.CacheTTL, "SESSION_CACHE_TTL_SECONDS", time.Second)
+	env.duration(&c.Sessions.ActivityFlush, "SESSION_ACTIVITY_FLUSH_SECONDS", time.Second)
+	env.int(&c.Sessions.ActivityHistoryLimit, "SESSION_ACTIVITY_HISTORY_LIMIT")
+
+	env.int(&c.Permissions.CacheSize, "PERMISSION_CACHE_SIZE")
+	env.duration(&c.Permissions.CacheTTL, "PERMISSION_CACHE_TTL_SECONDS", time.Second)
+
+	env.string(&c.Audit.HMACKey, "AUDIT_HMAC_KEY")
+	env.duration(&c.Audit.SealInterval, "AUDIT_SEAL_SECONDS", time.Second)
+
+	env.duration(&c.Webhooks.PollInterval, "WEBHOOK_POLL_SECONDS", time.Second)
+	env.int(&c.Webhooks.MaxAttempts, "WEBHOOK_MAX_ATTEMPTS")
+
+	env.duration(&c.Orders.ReservationTTL, "STOCK_RESERVATION_MINUTES", time.Minute)
+	env.duration(&c.Orders.ReservationReaperTick, "STOCK_REAPER_SECONDS", time.Second)
+
+	env.string(&c.Payments.Provider, "PAYMENT_PROVIDER")
+	env.string(&c.Payments.WebhookSecret, "PAYMENT_WEBHOOK_SECRET")
+	env.string(&c.Payments.CallbackURL, "PAYMENT_CALLBACK_URL")
+	env.duration(&c.Payments.FakeCallbackDelay, "PAYMENT_FAKE_CALLBACK_SECONDS", time.Second)
+
+	env.duration(&c.Privacy.ErasureGracePeriod, "ERASURE_GRACE_DAYS", 24*time.Hour)
+	env.duration(&c.Privacy.ErasureSweep, "ERASURE_SWEEP_SECONDS", time.Second)
+
+	return errors.Join(env.errs...)
+}
+
+// get returns the value of a variable that is set and not empty.
+func (e *envLoader) get(key string) (string, bool) {
+	value, ok := e.lookup(key)
+	return value, ok && value != ""
+}
+
+func (e *envLoader) string(target *string, key string) {
+	if value, ok := e.get(key); ok {
+		*target = value
+	}
+}
+
+func (e *envLoader) list(target *[]string, key string) {
+	if value, ok := e.get(key); ok {
+		*target = splitList(value)
+	}
+}
+
+func (e *envLoader) int(target *int, key string) {
+	value, ok := e.get(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s must be an integer, got %q", key, value))
+		return
+	}
+	*target = parsed
+}
+
+// duration reads a whole number of units.
+func (e *envLoader) duration(target *time.Duration, key string, unit time.Duration) {
+	value, ok := e.get(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s must be an integer, got %q", key, value))
+		return
+	}
+	*target = time.Duration(parsed) * unit
+}
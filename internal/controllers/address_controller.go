@@ -0,0 +1,109 @@
+package controllers
+
+//AddressController
+
+import (
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type AddressController struct {
+	addressService services.AddressServiceInterface
+}
+
+// NewAddressController creates a new instance of AddressController.
+func NewAddressController(addressService services.AddressServiceInterface) *AddressController {
+	return &AddressController{
+		addressService: addressService,
+	}
+}
+
+// GetAddresses returns the current user's address book.
+func (controller *AddressController) GetAddresses(c *gin.Context) {
+	addresses, err := controller.addressService.GetAddresses(c.GetInt("user_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, addresses)
+}
+
+// GetAddress returns one of the current user's addresses.
+func (controller *AddressController) GetAddress(c *gin.Context) {
+	addressID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid address ID"))
+		return
+	}
+
+	address, err := controller.addressService.GetAddress(c.GetInt("user_id"), addressID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, address)
+}
+
+// CreateAddress adds an address to the current user's address book.
+func (controller *AddressController) CreateAddress(c *gin.Context) {
+	var req models.AddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(goAuthException.NewValidationError(err))
+		return
+	}
+
+	address, err := controller.addressService.CreateAddress(c.GetInt("user_id"), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, address)
+}
+
+// UpdateAddress changes one of the current user's addresses. The response may carry a new ID
+// if the old address was kept for past orders.
+func (controller *AddressController) UpdateAddress(c *gin.Context) {
+	addressID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid address ID"))
+		return
+	}
+
+	var req models.AddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(goAuthException.NewValidationError(err))
+		return
+	}
+
+	address, err := controller.addressService.UpdateAddress(c.GetInt("user_id"), addressID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, address)
+}
+
+// DeleteAddress removes one of the current user's addresses.
+func (controller *AddressController) DeleteAddress(c *gin.Context) {
+	addressID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid address ID"))
+		return
+	}
+
+	archived, err := controller.addressService.DeleteAddress(c.GetInt("user_id"), addressID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Address deleted successfully", "archived": archived})
+}
@@ -3,82 +3,101 @@ package controllers
 //AdminController
 
 import (
-	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/models"
 	"backendGoAuth/internal/services"
-	"encoding/json"
 	"github.com/gin-gonic/gin"
-	"github.com/gorilla/mux"
 	"net/http"
 	"strconv"
 )
 
 type AdminController struct {
-	service services.AdminService
+	service      services.AdminService
+	auditService services.AuditServiceInterface
 }
 
-func NewAdminController(service services.AdminService) *AdminController {
-	return &AdminController{service}
+func NewAdminController(service services.AdminService, auditService services.AuditServiceInterface) *AdminController {
+	return &AdminController{service, auditService}
 }
 
-//func (c *AdminController) GetAllUsers(w http.ResponseWriter, r *http.Request) {
-//	users, err := c.service.GetAllUsers()
-//	if err != nil {
-//		http.Error(w, "Failed to fetch users", http.StatusInternalServerError)
-//		return
-//	}
-//
-//	w.Header().Set("Content-Type", "application/json")
-//	err = json.NewEncoder(w).Encode(users)
-//	if err != nil {
-//		return
-//	}
-//}
-
 func (c *AdminController) GetAllUsers(ctx *gin.Context) {
-	users, err := c.service.GetAllUsers()
+	users, err := c.service.GetAllUsers(ctx.Request.Context())
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		ctx.Error(goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to fetch users"))
 		return
 	}
 
 	ctx.JSON(http.StatusOK, users)
 }
 
-func (c *AdminController) EditUser(w http.ResponseWriter, r *http.Request) {
-	var user entities.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+func (c *AdminController) EditUser(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid user ID"))
 		return
 	}
 
-	if err := c.service.EditUser(user); err != nil {
-		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+	var req models.EditUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(goAuthException.NewValidationError(err))
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	_, err := w.Write([]byte("User updated successfully"))
+	before, err := c.service.GetUserByID(ctx.Request.Context(), userID)
 	if err != nil {
+		ctx.Error(goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to fetch user"))
 		return
 	}
-}
-
-func (c *AdminController) DeleteUser(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	userID, err := strconv.Atoi(vars["id"])
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	if before == nil {
+		ctx.Error(goAuthException.NewCustomError(goAuthException.NotFoundCode, "User not found"))
 		return
 	}
 
-	if err := c.service.DeleteUser(userID); err != nil {
-		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
+	after := *before
+	after.Username = req.Username
+	after.Email = req.Email
+	after.IsBlocked = req.IsBlocked
+	after.LoginAttempts = req.LoginAttempts
+
+	if err := c.service.EditUser(ctx.Request.Context(), after); err != nil {
+		ctx.Error(goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to update user"))
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	_, err = w.Write([]byte("User deleted successfully"))
+	c.auditService.RecordBestEffort(services.AuditEvent{
+		Actor:     auditActor(ctx),
+		TargetID:  userID,
+		Action:    services.AuditActionUserUpdated,
+		TableName: "users",
+		RowID:     userID,
+		Before:    before,
+		After:     after,
+	})
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
+}
+
+func (c *AdminController) DeleteUser(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
+		ctx.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid user ID"))
 		return
 	}
+
+	if err := c.service.DeleteUser(ctx.Request.Context(), userID); err != nil {
+		ctx.Error(goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to delete user"))
+		return
+	}
+
+	c.auditService.RecordBestEffort(services.AuditEvent{
+		Actor:     auditActor(ctx),
+		TargetID:  userID,
+		Action:    services.AuditActionUserDeleted,
+		TableName: "users",
+		RowID:     userID,
+		Before:    map[string]bool{"is_active": true},
+		After:     map[string]bool{"is_active": false},
+	})
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
@@ -0,0 +1,48 @@
+package controllers
+
+//AuditController
+
+import (
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type AuditController struct {
+	auditService services.AuditServiceInterface
+}
+
+// NewAuditController creates a new instance of AuditController.
+func NewAuditController(auditService services.AuditServiceInterface) *AuditController {
+	return &AuditController{
+		auditService: auditService,
+	}
+}
+
+// GetAuditLogs returns a filtered, paginated page of the audit log.
+func (controller *AuditController) GetAuditLogs(c *gin.Context) {
+	var req models.AuditLogQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(goAuthException.NewValidationError(err))
+		return
+	}
+
+	page, err := controller.auditService.QueryAuditLogs(req)
+	if err != nil {
+		c.Error(goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve audit logs"))
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// auditActor describes the authenticated user making the request for the audit log.
+func auditActor(c *gin.Context) services.AuditActor {
+	return services.AuditActor{
+		UserID:    c.GetInt("user_id"),
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	}
+}
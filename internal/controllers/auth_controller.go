@@ -3,25 +3,28 @@ package controllers
 //AuthController
 
 import (
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/logging"
 	"backendGoAuth/internal/models"
 	"backendGoAuth/internal/services"
 	"backendGoAuth/internal/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/mssola/user_agent"
-	"log"
 	"net/http"
 )
 
 type AuthController struct {
-	authService    *services.AuthService
-	sessionService *services.SessionService
+	authService    services.AuthServiceInterface
+	sessionService services.SessionServiceInterface
+	cartService    services.CartServiceInterface
 }
 
 // NewAuthController creates a new instance of AuthController.
-func NewAuthController(authService *services.AuthService, sessionService *services.SessionService) *AuthController {
+func NewAuthController(authService services.AuthServiceInterface, sessionService services.SessionServiceInterface, cartService services.CartServiceInterface) *AuthController {
 	return &AuthController{
 		authService:    authService,
 		sessionService: sessionService,
+		cartService:    cartService,
 	}
 }
 
@@ -29,7 +32,7 @@ func NewAuthController(authService *services.AuthService, sessionService *servic
 func (controller *AuthController) Register(c *gin.Context) {
 	var req models.RegistrationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		c.Error(goAuthException.NewValidationError(err))
 		return
 	}
 	userAgent := c.GetHeader("User-Agent")
@@ -38,9 +41,9 @@ func (controller *AuthController) Register(c *gin.Context) {
 	device := ua.OS()
 
 	ipAddress := c.ClientIP()
-	authResponse, err := controller.authService.RegisterUser(req, ipAddress, browser, device)
+	authResponse, err := controller.authService.RegisterUser(c.Request.Context(), req, ipAddress, browser, device)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user", "message": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -56,17 +59,24 @@ func (controller *AuthController) Login(c *gin.Context) {
 	device := ua.OS()
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		c.Error(goAuthException.NewValidationError(err))
 		return
 	}
 
 	ipAddress := c.ClientIP()
-	authResponse, err := controller.authService.AuthenticateUser(req.Identifier, req.Password, ipAddress, browser, device, c)
+	authResponse, err := controller.authService.AuthenticateUser(c.Request.Context(), req.Identifier, req.Password, ipAddress, browser, device, c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials", "message": err.Error()})
+		c.Error(err)
 		return
 	}
 
+	// Carry over what the user put in their cart before logging in; the login itself already succeeded
+	if len(req.GuestCart) > 0 {
+		if _, err := controller.cartService.MergeGuestCart(authResponse.User.ID, req.GuestCart); err != nil {
+			logging.FromContext(c.Request.Context()).Error("Error merging guest cart for user", "user_id", authResponse.User.ID, "error", err)
+		}
+	}
+
 	c.JSON(http.StatusOK, authResponse)
 }
 
@@ -75,14 +85,14 @@ func (controller *AuthController) RevokeCurrentSession(c *gin.Context) {
 	// Extract the session token from the request
 	token, err := utils.ExtractToken(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid token"})
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Missing or invalid token"))
 		return
 	}
 
-	// Revoke the session token
-	err = controller.sessionService.RevokeSession(token) // Assuming token is session ID
+	// Revoke the session the token belongs to
+	err = controller.sessionService.RevokeCurrentSessionToken(c.Request.Context(), token, auditActor(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session token"})
+		c.Error(goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to revoke session token"))
 		return
 	}
 
@@ -93,12 +103,17 @@ func (controller *AuthController) SecureEndpoint(c *gin.Context) {
 	// Access user ID from the context
 	userID, err := controller.sessionService.GetUserIDFromTokenOrSource(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(goAuthException.NewCustomError(goAuthException.UnauthorizedCode, "Unauthorized"))
 		return
 	}
 
 	// Your secure endpoint logic here
-	c.JSON(http.StatusOK, gin.H{"message": "Secure Endpoint", "user_id": userID})
+	response := gin.H{"message": "Secure Endpoint", "user_id": userID, "impersonated": false}
+	if impersonatorID, impersonating := c.Get("impersonator_id"); impersonating {
+		response["impersonated"] = true
+		response["impersonator_id"] = impersonatorID
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // GetActiveSessions retrieves active sessions for a user.
@@ -106,17 +121,16 @@ func (controller *AuthController) GetActiveSessions(c *gin.Context) {
 	// Extract the user ID from the context or request parameters
 	userID, err := controller.sessionService.GetUserIDFromTokenOrSource(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(goAuthException.NewCustomError(goAuthException.UnauthorizedCode, "Unauthorized"))
 		return
 	}
 
-	log.Printf("UserID from context: %v\n", userID)
+	logging.FromContext(c.Request.Context()).Debug("UserID from context", "user_id", userID)
 
 	// Retrieve active sessions for the user from the database
-	sessions, err := controller.sessionService.GetActiveSessions(userID)
+	sessions, err := controller.sessionService.GetActiveSessions(c.Request.Context(), userID)
 	if err != nil {
-		log.Printf("Error retrieving active sessions: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve active sessions"})
+		c.Error(goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve active sessions"))
 		return
 	}
 
@@ -128,14 +142,14 @@ func (ac *AuthController) RevokeSession(c *gin.Context) {
 	//todo check with the frontend later
 	sessionID := c.Query("session_id") // Assuming sessionID is sent as a query parameter
 	if sessionID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "session_id is required"})
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "session_id is required"))
 		return
 	}
 
 	// Call the RevokeSession method from the SessionService
-	err := ac.sessionService.RevokeSession(sessionID)
+	err := ac.sessionService.RevokeSession(c.Request.Context(), sessionID, auditActor(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		c.Error(err)
 		return
 	}
 
@@ -0,0 +1,124 @@
+package controllers
+
+//CartController
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type CartController struct {
+	cartService services.CartServiceInterface
+}
+
+// NewCartController creates a new instance of CartController.
+func NewCartController(cartService services.CartServiceInterface) *CartController {
+	return &CartController{
+		cartService: cartService,
+	}
+}
+
+// GetCart returns the current user's cart.
+func (controller *CartController) GetCart(c *gin.Context) {
+	cart, err := controller.cartService.GetCart(c.GetInt("user_id"))
+	controller.respond(c, http.StatusOK, cart, err)
+}
+
+// AddItem adds a product to the current user's cart.
+func (controller *CartController) AddItem(c *gin.Context) {
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var req models.CartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(goAuthException.NewValidationError(err))
+		return
+	}
+
+	cart, err := controller.cartService.AddItem(c.GetInt("user_id"), req, expectedVersion)
+	controller.respond(c, http.StatusOK, cart, err)
+}
+
+// UpdateItem sets the quantity of a product in the current user's cart.
+func (controller *CartController) UpdateItem(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid product ID"))
+		return
+	}
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var req models.CartItemUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(goAuthException.NewValidationError(err))
+		return
+	}
+
+	cart, err := controller.cartService.UpdateItem(c.GetInt("user_id"), productID, req.Quantity, expectedVersion)
+	controller.respond(c, http.StatusOK, cart, err)
+}
+
+// RemoveItem removes a product from the current user's cart.
+func (controller *CartController) RemoveItem(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid product ID"))
+		return
+	}
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	cart, err := controller.cartService.RemoveItem(c.GetInt("user_id"), productID, expectedVersion)
+	controller.respond(c, http.StatusOK, cart, err)
+}
+
+// ClearCart empties the current user's cart.
+func (controller *CartController) ClearCart(c *gin.Context) {
+	expectedVersion, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	cart, err := controller.cartService.ClearCart(c.GetInt("user_id"), expectedVersion)
+	controller.respond(c, http.StatusOK, cart, err)
+}
+
+// respond writes the cart with its version as ETag, so clients can send it back in If-Match.
+func (controller *CartController) respond(c *gin.Context, status int, cart *entities.Cart, err error) {
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", strconv.Quote(strconv.Itoa(cart.Version)))
+	c.JSON(status, cart)
+}
+
+// ifMatchVersion parses the optional If-Match header holding the cart version the client last saw.
+// It records a 400 error and returns false if the header is malformed.
+func ifMatchVersion(c *gin.Context) (*int, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" || header == "*" {
+		return nil, true
+	}
+
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(header, "W/"), `"`))
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid If-Match header"))
+		return nil, false
+	}
+	return &version, true
+}
@@ -0,0 +1,77 @@
+package controllers
+
+//ImpersonationController
+
+import (
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/services"
+	"backendGoAuth/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/mssola/user_agent"
+	"net/http"
+	"strconv"
+)
+
+type ImpersonationController struct {
+	impersonationService services.ImpersonationServiceInterface
+	tokens               *utils.TokenManager
+}
+
+// NewImpersonationController creates a new instance of ImpersonationController.
+func NewImpersonationController(impersonationService services.ImpersonationServiceInterface, tokens *utils.TokenManager) *ImpersonationController {
+	return &ImpersonationController{
+		impersonationService: impersonationService,
+		tokens:               tokens,
+	}
+}
+
+// StartImpersonation logs the current admin in as the user given in the path.
+func (controller *ImpersonationController) StartImpersonation(c *gin.Context) {
+	targetUserID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid user ID"))
+		return
+	}
+
+	originalToken, err := utils.ExtractToken(c)
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Missing or invalid token"))
+		return
+	}
+
+	ua := user_agent.New(c.GetHeader("User-Agent"))
+	browser, _ := ua.Browser()
+	device := ua.OS()
+
+	impersonator := auditActor(c)
+	token, user, err := controller.impersonationService.StartImpersonation(c.Request.Context(), impersonator, targetUserID, browser, device)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	controller.tokens.SetImpersonationCookies(c, token, originalToken)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Impersonation started", "user": user, "impersonator_id": impersonator.UserID})
+}
+
+// StopImpersonation ends the current impersonation session and restores the admin's own session.
+func (controller *ImpersonationController) StopImpersonation(c *gin.Context) {
+	impersonatorID, impersonating := c.Get("impersonator_id")
+	if !impersonating {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Not impersonating a user"))
+		return
+	}
+
+	impersonator := auditActor(c)
+	impersonator.UserID = impersonatorID.(int)
+	err := controller.impersonationService.StopImpersonation(c.Request.Context(), impersonator, c.GetInt("user_id"), c.GetInt("session_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	controller.tokens.ClearImpersonationCookies(c)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Impersonation stopped"})
+}
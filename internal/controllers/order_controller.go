@@ -0,0 +1,116 @@
+package controllers
+
+//OrderController
+
+import (
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type OrderController struct {
+	orderService services.OrderServiceInterface
+}
+
+// NewOrderController creates a new instance of OrderController.
+func NewOrderController(orderService services.OrderServiceInterface) *OrderController {
+	return &OrderController{
+		orderService: orderService,
+	}
+}
+
+// Checkout turns the current user's cart into an order.
+func (controller *OrderController) Checkout(c *gin.Context) {
+	var req models.CheckoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(goAuthException.NewValidationError(err))
+			return
+		}
+	}
+
+	order, err := controller.orderService.Checkout(c.GetInt("user_id"), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, order)
+}
+
+// GetOrders returns the current user's order history, optionally filtered by ?status=.
+func (controller *OrderController) GetOrders(c *gin.Context) {
+	var req models.OrderQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(goAuthException.NewValidationError(err))
+		return
+	}
+
+	page, err := controller.orderService.GetOrderHistory(c.GetInt("user_id"), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetVendorOrders returns the orders containing the current vendor's products.
+func (controller *OrderController) GetVendorOrders(c *gin.Context) {
+	var req models.OrderQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(goAuthException.NewValidationError(err))
+		return
+	}
+
+	page, err := controller.orderService.GetVendorOrders(c.GetInt("user_id"), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetOrder returns a single order.
+func (controller *OrderController) GetOrder(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid order ID"))
+		return
+	}
+
+	order, err := controller.orderService.GetOrder(c.GetInt("user_id"), orderID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// UpdateOrderStatus moves an order to a new status.
+func (controller *OrderController) UpdateOrderStatus(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid order ID"))
+		return
+	}
+
+	var req models.OrderStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(goAuthException.NewValidationError(err))
+		return
+	}
+
+	order, err := controller.orderService.TransitionOrder(c.GetInt("user_id"), orderID, req.Status)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
@@ -0,0 +1,117 @@
+package controllers
+
+//PaymentController
+
+import (
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/services"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// maxPaymentWebhookSize bounds the body read from payment provider webhooks.
+const maxPaymentWebhookSize = 64 << 10
+
+type PaymentController struct {
+	paymentService services.PaymentServiceInterface
+}
+
+// NewPaymentController creates a new instance of PaymentController.
+func NewPaymentController(paymentService services.PaymentServiceInterface) *PaymentController {
+	return &PaymentController{
+		paymentService: paymentService,
+	}
+}
+
+// PayOrder charges one of the current user's orders. The Idempotency-Key header is required,
+// so retries after a timeout don't charge twice.
+func (controller *PaymentController) PayOrder(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid order ID"))
+		return
+	}
+	idempotencyKey := c.GetHeader("Idempotency-Key")
+	if idempotencyKey == "" || len(idempotencyKey) > 200 {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Idempotency-Key header is required"))
+		return
+	}
+
+	var req models.PaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(goAuthException.NewValidationError(err))
+		return
+	}
+
+	payment, err := controller.paymentService.PayOrder(c.Request.Context(), c.GetInt("user_id"), orderID, req, idempotencyKey)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	switch payment.Status {
+	case services.PaymentSucceeded:
+		c.JSON(http.StatusOK, payment)
+	case services.PaymentPending:
+		c.JSON(http.StatusAccepted, payment)
+	default:
+		c.JSON(http.StatusPaymentRequired, payment)
+	}
+}
+
+// RefundOrder refunds a paid order.
+func (controller *PaymentController) RefundOrder(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid order ID"))
+		return
+	}
+
+	payment, err := controller.paymentService.RefundOrder(c.Request.Context(), c.GetInt("user_id"), orderID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if payment.Status != services.PaymentSucceeded {
+		c.JSON(http.StatusConflict, payment)
+		return
+	}
+	c.JSON(http.StatusOK, payment)
+}
+
+// GetOrderPayments returns the charges and refunds of an order.
+func (controller *PaymentController) GetOrderPayments(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid order ID"))
+		return
+	}
+
+	payments, err := controller.paymentService.GetOrderPayments(c.GetInt("user_id"), orderID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, payments)
+}
+
+// Webhook receives asynchronous payment outcomes from the payment provider.
+func (controller *PaymentController) Webhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPaymentWebhookSize))
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid request"))
+		return
+	}
+
+	if err := controller.paymentService.HandleWebhook(c.Request.Context(), c.Request.Header, body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook processed"})
+}
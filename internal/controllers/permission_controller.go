@@ -0,0 +1,32 @@
+package controllers
+
+//PermissionController
+
+import (
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type PermissionController struct {
+	permissionService services.PermissionServiceInterface
+}
+
+// NewPermissionController creates a new instance of PermissionController.
+func NewPermissionController(permissionService services.PermissionServiceInterface) *PermissionController {
+	return &PermissionController{
+		permissionService: permissionService,
+	}
+}
+
+// GetMyPermissions returns the current user's permissions, so clients can refresh them after a role change.
+func (controller *PermissionController) GetMyPermissions(c *gin.Context) {
+	permissions, err := controller.permissionService.GetPermissions(c.GetInt("user_id"))
+	if err != nil {
+		c.Error(goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve permissions"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"permissions": permissions})
+}
@@ -0,0 +1,87 @@
+package controllers
+
+//PrivacyController
+
+import (
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/services"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type PrivacyController struct {
+	privacyService services.PrivacyServiceInterface
+}
+
+// NewPrivacyController creates a new instance of PrivacyController.
+func NewPrivacyController(privacyService services.PrivacyServiceInterface) *PrivacyController {
+	return &PrivacyController{
+		privacyService: privacyService,
+	}
+}
+
+// ExportData returns everything stored about the current user as a downloadable JSON archive.
+func (controller *PrivacyController) ExportData(c *gin.Context) {
+	export, err := controller.privacyService.ExportUserData(auditActor(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%d-export.json"`, export.Profile.ID))
+	c.IndentedJSON(http.StatusOK, export)
+}
+
+// GetErasureRequest returns the current user's pending or completed erasure request.
+func (controller *PrivacyController) GetErasureRequest(c *gin.Context) {
+	request, err := controller.privacyService.GetErasureRequest(c.GetInt("user_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if request == nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.NotFoundCode, "No erasure request"))
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// RequestErasure schedules the current user's erasure after the grace period.
+func (controller *PrivacyController) RequestErasure(c *gin.Context) {
+	request, err := controller.privacyService.RequestErasure(auditActor(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, request)
+}
+
+// CancelErasure withdraws the current user's pending erasure request.
+func (controller *PrivacyController) CancelErasure(c *gin.Context) {
+	if err := controller.privacyService.CancelErasure(auditActor(c)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// EraseUser lets an admin erase a user right away, skipping the grace period.
+func (controller *PrivacyController) EraseUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid user ID"))
+		return
+	}
+
+	if err := controller.privacyService.EraseUser(auditActor(c), userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,188 @@
+package controllers
+
+//ProductController
+
+import (
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type ProductController struct {
+	productService   services.ProductServiceInterface
+	inventoryService services.InventoryServiceInterface
+}
+
+// NewProductController creates a new instance of ProductController.
+func NewProductController(productService services.ProductServiceInterface, inventoryService services.InventoryServiceInterface) *ProductController {
+	return &ProductController{
+		productService:   productService,
+		inventoryService: inventoryService,
+	}
+}
+
+// ListProducts returns a page of products, filtered by ?q=, ?category_id= and ?vendor_id=.
+func (controller *ProductController) ListProducts(c *gin.Context) {
+	var req models.ProductQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(goAuthException.NewValidationError(err))
+		return
+	}
+
+	page, err := controller.productService.ListProducts(req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// SearchProducts runs a ranked full-text search, filtered by ?category_id=, ?min_price= and ?max_price=.
+func (controller *ProductController) SearchProducts(c *gin.Context) {
+	var req models.ProductSearchQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(goAuthException.NewValidationError(err))
+		return
+	}
+
+	page, err := controller.productService.SearchProducts(req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// SuggestProducts returns product names completing ?q=, for autocomplete.
+func (controller *ProductController) SuggestProducts(c *gin.Context) {
+	suggestions, err := controller.productService.SuggestProducts(c.Query("q"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
+
+// GetProduct returns a single product.
+func (controller *ProductController) GetProduct(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid product ID"))
+		return
+	}
+
+	product, err := controller.productService.GetProduct(productID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// GetCategories returns all product categories.
+func (controller *ProductController) GetCategories(c *gin.Context) {
+	categories, err := controller.productService.GetCategories()
+	if err != nil {
+		c.Error(goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve categories"))
+		return
+	}
+
+	c.JSON(http.StatusOK, categories)
+}
+
+// CreateProduct creates a product owned by the current user.
+func (controller *ProductController) CreateProduct(c *gin.Context) {
+	var req models.ProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(goAuthException.NewValidationError(err))
+		return
+	}
+
+	product, err := controller.productService.CreateProduct(c.GetInt("user_id"), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, product)
+}
+
+// UpdateProduct updates one of the current user's products.
+func (controller *ProductController) UpdateProduct(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid product ID"))
+		return
+	}
+
+	var req models.ProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(goAuthException.NewValidationError(err))
+		return
+	}
+
+	product, err := controller.productService.UpdateProduct(c.GetInt("user_id"), productID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// DeleteProduct deletes one of the current user's products.
+func (controller *ProductController) DeleteProduct(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid product ID"))
+		return
+	}
+
+	if err := controller.productService.DeleteProduct(c.GetInt("user_id"), productID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product deleted successfully"})
+}
+
+// SetStock sets the stock on hand of one of the current user's products.
+func (controller *ProductController) SetStock(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid product ID"))
+		return
+	}
+
+	var req models.StockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(goAuthException.NewValidationError(err))
+		return
+	}
+
+	product, err := controller.inventoryService.SetStock(c.GetInt("user_id"), productID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// GetLowStockProducts returns the current vendor's products running low on stock.
+func (controller *ProductController) GetLowStockProducts(c *gin.Context) {
+	products, err := controller.inventoryService.GetLowStockProducts(c.GetInt("user_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, products)
+}
@@ -0,0 +1,108 @@
+package controllers
+
+//VendorController
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type VendorController struct {
+	vendorService services.VendorServiceInterface
+}
+
+// NewVendorController creates a new instance of VendorController.
+func NewVendorController(vendorService services.VendorServiceInterface) *VendorController {
+	return &VendorController{
+		vendorService: vendorService,
+	}
+}
+
+// SubmitApplication files the current user's application to become a vendor.
+func (controller *VendorController) SubmitApplication(c *gin.Context) {
+	var req models.VendorApplicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(goAuthException.NewValidationError(err))
+		return
+	}
+
+	application, err := controller.vendorService.SubmitApplication(c.GetInt("user_id"), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, application)
+}
+
+// GetMyApplications returns the current user's vendor applications.
+func (controller *VendorController) GetMyApplications(c *gin.Context) {
+	applications, err := controller.vendorService.GetUserApplications(c.GetInt("user_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, applications)
+}
+
+// GetApplications returns vendor applications for review, optionally filtered by ?status=.
+func (controller *VendorController) GetApplications(c *gin.Context) {
+	applications, err := controller.vendorService.GetApplications(c.Query("status"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, applications)
+}
+
+// ApproveApplication makes the applicant a vendor.
+func (controller *VendorController) ApproveApplication(c *gin.Context) {
+	controller.reviewApplication(c, controller.vendorService.ApproveApplication)
+}
+
+// RejectApplication declines a vendor application.
+func (controller *VendorController) RejectApplication(c *gin.Context) {
+	controller.reviewApplication(c, controller.vendorService.RejectApplication)
+}
+
+// GetDashboard summarizes the current vendor's store, products and orders.
+func (controller *VendorController) GetDashboard(c *gin.Context) {
+	dashboard, err := controller.vendorService.GetDashboard(c.GetInt("user_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dashboard)
+}
+
+func (controller *VendorController) reviewApplication(c *gin.Context, review func(services.AuditActor, int, models.VendorApplicationReviewRequest) (*entities.VendorApplication, error)) {
+	applicationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid application ID"))
+		return
+	}
+
+	var req models.VendorApplicationReviewRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(goAuthException.NewValidationError(err))
+			return
+		}
+	}
+
+	application, err := review(auditActor(c), applicationID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, application)
+}
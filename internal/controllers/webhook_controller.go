@@ -0,0 +1,94 @@
+package controllers
+
+//WebhookController
+
+import (
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type WebhookController struct {
+	webhookService services.WebhookServiceInterface
+}
+
+// NewWebhookController creates a new instance of WebhookController.
+func NewWebhookController(webhookService services.WebhookServiceInterface) *WebhookController {
+	return &WebhookController{
+		webhookService: webhookService,
+	}
+}
+
+// RegisterEndpoint registers a webhook endpoint for security events.
+func (controller *WebhookController) RegisterEndpoint(c *gin.Context) {
+	var req models.WebhookEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(goAuthException.NewValidationError(err))
+		return
+	}
+
+	endpoint, err := controller.webhookService.RegisterEndpoint(req, c.GetInt("user_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, endpoint)
+}
+
+// GetEndpoints lists the registered webhook endpoints.
+func (controller *WebhookController) GetEndpoints(c *gin.Context) {
+	endpoints, err := controller.webhookService.GetEndpoints()
+	if err != nil {
+		c.Error(goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve webhook endpoints"))
+		return
+	}
+
+	c.JSON(http.StatusOK, endpoints)
+}
+
+// DeleteEndpoint stops deliveries to a webhook endpoint.
+func (controller *WebhookController) DeleteEndpoint(c *gin.Context) {
+	endpointID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid endpoint ID"))
+		return
+	}
+
+	if err := controller.webhookService.DeleteEndpoint(endpointID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook endpoint deleted"})
+}
+
+// GetDeliveries lists recent deliveries, e.g. ?status=DEAD for the dead-letter queue.
+func (controller *WebhookController) GetDeliveries(c *gin.Context) {
+	deliveries, err := controller.webhookService.GetDeliveries(c.Query("status"))
+	if err != nil {
+		c.Error(goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve webhook deliveries"))
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// ReplayDelivery sends a delivery again.
+func (controller *WebhookController) ReplayDelivery(c *gin.Context) {
+	deliveryID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid delivery ID"))
+		return
+	}
+
+	if err := controller.webhookService.ReplayDelivery(deliveryID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Webhook delivery scheduled for replay"})
+}
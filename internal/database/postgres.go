@@ -1,103 +1,183 @@
 package database
 
 import (
+	"backendGoAuth/internal/config"
+	"backendGoAuth/internal/logging"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
-	"log"
+	"github.com/lib/pq"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
-type DBConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	Name     string
-}
-
-var db *sql.DB
-
-func getDBConfigFromEnv() DBConfig {
-	return DBConfig{
-		Host:     os.Getenv("DB_HOST"),
-		Port:     os.Getenv("DB_PORT"),
-		User:     os.Getenv("DB_USER"),
-		Password: os.Getenv("DB_PASSWORD"),
-		Name:     os.Getenv("DB_NAME"),
-	}
-}
-
-func ConnectDB() error {
-	dbConfig := getDBConfigFromEnv()
+// migrationDir holds the migration files, named <version>_<name>.up.sql.
+const migrationDir = "migrations"
 
-	err := openDB(dbConfig)
+// Open connects to the database described by cfg and applies the pending migrations.
+// The caller closes the returned database.
+func Open(cfg config.DatabaseConfig) (*sql.DB, error) {
+	db, err := openDB(cfg)
 	if err != nil {
-		log.Printf("Error connecting to the database: %v\n", err)
-		return err
+		slog.Error("Error connecting to the database", "error", err)
+		return nil, err
 	}
 
-	if err := runMigrations(dbConfig); err != nil {
-		log.Printf("Error applying database migrations: %v\n", err)
+	if err := runMigrations(cfg); err != nil {
+		slog.Error("Error applying database migrations", "error", err)
 		if closeErr := db.Close(); closeErr != nil {
-			log.Printf("Error closing database connection: %v\n", closeErr)
+			slog.Error("Error closing database connection", "error", closeErr)
 		}
-		return err
+		return nil, err
 	}
 
-	log.Println("Database connection and migrations applied successfully")
-	return nil
+	slog.Info("Database connection and migrations applied successfully")
+	return db, nil
 }
 
-func GetDB() *sql.DB {
-	if db == nil {
-		log.Fatalf("Database connection is not initialized")
+// Listen subscribes to a Postgres NOTIFY channel of the database described by cfg and calls onNotify with the
+// payload of every notification until ctx is cancelled. onReconnect is called
+// after the listener re-establishes a dropped connection, since notifications
+// sent in the meantime are lost.
+func Listen(ctx context.Context, cfg config.DatabaseConfig, channel string, onNotify func(payload string), onReconnect func()) error {
+	listener := pq.NewListener(connectionString(cfg), 10*time.Second, time.Minute,
+		func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				logging.FromContext(ctx).Error("Error on listener", "channel", channel, "error", err)
+			}
+		})
+
+	if err := listener.Listen(channel); err != nil {
+		logging.FromContext(ctx).Error("Error listening on channel", "channel", channel, "error", err)
+		if closeErr := listener.Close(); closeErr != nil {
+			logging.FromContext(ctx).Error("Error closing listener", "channel", channel, "error", closeErr)
+		}
+		return err
 	}
-	return db
+
+	go func() {
+		defer func() {
+			if err := listener.Close(); err != nil {
+				logging.FromContext(ctx).Error("Error closing listener", "channel", channel, "error", err)
+			}
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case notification := <-listener.Notify:
+				// A nil notification means the connection was re-established
+				if notification == nil {
+					if onReconnect != nil {
+						onReconnect()
+					}
+					continue
+				}
+				onNotify(notification.Extra)
+			}
+		}
+	}()
+
+	return nil
 }
 
-func openDB(cfg DBConfig) error {
-	connectionString := fmt.Sprintf(
+func connectionString(cfg config.DatabaseConfig) string {
+	return fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name,
 	)
-	var err error
-	db, err = sql.Open("postgres", connectionString)
+}
+
+func openDB(cfg config.DatabaseConfig) (*sql.DB, error) {
+	db, err := sql.Open("postgres", connectionString(cfg))
 	if err != nil {
-		log.Printf("Error opening database connection: %v\n", err)
-		return err
+		slog.Error("Error opening database connection", "error", err)
+		return nil, err
 	}
 
 	if err := db.Ping(); err != nil {
-		log.Printf("Error pinging database: %v\n", err)
-		return err
+		slog.Error("Error pinging database", "error", err)
+		if closeErr := db.Close(); closeErr != nil {
+			slog.Error("Error closing database connection", "error", closeErr)
+		}
+		return nil, err
 	}
 
-	return nil
+	return db, nil
 }
 
-func runMigrations(cfg DBConfig) error {
-	migrationDir := "migrations"
-
+func runMigrations(cfg config.DatabaseConfig) error {
 	m, err := migrate.New(
 		"file://"+migrationDir,
 		fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.Name),
 	)
 	if err != nil {
-		log.Printf("Error initializing migration tool: %v\n", err)
+		slog.Error("Error initializing migration tool", "error", err)
 		return err
 	}
 
 	if err := m.Up(); err != nil {
 		if !errors.Is(err, migrate.ErrNoChange) {
-			log.Printf("Error applying migrations: %v\n", err)
+			slog.Error("Error applying migrations", "error", err)
 			return err
 		}
 	}
 
-	log.Println("Migrations applied successfully")
+	slog.Info("Migrations applied successfully")
 	return nil
 }
+
+// CheckMigrations returns an error if the last migration of db failed halfway or the schema is
+// older than the newest migration file. A newer schema is fine, as during a rolling deploy.
+func CheckMigrations(ctx context.Context, db *sql.DB) error {
+	expected, err := latestMigrationVersion()
+	if err != nil {
+		return err
+	}
+
+	var version int64
+	var dirty bool
+	if err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty); err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+	if dirty {
+		return fmt.Errorf("migration %d failed and left the schema dirty", version)
+	}
+	if version < expected {
+		return fmt.Errorf("schema is at version %d, expected %d", version, expected)
+	}
+	return nil
+}
+
+// latestMigrationVersion returns the highest version among the migration files.
+func latestMigrationVersion() (int64, error) {
+	entries, err := os.ReadDir(migrationDir)
+	if err != nil {
+		return 0, err
+	}
+
+	var latest int64
+	for _, entry := range entries {
+		name := entry.Name()
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok || !strings.HasSuffix(name, ".up.sql") {
+			continue
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		if version > latest {
+			latest = version
+		}
+	}
+	return latest, nil
+}
@@ -3,13 +3,16 @@ package entities
 import "time"
 
 type Address struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	Street    string    `json:"street"`
-	City      string    `json:"city"`
-	State     string    `json:"state"`
-	ZipCode   string    `json:"zip_code"`
-	Country   string    `json:"country"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                int        `json:"id"`
+	UserID            int        `json:"user_id"`
+	Street            string     `json:"street"`
+	City              string     `json:"city"`
+	State             string     `json:"state"`
+	ZipCode           string     `json:"zip_code"`
+	Country           string     `json:"country"`
+	IsDefaultShipping bool       `json:"is_default_shipping"`
+	IsDefaultBilling  bool       `json:"is_default_billing"`
+	ArchivedAt        *time.Time `json:"archived_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
 }
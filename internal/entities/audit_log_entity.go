@@ -0,0 +1,27 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditLog struct {
+	ID        int             `json:"id"`
+	UserID    *int            `json:"user_id"`
+	ActorID   *int            `json:"actor_id"`
+	TargetID  *int            `json:"target_id"`
+	Action    string          `json:"action"`
+	TableName string          `json:"table_name"`
+	RowID     *int            `json:"row_id"`
+	OldData   json.RawMessage `json:"old_data"`
+	NewData   json.RawMessage `json:"new_data"`
+	IPAddress string          `json:"ip_address"`
+	UserAgent string          `json:"user_agent"`
+	CreatedAt time.Time       `json:"created_at"`
+
+	ChainSeq      *int64     `json:"chain_seq"`
+	ContentDigest string     `json:"content_digest"`
+	PrevHash      string     `json:"prev_hash"`
+	Hash          string     `json:"hash"`
+	RedactedAt    *time.Time `json:"redacted_at"`
+}
@@ -6,6 +6,8 @@ type Cart struct {
 	ID        int        `json:"id"`
 	UserID    int        `json:"user_id"`
 	Items     []CartItem `json:"items"`
+	Total     float64    `json:"total"`
+	Version   int        `json:"version"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 }
@@ -6,7 +6,10 @@ type CartItem struct {
 	ID        int       `json:"id"`
 	CartID    int       `json:"cart_id"`
 	ProductID int       `json:"product_id"`
+	Name      string    `json:"name"`
+	UnitPrice float64   `json:"unit_price"`
 	Quantity  int       `json:"quantity"`
+	LineTotal float64   `json:"line_total"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
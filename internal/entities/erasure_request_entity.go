@@ -0,0 +1,11 @@
+package entities
+
+import "time"
+
+type ErasureRequest struct {
+	UserID       int        `json:"user_id"`
+	RequestedBy  *int       `json:"requested_by,omitempty"`
+	RequestedAt  time.Time  `json:"requested_at"`
+	ScheduledFor time.Time  `json:"scheduled_for"`
+	ErasedAt     *time.Time `json:"erased_at,omitempty"`
+}
@@ -0,0 +1,16 @@
+package entities
+
+import "time"
+
+type Order struct {
+	ID                int         `json:"id"`
+	UserID            int         `json:"user_id"`
+	TotalAmount       float64     `json:"total_amount"`
+	Status            string      `json:"status"`
+	PaymentStatus     string      `json:"payment_status"`
+	PaymentMethod     *string     `json:"payment_method,omitempty"`
+	ShippingAddressID *int        `json:"shipping_address_id,omitempty"`
+	Items             []OrderItem `json:"items"`
+	CreatedAt         time.Time   `json:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
+}
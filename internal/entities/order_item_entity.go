@@ -0,0 +1,15 @@
+package entities
+
+import "time"
+
+type OrderItem struct {
+	ID           int       `json:"id"`
+	OrderID      int       `json:"order_id"`
+	ProductID    int       `json:"product_id"`
+	Name         string    `json:"name"`
+	VendorID     int       `json:"vendor_id"`
+	Quantity     int       `json:"quantity"`
+	PriceAtOrder float64   `json:"price_at_order"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
@@ -0,0 +1,19 @@
+package entities
+
+import "time"
+
+type Payment struct {
+	ID             int       `json:"id"`
+	OrderID        int       `json:"order_id"`
+	Provider       string    `json:"provider"`
+	ProviderRef    string    `json:"provider_ref,omitempty"`
+	IdempotencyKey string    `json:"-"`
+	Kind           string    `json:"kind"`
+	Amount         float64   `json:"amount"`
+	PaymentMethod  string    `json:"payment_method,omitempty"`
+	Status         string    `json:"status"`
+	Error          string    `json:"error,omitempty"`
+	Attempt        int       `json:"attempt"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
@@ -3,12 +3,16 @@ package entities
 import "time"
 
 type Product struct {
-	ID          int       `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Price       float64   `json:"price"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	CategoryID  int       `json:"category_id"`
-	UserID      int       `json:"user_id"`
+	ID                int       `json:"id"`
+	Name              string    `json:"name"`
+	Description       string    `json:"description"`
+	Price             float64   `json:"price"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	CategoryID        int       `json:"category_id"`
+	UserID            int       `json:"user_id"`
+	StockQuantity     int       `json:"stock_quantity"`
+	ReservedQuantity  int       `json:"reserved_quantity"`
+	AvailableQuantity int       `json:"available_quantity"`
+	LowStockThreshold int       `json:"low_stock_threshold"`
 }
@@ -0,0 +1,11 @@
+package entities
+
+import "time"
+
+type SessionActivity struct {
+	ID        int       `json:"id"`
+	SessionID int       `json:"session_id"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package entities
+
+import "time"
+
+type VendorApplication struct {
+	ID           int        `json:"id"`
+	UserID       int        `json:"user_id"`
+	StoreName    string     `json:"store_name"`
+	Description  string     `json:"description"`
+	ContactEmail string     `json:"contact_email"`
+	Status       string     `json:"status"`
+	ReviewedBy   *int       `json:"reviewed_by,omitempty"`
+	ReviewNote   string     `json:"review_note,omitempty"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
@@ -1,6 +1,7 @@
 package goAuthException
 
 import (
+	"errors"
 	"net/http"
 )
 
@@ -10,7 +11,7 @@ const (
 	UnauthorizedCode        = 401
 	ForbiddenCode           = 403
 	NotFoundCode            = 404
-	Teapot                  = 418
+	ConflictCode            = 409
 	InternalServerErrorCode = 500
 )
 
@@ -27,44 +28,114 @@ const (
 	InternalErrorMessage  = "Internal server error"
 )
 
+// ErrorCode is a machine-readable error code, sent to clients along with the message so they
+// can tell failures apart without parsing it. Codes are stable: once released they don't change.
+type ErrorCode string
+
+// Error codes shared by every failure with the same status, used when no specific code is set.
+const (
+	ErrorCodeBadRequest       ErrorCode = "bad_request"
+	ErrorCodeValidationFailed ErrorCode = "validation_failed"
+	ErrorCodeUnauthorized     ErrorCode = "unauthorized"
+	ErrorCodeForbidden        ErrorCode = "forbidden"
+	ErrorCodeNotFound         ErrorCode = "not_found"
+	ErrorCodeConflict         ErrorCode = "conflict"
+	ErrorCodeInternal         ErrorCode = "internal_error"
+)
+
+// Error codes of specific failures.
+const (
+	ErrorCodeInvalidToken            ErrorCode = "invalid_token"
+	ErrorCodeInvalidCredentials      ErrorCode = "invalid_credentials"
+	ErrorCodeAccountLocked           ErrorCode = "account_locked"
+	ErrorCodeUsernameTaken           ErrorCode = "username_taken"
+	ErrorCodeEmailTaken              ErrorCode = "email_taken"
+	ErrorCodeUserNotFound            ErrorCode = "user_not_found"
+	ErrorCodeUserAlreadyErased       ErrorCode = "user_already_erased"
+	ErrorCodeOpenOrders              ErrorCode = "open_orders"
+	ErrorCodeSessionNotFound         ErrorCode = "session_not_found"
+	ErrorCodeImpersonationForbidden  ErrorCode = "impersonation_forbidden"
+	ErrorCodeCartModified            ErrorCode = "cart_modified"
+	ErrorCodeOrderModified           ErrorCode = "order_modified"
+	ErrorCodeInsufficientStock       ErrorCode = "insufficient_stock"
+	ErrorCodeIdempotencyKeyReused    ErrorCode = "idempotency_key_reused"
+	ErrorCodePaymentInProgress       ErrorCode = "payment_in_progress"
+	ErrorCodeInvalidWebhookSignature ErrorCode = "invalid_webhook_signature"
+)
+
+// FieldError describes what's wrong with one field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 // CustomError represents an error with an associated error code.
 type CustomError struct {
-	Code    int    // Error code
-	Message string // Error message
+	Code      int          // HTTP status code
+	Message   string       // Error message, only shown to clients for errors below 500
+	ErrorCode ErrorCode    // Machine-readable error code
+	Details   []FieldError // Problems with individual fields of the request
+	Err       error        // Underlying cause, never shown to clients
 }
 
-// Error returns the error message.
+// Error returns the error message, followed by the underlying cause if there is one.
 func (e *CustomError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
 	return e.Message
 }
 
+// Unwrap returns the underlying cause, for errors.Is and errors.As.
+func (e *CustomError) Unwrap() error {
+	return e.Err
+}
+
+// WithErrorCode sets the machine-readable error code, replacing the one shared by its status.
+func (e *CustomError) WithErrorCode(errorCode ErrorCode) *CustomError {
+	e.ErrorCode = errorCode
+	return e
+}
+
+// WithDetails adds problems with individual fields of the request.
+func (e *CustomError) WithDetails(details ...FieldError) *CustomError {
+	e.Details = append(e.Details, details...)
+	return e
+}
+
 // NewCustomError creates a new CustomError with the given code and message.
 func NewCustomError(code int, message string) *CustomError {
-	return &CustomError{Code: code, Message: message}
+	return &CustomError{Code: code, Message: message, ErrorCode: defaultErrorCode(code)}
+}
+
+// Wrap creates a new CustomError with the given code and message, caused by err.
+func Wrap(err error, code int, message string) *CustomError {
+	customErr := NewCustomError(code, message)
+	customErr.Err = err
+	return customErr
+}
+
+// AsCustomError finds the first CustomError in err's chain.
+func AsCustomError(err error) (*CustomError, bool) {
+	var customErr *CustomError
+	ok := errors.As(err, &customErr)
+	return customErr, ok
 }
 
-// ErrorHandler handles errors and returns the appropriate HTTP response.
-type ErrorHandler struct{}
-
-// HandleError handles errors and returns the appropriate HTTP response.
-func (eh *ErrorHandler) HandleError(err error) (int, interface{}) {
-	switch e := err.(type) {
-	case *CustomError:
-		switch e.Code {
-		case BadRequestCode:
-			return http.StatusBadRequest, map[string]string{"error": e.Message}
-		case UnauthorizedCode:
-			return http.StatusUnauthorized, map[string]string{"error": e.Message}
-		case ForbiddenCode:
-			return http.StatusForbidden, map[string]string{"error": e.Message}
-		case NotFoundCode:
-			return http.StatusNotFound, map[string]string{"error": e.Message}
-		case Teapot:
-			return http.StatusTeapot, map[string]string{"error": e.Message}
-		default:
-			return http.StatusInternalServerError, map[string]string{"error": InternalErrorMessage}
-		}
+// defaultErrorCode returns the error code shared by every failure with the given status.
+func defaultErrorCode(code int) ErrorCode {
+	switch code {
+	case http.StatusBadRequest:
+		return ErrorCodeBadRequest
+	case http.StatusUnauthorized:
+		return ErrorCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrorCodeForbidden
+	case http.StatusNotFound:
+		return ErrorCodeNotFound
+	case http.StatusConflict:
+		return ErrorCodeConflict
 	default:
-		return http.StatusInternalServerError, map[string]string{"error": InternalErrorMessage}
+		return ErrorCodeInternal
 	}
 }
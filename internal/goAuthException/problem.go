@@ -0,0 +1,52 @@
+package goAuthException
+
+import (
+	"net/http"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Code and Errors are extension members:
+// clients switch on Code, and Errors lists what's wrong with each invalid field.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      ErrorCode    `json:"code"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// ErrorHandler handles errors and returns the appropriate HTTP response.
+type ErrorHandler struct{}
+
+// HandleError returns the status and problem details describing err. Only CustomErrors below
+// 500 are described to clients: anything else is reported as an internal error, so causes,
+// queries and stack traces never leak.
+func (eh *ErrorHandler) HandleError(err error) (int, *Problem) {
+	customErr, ok := AsCustomError(err)
+	if !ok || customErr.Code < http.StatusBadRequest || customErr.Code >= http.StatusInternalServerError {
+		return http.StatusInternalServerError, newProblem(http.StatusInternalServerError, InternalErrorMessage, ErrorCodeInternal)
+	}
+
+	problem := newProblem(customErr.Code, customErr.Message, customErr.ErrorCode)
+	if problem.Code == "" {
+		problem.Code = defaultErrorCode(customErr.Code)
+	}
+	problem.Errors = customErr.Details
+	return customErr.Code, problem
+}
+
+// newProblem creates problem details without a specific type URI, titled by the status.
+func newProblem(status int, detail string, code ErrorCode) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
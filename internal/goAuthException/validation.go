@@ -0,0 +1,73 @@
+package goAuthException
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+)
+
+// InvalidRequestMessage is the message of requests that couldn't be parsed or validated.
+const InvalidRequestMessage = "Invalid request"
+
+// NewValidationError creates a 400 CustomError from the error returned when binding a
+// request, listing what's wrong with each invalid field.
+func NewValidationError(err error) *CustomError {
+	customErr := Wrap(err, BadRequestCode, InvalidRequestMessage)
+
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &validationErrs):
+		for _, fieldErr := range validationErrs {
+			customErr.WithDetails(FieldError{Field: fieldErr.Field(), Message: describeValidation(fieldErr)})
+		}
+		customErr.ErrorCode = ErrorCodeValidationFailed
+	case errors.As(err, &typeErr):
+		customErr.WithDetails(FieldError{Field: typeErr.Field, Message: "must be a " + typeErr.Type.String()})
+		customErr.ErrorCode = ErrorCodeValidationFailed
+	}
+	return customErr
+}
+
+// describeValidation explains a failed validation rule to clients.
+func describeValidation(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "min":
+		if fieldErr.Kind() == reflect.String {
+			return "must be at least " + fieldErr.Param() + " characters long"
+		}
+		return "must be at least " + fieldErr.Param()
+	case "max":
+		if fieldErr.Kind() == reflect.String {
+			return "must be at most " + fieldErr.Param() + " characters long"
+		}
+		return "must be at most " + fieldErr.Param()
+	case "gt":
+		return "must be greater than " + fieldErr.Param()
+	case "lt":
+		return "must be less than " + fieldErr.Param()
+	default:
+		return "is invalid"
+	}
+}
+
+// FieldName names struct fields in validation errors the way clients send them: by their
+// json tag, or their form tag for query parameters. Register it on the binding validator
+// with RegisterTagNameFunc.
+func FieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
@@ -0,0 +1,119 @@
+// Package health runs the dependency checks behind the liveness and readiness endpoints.
+package health
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Statuses reported for the service and for each check
+const (
+	StatusOK           = "ok"
+	StatusFailed       = "failed"
+	StatusShuttingDown = "shutting_down"
+)
+
+// CheckFunc checks a dependency, returning an error if the service can't use it.
+type CheckFunc func(ctx context.Context) error
+
+// CheckResult is the outcome of one check.
+type CheckResult struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// Report is the readiness of the service with the breakdown of its checks.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type namedCheck struct {
+	name  string
+	check CheckFunc
+}
+
+// Checker decides whether the service is ready to receive traffic.
+type Checker struct {
+	Timeout      time.Duration // for each check
+	checks       []namedCheck
+	shuttingDown atomic.Bool
+}
+
+// NewChecker creates a new instance of Checker.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{Timeout: timeout}
+}
+
+// Register adds a check run on every readiness probe. Register all checks before serving probes.
+func (h *Checker) Register(name string, check CheckFunc) {
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// MarkShuttingDown makes the service report not ready from now on, so load balancers stop
+// sending it new requests while in-flight ones finish.
+func (h *Checker) MarkShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Ready runs every check concurrently and reports whether they all passed.
+func (h *Checker) Ready(ctx context.Context) Report {
+	if h.shuttingDown.Load() {
+		return Report{Status: StatusShuttingDown}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.Timeout)
+	defer cancel()
+
+	results := make([]CheckResult, len(h.checks))
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func(i int, check CheckFunc) {
+			defer wg.Done()
+			start := time.Now()
+			result := CheckResult{Status: StatusOK}
+			if err := check(ctx); err != nil {
+				result = CheckResult{Status: StatusFailed, Error: err.Error()}
+			}
+			result.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+			results[i] = result
+		}(i, check.check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(h.checks))}
+	for i, check := range h.checks {
+		report.Checks[check.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFailed
+		}
+	}
+	return report
+}
+
+// LivenessHandler reports the process is up. It checks no dependencies, so an outage of one
+// doesn't get every instance restarted.
+func (h *Checker) LivenessHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": StatusOK})
+	}
+}
+
+// ReadinessHandler reports the outcome of every check, with 503 if any failed or the
+// service is shutting down.
+func (h *Checker) ReadinessHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := h.Ready(c.Request.Context())
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, report)
+	}
+}
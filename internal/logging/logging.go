@@ -0,0 +1,41 @@
+// Package logging builds the service's structured logger. Every record goes through the
+// redaction in redact.go, so passwords, tokens and secrets never reach the logs.
+package logging
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Options configures the logger built by New.
+type Options struct {
+	Level   slog.Level
+	Format  string   // "json" or "text"
+	Secrets []string // values scrubbed from every record, such as keys read from the environment
+}
+
+// ParseOptions reads the level (debug, info, warn or error) and format (json or text).
+func ParseOptions(level, format string) (Options, error) {
+	opts := Options{Format: strings.ToLower(format)}
+	if err := opts.Level.UnmarshalText([]byte(level)); err != nil {
+		return Options{}, err
+	}
+	if opts.Format != "text" {
+		opts.Format = "json"
+	}
+	return opts, nil
+}
+
+// New creates a logger writing to w, redacting opts.Secrets wherever they show up.
+func New(w io.Writer, opts Options) *slog.Logger {
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level, ReplaceAttr: NewRedactor(opts.Secrets...).ReplaceAttr}
+
+	var handler slog.Handler
+	if opts.Format == "text" {
+		handler = slog.NewTextHandler(w, handlerOpts)
+	} else {
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	}
+	return slog.New(handler)
+}
@@ -0,0 +1,137 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces every value that could be a secret.
+const Redacted = "[REDACTED]"
+
+// minSecretLength keeps short secrets, which would match all over the logs, from being scrubbed.
+const minSecretLength = 6
+
+// sensitiveKeys are the parts of attribute and field names whose values are never logged.
+// Names are compared in lower case without separators, so "access_token" and "X-Api-Key" match.
+var sensitiveKeys = []string{"password", "passwd", "secret", "token", "authorization", "cookie", "apikey", "privatekey", "credential", "hmac"}
+
+var (
+	jwtPattern        = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	authSchemePattern = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/=-]+`)
+	assignmentPattern = regexp.MustCompile(`(?i)\b([a-z_-]*(?:password|passwd|secret|token|api_?key)[a-z_-]*)("?\s*[:=]\s*"?)[^\s",&;]+`)
+)
+
+// Redactor redacts the records of a logger: the values of sensitive keys, tokens and
+// credentials, and the secrets it was created with wherever they appear.
+type Redactor struct {
+	secrets []string
+}
+
+// NewRedactor creates a Redactor scrubbing secrets, such as keys read from the environment,
+// on top of what is always redacted.
+func NewRedactor(secrets ...string) *Redactor {
+	r := &Redactor{}
+	for _, secret := range secrets {
+		if len(secret) >= minSecretLength {
+			r.secrets = append(r.secrets, secret)
+		}
+	}
+	return r
+}
+
+// Scrub replaces the tokens, credentials and secrets in free text, such as log messages
+// and error strings.
+func (r *Redactor) Scrub(s string) string {
+	s = jwtPattern.ReplaceAllString(s, Redacted)
+	s = authSchemePattern.ReplaceAllString(s, "$1 "+Redacted)
+	s = assignmentPattern.ReplaceAllString(s, "${1}${2}"+Redacted)
+
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	return s
+}
+
+// isSensitiveKey reports whether values named key must never be logged.
+func isSensitiveKey(key string) bool {
+	normalized := strings.NewReplacer("_", "", "-", "", ".", "", " ", "").Replace(strings.ToLower(key))
+	for _, sensitiveKey := range sensitiveKeys {
+		if strings.Contains(normalized, sensitiveKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplaceAttr is the slog ReplaceAttr hook redacting every record before it is written,
+// including the message and records from the standard log package.
+func (r *Redactor) ReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey || a.Key == slog.SourceKey) {
+		return a
+	}
+	if isSensitiveKey(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, r.Scrub(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, r.Scrub(err.Error()))
+		}
+		return slog.Any(a.Key, r.redactValue(a.Value.Any()))
+	}
+	return a
+}
+
+// redactValue redacts the sensitive fields of maps, structs and slices, going through their
+// JSON form so nested values and json tags are handled the way they'd be written out.
+func (r *Redactor) redactValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	switch reflect.Indirect(reflect.ValueOf(value)).Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+	default:
+		return r.Scrub(fmt.Sprint(value))
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		// What can't be inspected can't be logged safely
+		return Redacted
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return Redacted
+	}
+	return r.redactDecoded(decoded)
+}
+
+// redactDecoded redacts a value decoded from JSON in place.
+func (r *Redactor) redactDecoded(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if isSensitiveKey(key) {
+				value[key] = Redacted
+			} else {
+				value[key] = r.redactDecoded(field)
+			}
+		}
+		return value
+	case []interface{}:
+		for i, element := range value {
+			value[i] = r.redactDecoded(element)
+		}
+		return value
+	case string:
+		return r.Scrub(value)
+	}
+	return value
+}
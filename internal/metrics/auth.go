@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+// LoginSucceeded counts a successful login.
+func (m *Metrics) LoginSucceeded() {
+	m.loginSuccesses.Inc()
+}
+
+// LoginFailed counts a failed login, such as "invalid_password" or "account_locked".
+func (m *Metrics) LoginFailed(reason string) {
+	m.loginFailures.WithLabelValues(reason).Inc()
+}
+
+// UserRegistered counts a new user.
+func (m *Metrics) UserRegistered() {
+	m.registrations.Inc()
+}
+
+// AccountLocked counts an account locked, after too many failed logins or by an admin.
+func (m *Metrics) AccountLocked() {
+	m.lockouts.Inc()
+}
+
+// SessionsRevoked counts sessions ended for the given reason, such as "logout".
+func (m *Metrics) SessionsRevoked(reason string, count int) {
+	m.sessionRevocations.WithLabelValues(reason).Add(float64(count))
+}
+
+// ObserveTokenValidation records how long validating a token that started at start took.
+func (m *Metrics) ObserveTokenValidation(ctx context.Context, start time.Time, err error) {
+	result := "valid"
+	if err != nil {
+		result = "invalid"
+	}
+	observe(ctx, m.tokenValidationDuration.WithLabelValues(result), time.Since(start).Seconds())
+}
+
+// initAuthCollectors creates the collectors counting logins, registrations and sessions.
+func (m *Metrics) initAuthCollectors() {
+	m.loginSuccesses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "auth_login_success_total",
+		Help: "Number of successful logins.",
+	})
+	m.loginFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_login_failures_total",
+			Help: "Number of failed logins, by reason.",
+		},
+		[]string{"reason"},
+	)
+	m.registrations = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "auth_registrations_total",
+		Help: "Number of registered users.",
+	})
+	m.lockouts = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "auth_lockouts_total",
+		Help: "Number of accounts locked, after too many failed logins or by an admin.",
+	})
+	m.sessionRevocations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_session_revocations_total",
+			Help: "Number of sessions ended, by reason.",
+		},
+		[]string{"reason"},
+	)
+	m.tokenValidationDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "auth_token_validation_duration_seconds",
+			Help:    "Histogram of JWT validation durations, by result.",
+			Buckets: []float64{.00005, .0001, .00025, .0005, .001, .0025, .005, .01},
+		},
+		[]string{"result"},
+	)
+}
@@ -0,0 +1,19 @@
+package metrics
+
+import (
+	"backendGoAuth/internal/tracing"
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// observe records value on observer, with the ID of the trace in ctx as an exemplar when
+// the trace is sampled, so a slow bucket can be followed to the request that landed in it.
+func observe(ctx context.Context, observer prometheus.Observer, value float64) {
+	if traceID, ok := tracing.TraceID(ctx); ok {
+		if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok {
+			exemplarObserver.ObserveWithExemplar(value, prometheus.Labels{"trace_id": traceID})
+			return
+		}
+	}
+	observer.Observe(value)
+}
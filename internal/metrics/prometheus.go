@@ -1,55 +1,78 @@
 package metrics
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"log/slog"
+	"math"
 	"time"
 )
 
-var (
-	requestDuration = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "http_request_duration_seconds",
-			Help:    "Histogram of HTTP request durations.",
-			Buckets: prometheus.DefBuckets, // Use default buckets
-		},
-		[]string{"method", "path", "status"},
-	)
-)
-
-func init() {
-	prometheus.MustRegister(requestDuration)
-}
+// dbName labels the connection pool stats of the application database.
+const dbName = "goauth"
 
-func RegisterMetrics(reg prometheus.Registerer) {
-	// Register other metrics
-	registerCPUMetrics(reg)
-	registerHDMetrics(reg)
+// Metrics are the collectors of one instance of the service. Each instance registers its own,
+// so several in one process, such as in tests, don't count each other's requests.
+type Metrics struct {
+	requestDuration         *prometheus.HistogramVec
+	loginSuccesses          prometheus.Counter
+	loginFailures           *prometheus.CounterVec
+	registrations           prometheus.Counter
+	lockouts                prometheus.Counter
+	sessionRevocations      *prometheus.CounterVec
+	tokenValidationDuration *prometheus.HistogramVec
 }
 
-func registerCPUMetrics(reg prometheus.Registerer) {
-	cpuTemp := prometheus.NewGauge(prometheus.GaugeOpts{
-		Name: "cpu_temperature_celsius",
-		Help: "Current temperature of the CPU.",
-	})
-	reg.MustRegister(cpuTemp)
-}
+// New creates the metrics the service exposes and registers them on reg, along with the Go
+// runtime, process and connection pool stats of db, if any. countActiveSessions is called on each scrape.
+func New(reg prometheus.Registerer, db *sql.DB, countActiveSessions func(context.Context) (int, error)) *Metrics {
+	m := &Metrics{
+		requestDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_request_duration_seconds",
+				Help:    "Histogram of HTTP request durations.",
+				Buckets: prometheus.DefBuckets, // Use default buckets
+			},
+			[]string{"method", "path", "status"},
+		),
+	}
+	m.initAuthCollectors()
 
-func registerHDMetrics(reg prometheus.Registerer) {
-	hdFailures := prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "hd_errors_total",
-			Help: "Number of hard-disk goAuthException.",
-		},
-		[]string{"device"},
+	if db != nil {
+		reg.MustRegister(collectors.NewDBStatsCollector(db, dbName))
+	}
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requestDuration,
+		m.loginSuccesses,
+		m.loginFailures,
+		m.registrations,
+		m.lockouts,
+		m.sessionRevocations,
+		m.tokenValidationDuration,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "auth_active_sessions",
+			Help: "Number of sessions that haven't been revoked.",
+		}, func() float64 {
+			count, err := countActiveSessions(context.Background())
+			if err != nil {
+				slog.Error("Error counting active sessions", "error", err)
+				return math.NaN()
+			}
+			return float64(count)
+		}),
 	)
-	reg.MustRegister(hdFailures)
+	return m
 }
 
 // InstrumentHandler Middleware for tracking request duration
-func InstrumentHandler() gin.HandlerFunc {
+func (m *Metrics) InstrumentHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 
@@ -60,13 +83,14 @@ func InstrumentHandler() gin.HandlerFunc {
 		method := c.Request.Method
 		path := c.FullPath()
 
-		requestDuration.WithLabelValues(method, path, status).Observe(duration)
+		observe(c.Request.Context(), m.requestDuration.WithLabelValues(method, path, status), duration)
 	}
 }
 
-// MetricsHandler Handler for exposing Prometheus metrics
-func MetricsHandler() gin.HandlerFunc {
-	h := promhttp.Handler()
+// MetricsHandler Handler for exposing the Prometheus metrics registered on reg. Exemplars are
+// only exposed to scrapers that negotiate the OpenMetrics format.
+func MetricsHandler(reg *prometheus.Registry) gin.HandlerFunc {
+	h := promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg, EnableOpenMetrics: true})
 
 	return func(c *gin.Context) {
 		h.ServeHTTP(c.Writer, c.Request)
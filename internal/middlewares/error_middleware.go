@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/logging"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// ErrorHandler renders the last error handlers attached with c.Error as RFC 7807 problem
+// details, unless they already wrote a response. Internal errors are logged with their
+// cause and described to clients only as such.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		err := c.Errors.Last()
+		if err == nil || c.Writer.Written() {
+			return
+		}
+		if status := writeProblem(c, err.Err); status >= http.StatusInternalServerError {
+			logging.FromContext(c.Request.Context()).Error("Request failed", "error", err.Err)
+		}
+	}
+}
+
+// NoRoute answers requests for unknown routes with problem details.
+func NoRoute(c *gin.Context) {
+	c.Error(goAuthException.NewCustomError(goAuthException.NotFoundCode, "Route not found"))
+}
+
+// writeProblem aborts the request with problem details describing err, returning their status.
+func writeProblem(c *gin.Context, err error) int {
+	var errorHandler goAuthException.ErrorHandler
+	status, problem := errorHandler.HandleError(err)
+	problem.Instance = c.Request.URL.Path
+	problem.RequestID = c.GetString("request_id")
+
+	c.Header("Content-Type", goAuthException.ProblemContentType)
+	c.AbortWithStatusJSON(status, problem)
+	return status
+}
@@ -0,0 +1,21 @@
+package middlewares
+
+import (
+	"backendGoAuth/internal/goAuthException"
+	"github.com/gin-gonic/gin"
+)
+
+// DenyImpersonation blocks sensitive actions (password/MFA changes, session
+// management, admin actions) while an admin is impersonating a user.
+// It must run after the JWT middleware, which puts the impersonator ID in the context.
+func DenyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, impersonating := c.Get("impersonator_id"); impersonating {
+			c.Error(goAuthException.NewCustomError(goAuthException.ForbiddenCode, "Action not allowed while impersonating a user").WithErrorCode(goAuthException.ErrorCodeImpersonationForbidden))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -1,20 +1,20 @@
 package middlewares
 
 import (
+	"backendGoAuth/internal/goAuthException"
 	"backendGoAuth/internal/utils"
 	"github.com/gin-gonic/gin"
-	"net/http"
 )
 
-// JWTMiddleware holds the JWT service and secret key.
+// JWTMiddleware holds the token manager validating JWTs.
 type JWTMiddleware struct {
-	JwtSecret string
+	Tokens *utils.TokenManager
 }
 
-// NewJWTMiddleware creates a new instance of JWTMiddleware with the provided secret key and JwtService.
-func NewJWTMiddleware(jwtSecret string) *JWTMiddleware {
+// NewJWTMiddleware creates a new instance of JWTMiddleware with the provided token manager.
+func NewJWTMiddleware(tokens *utils.TokenManager) *JWTMiddleware {
 	return &JWTMiddleware{
-		JwtSecret: jwtSecret,
+		Tokens: tokens,
 	}
 }
 
@@ -24,15 +24,15 @@ func (jwtMiddleware *JWTMiddleware) MiddlewareFunc() gin.HandlerFunc {
 		// Extract token from request cookies
 		tokenString, err := utils.ExtractToken(c)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "No token provided"})
+			c.Error(goAuthException.NewCustomError(goAuthException.UnauthorizedCode, "No token provided"))
 			c.Abort()
 			return
 		}
 
 		// Validate token and retrieve claims
-		claims, err := utils.ValidateToken(tokenString)
+		claims, err := jwtMiddleware.Tokens.ValidateToken(c.Request.Context(), tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Error(goAuthException.NewCustomError(goAuthException.UnauthorizedCode, "Invalid token").WithErrorCode(goAuthException.ErrorCodeInvalidToken))
 			c.Abort()
 			return
 		}
@@ -40,12 +40,20 @@ func (jwtMiddleware *JWTMiddleware) MiddlewareFunc() gin.HandlerFunc {
 		// Extract user ID from claims and set it in the context
 		userID, ok := claims["user_id"].(float64) // JWT numeric claims are typically float64
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in token"})
+			c.Error(goAuthException.NewCustomError(goAuthException.UnauthorizedCode, "Invalid user ID in token").WithErrorCode(goAuthException.ErrorCodeInvalidToken))
 			c.Abort()
 			return
 		}
 		c.Set("user_id", int(userID)) // Convert to int and set it in context
 
+		if sessionID, ok := claims["session_id"].(float64); ok {
+			c.Set("session_id", int(sessionID))
+		}
+
+		if impersonatorID, ok := utils.ImpersonatorIDFromClaims(claims); ok {
+			c.Set("impersonator_id", impersonatorID)
+		}
+
 		c.Next()
 	}
 }
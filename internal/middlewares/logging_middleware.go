@@ -0,0 +1,54 @@
+package middlewares
+
+import (
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/logging"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// AccessLog logs each request once it has been served, with the request's logger. Query
+// strings and headers are left out since they can carry credentials.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		} else if status >= http.StatusBadRequest {
+			level = slog.LevelWarn
+		}
+
+		ctx := c.Request.Context()
+		logging.FromContext(ctx).LogAttrs(ctx, level, "Request served",
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Int("size", c.Writer.Size()),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		)
+	}
+}
+
+// Recovery turns panics into 500 responses, logging them with the request's logger instead
+// of gin's default, which dumps the request headers and their cookies.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered interface{}) {
+		ctx := c.Request.Context()
+		logging.FromContext(ctx).ErrorContext(ctx, "Recovered from panic",
+			"panic", recovered,
+			"stack", string(debug.Stack()),
+		)
+		writeProblem(c, goAuthException.NewCustomError(goAuthException.InternalServerErrorCode, goAuthException.InternalErrorMessage))
+	})
+}
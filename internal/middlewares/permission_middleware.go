@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"backendGoAuth/internal/goAuthException"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+)
+
+// PermissionChecker checks whether a user has a permission.
+type PermissionChecker interface {
+	HasPermission(userID int, permission string) (bool, error)
+}
+
+// RequirePermission rejects requests from users lacking the given permission.
+// It must run after the JWT middleware, which puts the user ID in the context.
+func RequirePermission(checker PermissionChecker, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.Error(goAuthException.NewCustomError(goAuthException.UnauthorizedCode, "Unauthorized"))
+			c.Abort()
+			return
+		}
+
+		allowed, err := checker.HasPermission(userID.(int), permission)
+		if err != nil {
+			slog.Error("Error checking permission", "permission", permission, "error", err)
+			c.Error(goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to check permissions"))
+			c.Abort()
+			return
+		}
+		if !allowed {
+			c.Error(goAuthException.NewCustomError(goAuthException.ForbiddenCode, "Forbidden"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,69 @@
+package middlewares
+
+import (
+	"backendGoAuth/internal/logging"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+)
+
+// RequestIDHeader carries the request ID in both directions, so callers can pass their own
+// and quote it back when reporting a problem.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the request IDs accepted from callers.
+const maxRequestIDLength = 128
+
+// RequestID tags each request with an ID, taken from the X-Request-ID header when it is
+// well formed and generated otherwise. The ID is echoed in the response and carried by the
+// request context, along with a logger derived from logger which includes it, and the ID of
+// the request's trace, in every record. It must run after the tracing middleware.
+func RequestID(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		c.Header(RequestIDHeader, requestID)
+		c.Set("request_id", requestID)
+
+		// Link the request's logs and trace both ways
+		requestLogger := logger.With("request_id", requestID)
+		span := trace.SpanFromContext(c.Request.Context())
+		span.SetAttributes(attribute.String("request.id", requestID))
+		if spanContext := span.SpanContext(); spanContext.IsSampled() {
+			requestLogger = requestLogger.With("trace_id", spanContext.TraceID().String())
+		}
+
+		ctx := logging.WithRequestID(c.Request.Context(), requestID)
+		ctx = logging.WithLogger(ctx, requestLogger)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// validRequestID reports whether a caller's request ID is safe to log and echo back.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID generates a random 128-bit request ID.
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		slog.Error("Error generating request ID", "error", err)
+	}
+	return hex.EncodeToString(b)
+}
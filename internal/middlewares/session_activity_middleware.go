@@ -0,0 +1,22 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// SessionActivityRecorder records requests made with a session.
+type SessionActivityRecorder interface {
+	Record(sessionID int, ipAddress, userAgent string)
+}
+
+// SessionActivityMiddleware records the activity of the authenticated session.
+// It must run after the JWT middleware, which puts the session ID in the context.
+func SessionActivityMiddleware(recorder SessionActivityRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if sessionID, ok := c.Get("session_id"); ok {
+			recorder.Record(sessionID.(int), c.ClientIP(), c.GetHeader("User-Agent"))
+		}
+
+		c.Next()
+	}
+}
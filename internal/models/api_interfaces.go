@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"backendGoAuth/internal/entities"
+	"time"
+)
 
 type RegistrationRequest struct {
 	Username string `json:"username" binding:"required"`
@@ -9,8 +12,16 @@ type RegistrationRequest struct {
 }
 
 type LoginRequest struct {
-	Identifier string `json:"identifier" binding:"required"`
-	Password   string `json:"password" binding:"required"`
+	Identifier string            `json:"identifier" binding:"required"`
+	Password   string            `json:"password" binding:"required"`
+	GuestCart  []CartItemRequest `json:"guest_cart" binding:"max=100"` // items added before logging in; invalid ones are skipped
+}
+
+type EditUserRequest struct {
+	Username      string `json:"username" binding:"required"`
+	Email         string `json:"email" binding:"required,email"`
+	IsBlocked     bool   `json:"is_blocked"`
+	LoginAttempts int    `json:"login_attempts" binding:"min=0"`
 }
 
 type UserData struct {
@@ -24,13 +35,206 @@ type AuthResponse struct {
 }
 
 type SessionResponse struct {
-	ID              int       `json:"id"`
-	UserID          int       `json:"user_id"`
-	IPAddress       string    `json:"ip_address"`
-	IsActive        bool      `json:"is_active"`
-	CreatedAt       time.Time `json:"created_date_at"`
-	UpdatedAt       time.Time `json:"updated_date_at"`
-	Location        string    `json:"location"`
-	DeviceConnected string    `json:"device_connected"`
-	BrowserUsed     string    `json:"browser_used"`
+	ID              int                       `json:"id"`
+	UserID          int                       `json:"user_id"`
+	IPAddress       string                    `json:"ip_address"`
+	IsActive        bool                      `json:"is_active"`
+	CreatedAt       time.Time                 `json:"created_date_at"`
+	UpdatedAt       time.Time                 `json:"updated_date_at"`
+	Location        string                    `json:"location"`
+	DeviceConnected string                    `json:"device_connected"`
+	BrowserUsed     string                    `json:"browser_used"`
+	LastSeenAt      *time.Time                `json:"last_seen_at"`
+	LastIP          string                    `json:"last_ip"`
+	RequestCount    int64                     `json:"request_count"`
+	Activity        []SessionActivityResponse `json:"activity"`
+}
+
+type SessionActivityResponse struct {
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	SeenAt    time.Time `json:"seen_at"`
+}
+
+type AuditLogQuery struct {
+	ActorID  int        `form:"actor_id"`
+	TargetID int        `form:"target_id"`
+	Action   string     `form:"action"`
+	From     *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To       *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Page     int        `form:"page" binding:"omitempty,min=1"`
+	PageSize int        `form:"page_size" binding:"omitempty,min=1"`
+}
+
+type AuditLogPage struct {
+	Items    []entities.AuditLog `json:"items"`
+	Page     int                 `json:"page"`
+	PageSize int                 `json:"page_size"`
+	Total    int                 `json:"total"`
+}
+
+type WebhookEndpointRequest struct {
+	URL        string   `json:"url" binding:"required,url"`
+	EventTypes []string `json:"event_types" binding:"required,min=1"`
+	Secret     string   `json:"secret" binding:"omitempty,min=16"`
+}
+
+type WebhookEndpointResponse struct {
+	entities.WebhookEndpoint
+	Secret string `json:"secret"`
+}
+
+type ProductQuery struct {
+	Query      string `form:"q"`
+	CategoryID int    `form:"category_id"`
+	VendorID   int    `form:"vendor_id"`
+	Page       int    `form:"page" binding:"omitempty,min=1"`
+	PageSize   int    `form:"page_size" binding:"omitempty,min=1"`
+}
+
+type ProductPage struct {
+	Items    []entities.Product `json:"items"`
+	Page     int                `json:"page"`
+	PageSize int                `json:"page_size"`
+	Total    int                `json:"total"`
+}
+
+type ProductRequest struct {
+	Name        string  `json:"name" binding:"required,max=255"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price" binding:"required,gt=0,lt=100000000"`
+	CategoryID  int     `json:"category_id" binding:"min=0"`
+}
+
+type CartItemRequest struct {
+	ProductID int `json:"product_id" binding:"required,min=1"`
+	Quantity  int `json:"quantity" binding:"required,min=1"`
+}
+
+type CartItemUpdateRequest struct {
+	Quantity int `json:"quantity" binding:"min=0"`
+}
+
+type CheckoutRequest struct {
+	PaymentMethod     string `json:"payment_method" binding:"max=50"`
+	ShippingAddressID int    `json:"shipping_address_id" binding:"min=0"` // defaults to the default shipping address
+}
+
+type OrderQuery struct {
+	Status   string `form:"status"`
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1"`
+}
+
+type OrderPage struct {
+	Items    []entities.Order `json:"items"`
+	Page     int              `json:"page"`
+	PageSize int              `json:"page_size"`
+	Total    int              `json:"total"`
+}
+
+type OrderStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+type PaymentRequest struct {
+	PaymentMethod string `json:"payment_method" binding:"required,max=50"`
+}
+
+type AddressRequest struct {
+	Street            string `json:"street" binding:"required,max=255"`
+	City              string `json:"city" binding:"required,max=255"`
+	State             string `json:"state" binding:"max=255"`
+	ZipCode           string `json:"zip_code" binding:"max=20"`
+	Country           string `json:"country" binding:"required"` // ISO 3166-1 alpha-2 code
+	IsDefaultShipping bool   `json:"is_default_shipping"`
+	IsDefaultBilling  bool   `json:"is_default_billing"`
+}
+
+type StockRequest struct {
+	StockQuantity     int  `json:"stock_quantity" binding:"min=0,max=1000000"`
+	LowStockThreshold *int `json:"low_stock_threshold" binding:"omitempty,min=0"`
+}
+
+type ProductSearchQuery struct {
+	Query      string  `form:"q"`
+	CategoryID int     `form:"category_id"`
+	MinPrice   float64 `form:"min_price" binding:"min=0"`
+	MaxPrice   float64 `form:"max_price" binding:"min=0"`
+	Page       int     `form:"page" binding:"omitempty,min=1"`
+	PageSize   int     `form:"page_size" binding:"omitempty,min=1"`
+}
+
+type ProductSearchHit struct {
+	entities.Product
+	Rank float64 `json:"rank"`
+}
+
+type CategoryFacet struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type PriceRangeFacet struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max,omitempty"` // unset for the open-ended top range
+	Count int      `json:"count"`
+}
+
+type ProductSearchFacets struct {
+	Categories  []CategoryFacet   `json:"categories"`
+	PriceRanges []PriceRangeFacet `json:"price_ranges"`
+}
+
+type ProductSearchPage struct {
+	Items    []ProductSearchHit  `json:"items"`
+	Page     int                 `json:"page"`
+	PageSize int                 `json:"page_size"`
+	Total    int                 `json:"total"`
+	Facets   ProductSearchFacets `json:"facets"`
+}
+
+type VendorApplicationRequest struct {
+	StoreName    string `json:"store_name" binding:"required,max=255"`
+	Description  string `json:"description" binding:"max=5000"`
+	ContactEmail string `json:"contact_email" binding:"required,email,max=255"`
+}
+
+type VendorApplicationReviewRequest struct {
+	Note string `json:"note" binding:"max=2000"`
+}
+
+type VendorDashboard struct {
+	Store           *entities.VendorApplication `json:"store,omitempty"`
+	ProductCount    int                         `json:"product_count"`
+	LowStockCount   int                         `json:"low_stock_count"`
+	OutOfStockCount int                         `json:"out_of_stock_count"`
+	OrdersByStatus  map[string]int              `json:"orders_by_status"`
+	UnitsSold       int                         `json:"units_sold"`
+	Revenue         float64                     `json:"revenue"`
+}
+
+type AccountProfile struct {
+	ID        int        `json:"id"`
+	Username  string     `json:"username"`
+	Email     string     `json:"email"`
+	IsActive  bool       `json:"is_active"`
+	IsBlocked bool       `json:"is_blocked"`
+	LastLogin *time.Time `json:"last_login"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// UserDataExport is the archive of everything stored about a user, returned by the data export.
+type UserDataExport struct {
+	ExportedAt         time.Time                    `json:"exported_at"`
+	Profile            AccountProfile               `json:"profile"`
+	Permissions        []string                     `json:"permissions"`
+	Sessions           []entities.Session           `json:"sessions"`
+	Addresses          []entities.Address           `json:"addresses"`
+	Orders             []entities.Order             `json:"orders"`
+	VendorApplications []entities.VendorApplication `json:"vendor_applications"`
+	AuditEntries       []entities.AuditLog          `json:"audit_entries"`
+	Erasure            *entities.ErasureRequest     `json:"erasure,omitempty"`
 }
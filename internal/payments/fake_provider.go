@@ -0,0 +1,204 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Payment methods understood by FakeProvider, in the spirit of gateway test cards.
+const (
+	FakeMethodSuccess      = "fake_success"       // authorized immediately
+	FakeMethodDecline      = "fake_decline"       // declined immediately
+	FakeMethodAsync        = "fake_async"         // pending, then authorized by webhook
+	FakeMethodAsyncDecline = "fake_async_decline" // pending, then declined by webhook
+)
+
+// FakeSignatureHeader carries the hex HMAC-SHA256 of a fake webhook body.
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// FakeProvider is an in-memory gateway that simulates success, declines and asynchronous
+// webhook callbacks, so the payment flow can be exercised without a real gateway.
+type FakeProvider struct {
+	secret        []byte
+	callbackURL   string
+	callbackDelay time.Duration
+	client        *http.Client
+
+	mu      sync.Mutex
+	nextRef int
+	results map[string]Result      // by idempotency key
+	charges map[string]*fakeCharge // by reference
+}
+
+type fakeCharge struct {
+	authorized int64
+	captured   int64
+	refunded   int64
+	status     Status
+}
+
+// NewFakeProvider creates a new instance of FakeProvider. Asynchronous outcomes are posted,
+// signed with secret, to callbackURL after callbackDelay; an empty callbackURL disables them.
+func NewFakeProvider(secret, callbackURL string, callbackDelay time.Duration) *FakeProvider {
+	return &FakeProvider{
+		secret:        []byte(secret),
+		callbackURL:   callbackURL,
+		callbackDelay: callbackDelay,
+		client:        &http.Client{Timeout: 10 * time.Second},
+		results:       make(map[string]Result),
+		charges:       make(map[string]*fakeCharge),
+	}
+}
+
+// Name returns the provider name stored with payments.
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+// Authorize reserves the amount according to the payment method, see the FakeMethod constants.
+func (p *FakeProvider) Authorize(ctx context.Context, req AuthorizeRequest) (Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if result, ok := p.results[req.IdempotencyKey]; ok {
+		return result, nil
+	}
+
+	p.nextRef++
+	result := Result{Reference: fmt.Sprintf("fake_ch_%d", p.nextRef)}
+	charge := &fakeCharge{authorized: req.AmountCents}
+
+	switch req.PaymentMethod {
+	case FakeMethodDecline:
+		result.Status = StatusDeclined
+		result.DeclineReason = "card_declined"
+		charge.status = StatusDeclined
+	case FakeMethodAsync:
+		// The charge is settled now but only reported when the webhook fires
+		result.Status = StatusPending
+		charge.status = StatusAuthorized
+		p.scheduleCallback(WebhookEvent{ID: result.Reference + "_evt", Reference: result.Reference, Status: StatusAuthorized})
+	case FakeMethodAsyncDecline:
+		result.Status = StatusPending
+		charge.status = StatusDeclined
+		p.scheduleCallback(WebhookEvent{ID: result.Reference + "_evt", Reference: result.Reference, Status: StatusDeclined, DeclineReason: "insufficient_funds"})
+	default:
+		result.Status = StatusAuthorized
+		charge.status = StatusAuthorized
+	}
+
+	p.charges[result.Reference] = charge
+	p.results[req.IdempotencyKey] = result
+	return result, nil
+}
+
+// Capture collects up to the authorized amount.
+func (p *FakeProvider) Capture(ctx context.Context, req CaptureRequest) (Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if result, ok := p.results[req.IdempotencyKey]; ok {
+		return result, nil
+	}
+
+	charge, ok := p.charges[req.Reference]
+	if !ok {
+		return Result{}, fmt.Errorf("fake provider: unknown charge %s", req.Reference)
+	}
+	result := Result{Reference: req.Reference, Status: StatusCaptured}
+	if charge.status != StatusAuthorized || req.AmountCents > charge.authorized {
+		result.Status = StatusDeclined
+		result.DeclineReason = "capture_not_allowed"
+	} else {
+		charge.captured = req.AmountCents
+		charge.status = StatusCaptured
+	}
+
+	p.results[req.IdempotencyKey] = result
+	return result, nil
+}
+
+// Refund gives back up to the captured amount not yet refunded.
+func (p *FakeProvider) Refund(ctx context.Context, req RefundRequest) (Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if result, ok := p.results[req.IdempotencyKey]; ok {
+		return result, nil
+	}
+
+	charge, ok := p.charges[req.Reference]
+	if !ok {
+		return Result{}, fmt.Errorf("fake provider: unknown charge %s", req.Reference)
+	}
+	result := Result{Reference: req.Reference, Status: StatusRefunded}
+	if charge.captured == 0 || req.AmountCents > charge.captured-charge.refunded {
+		result.Status = StatusDeclined
+		result.DeclineReason = "refund_exceeds_capture"
+	} else {
+		charge.refunded += req.AmountCents
+	}
+
+	p.results[req.IdempotencyKey] = result
+	return result, nil
+}
+
+// VerifyWebhook checks the FakeSignatureHeader and decodes the event.
+func (p *FakeProvider) VerifyWebhook(header http.Header, body []byte) (WebhookEvent, error) {
+	signature, err := hex.DecodeString(header.Get(FakeSignatureHeader))
+	if err != nil || !hmac.Equal(signature, p.sign(body)) {
+		return WebhookEvent{}, ErrInvalidSignature
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return WebhookEvent{}, fmt.Errorf("fake provider: invalid webhook body: %w", err)
+	}
+	return event, nil
+}
+
+func (p *FakeProvider) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// scheduleCallback posts a signed webhook for the event after the callback delay.
+func (p *FakeProvider) scheduleCallback(event WebhookEvent) {
+	if p.callbackURL == "" {
+		slog.Warn("Fake payment provider has no callback URL, dropping webhook", "reference", event.Reference)
+		return
+	}
+
+	time.AfterFunc(p.callbackDelay, func() {
+		body, err := json.Marshal(event)
+		if err != nil {
+			slog.Error("Error encoding fake payment webhook", "error", err)
+			return
+		}
+		req, err := http.NewRequest(http.MethodPost, p.callbackURL, bytes.NewReader(body))
+		if err != nil {
+			slog.Error("Error creating fake payment webhook", "error", err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(FakeSignatureHeader, hex.EncodeToString(p.sign(body)))
+
+		resp, err := p.client.Do(req)
+		if err != nil {
+			slog.Error("Error sending fake payment webhook", "reference", event.Reference, "error", err)
+			return
+		}
+		resp.Body.Close()
+		slog.Info("Fake payment webhook answered", "reference", event.Reference, "status_code", resp.StatusCode)
+	})
+}
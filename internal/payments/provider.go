@@ -0,0 +1,71 @@
+// Package payments defines the interface to payment gateways and a fake gateway for local development.
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Status is the state of a charge at the provider.
+type Status string
+
+const (
+	StatusAuthorized Status = "authorized" // funds reserved, waiting for capture
+	StatusCaptured   Status = "captured"
+	StatusPending    Status = "pending" // outcome will be reported by webhook
+	StatusDeclined   Status = "declined"
+	StatusRefunded   Status = "refunded"
+)
+
+// ErrInvalidSignature is returned when a webhook isn't signed by the provider.
+var ErrInvalidSignature = errors.New("payments: invalid webhook signature")
+
+// AuthorizeRequest asks the provider to reserve an amount on a payment method.
+type AuthorizeRequest struct {
+	IdempotencyKey string // repeated requests with the same key return the original result
+	OrderID        int
+	AmountCents    int64
+	Currency       string
+	PaymentMethod  string
+}
+
+// CaptureRequest asks the provider to collect a previously authorized amount.
+type CaptureRequest struct {
+	IdempotencyKey string
+	Reference      string
+	AmountCents    int64
+}
+
+// RefundRequest asks the provider to give back a captured amount.
+type RefundRequest struct {
+	IdempotencyKey string
+	Reference      string
+	AmountCents    int64
+}
+
+// Result is the provider's answer to a request. Declines are results, not errors.
+type Result struct {
+	Reference     string // provider's ID for the charge
+	Status        Status
+	DeclineReason string
+}
+
+// WebhookEvent is an asynchronous update about a charge.
+type WebhookEvent struct {
+	ID            string
+	Reference     string
+	Status        Status
+	DeclineReason string
+}
+
+// Provider is a payment gateway. Errors are reserved for failures to reach or understand the
+// provider; the caller may retry them with the same idempotency key.
+type Provider interface {
+	Name() string
+	Authorize(ctx context.Context, req AuthorizeRequest) (Result, error)
+	Capture(ctx context.Context, req CaptureRequest) (Result, error)
+	Refund(ctx context.Context, req RefundRequest) (Result, error)
+	// VerifyWebhook checks a webhook's signature and decodes it.
+	VerifyWebhook(header http.Header, body []byte) (WebhookEvent, error)
+}
@@ -0,0 +1,179 @@
+package repositories
+
+import (
+	"backendGoAuth/internal/entities"
+	"database/sql"
+	"errors"
+	"log/slog"
+)
+
+// addressColumns lists the columns read by scanAddress, in order.
+const addressColumns = "id, user_id, street, city, state, zip_code, country, is_default_shipping, is_default_billing, archived_at, created_at, updated_at"
+
+// AddressRepository is the concrete struct for interacting with user addresses.
+type AddressRepository struct {
+	db *sql.DB
+}
+
+// NewAddressRepository creates a new instance of AddressRepository.
+func NewAddressRepository(db *sql.DB) *AddressRepository {
+	return &AddressRepository{db}
+}
+
+// DB returns the underlying database connection, for running repository methods in a transaction.
+func (r *AddressRepository) DB() *sql.DB {
+	return r.db
+}
+
+// LockAddressBook serializes changes to a user's addresses until the end of the transaction,
+// so two requests can't both set a default.
+func (r *AddressRepository) LockAddressBook(tx *sql.Tx, userID int) error {
+	_, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('addresses'), $1)", userID)
+	return err
+}
+
+// GetAddresses retrieves a user's addresses that aren't archived.
+func (r *AddressRepository) GetAddresses(userID int) ([]entities.Address, error) {
+	rows, err := r.db.Query("SELECT "+addressColumns+" FROM addresses WHERE user_id = $1 AND archived_at IS NULL ORDER BY id", userID)
+	if err != nil {
+		slog.Error("Error querying addresses", "error", err)
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	addresses := []entities.Address{}
+	for rows.Next() {
+		address, err := scanAddress(rows)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, rows.Err()
+}
+
+// GetAddress retrieves one of a user's addresses that isn't archived. It returns nil if there is none.
+func (r *AddressRepository) GetAddress(exec DBExecutor, userID, addressID int) (*entities.Address, error) {
+	if exec == nil {
+		exec = r.db
+	}
+	return r.getAddress(exec, "SELECT "+addressColumns+" FROM addresses WHERE id = $1 AND user_id = $2 AND archived_at IS NULL", addressID, userID)
+}
+
+// GetDefaultShippingAddress retrieves a user's default shipping address. It returns nil if there is none.
+func (r *AddressRepository) GetDefaultShippingAddress(exec DBExecutor, userID int) (*entities.Address, error) {
+	if exec == nil {
+		exec = r.db
+	}
+	return r.getAddress(exec, "SELECT "+addressColumns+" FROM addresses WHERE user_id = $1 AND is_default_shipping AND archived_at IS NULL", userID)
+}
+
+// HasDefaults reports whether the user has a default shipping and a default billing address.
+func (r *AddressRepository) HasDefaults(tx *sql.Tx, userID int) (shipping, billing bool, err error) {
+	err = tx.QueryRow(`
+    SELECT COALESCE(BOOL_OR(is_default_shipping), FALSE), COALESCE(BOOL_OR(is_default_billing), FALSE)
+    FROM addresses WHERE user_id = $1 AND archived_at IS NULL`, userID).Scan(&shipping, &billing)
+	return shipping, billing, err
+}
+
+// ClearDefaults unsets the user's default shipping and/or billing address.
+func (r *AddressRepository) ClearDefaults(tx *sql.Tx, userID int, shipping, billing bool) error {
+	_, err := tx.Exec(`
+    UPDATE addresses
+    SET is_default_shipping = is_default_shipping AND NOT $2,
+        is_default_billing = is_default_billing AND NOT $3,
+        updated_at = CURRENT_TIMESTAMP
+    WHERE user_id = $1 AND archived_at IS NULL AND ((is_default_shipping AND $2) OR (is_default_billing AND $3))`,
+		userID, shipping, billing)
+	return err
+}
+
+// InsertAddress inserts an address.
+func (r *AddressRepository) InsertAddress(tx *sql.Tx, address *entities.Address) error {
+	err := tx.QueryRow(`
+    INSERT INTO addresses (user_id, street, city, state, zip_code, country, is_default_shipping, is_default_billing)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    RETURNING id, created_at, updated_at`,
+		address.UserID, address.Street, address.City, address.State, address.ZipCode, address.Country,
+		address.IsDefaultShipping, address.IsDefaultBilling,
+	).Scan(&address.ID, &address.CreatedAt, &address.UpdatedAt)
+	if err != nil {
+		slog.Error("Error inserting address", "error", err)
+	}
+	return err
+}
+
+// UpdateAddress updates an address in place.
+func (r *AddressRepository) UpdateAddress(tx *sql.Tx, address *entities.Address) error {
+	err := tx.QueryRow(`
+    UPDATE addresses
+    SET street = $2, city = $3, state = $4, zip_code = $5, country = $6,
+        is_default_shipping = $7, is_default_billing = $8, updated_at = CURRENT_TIMESTAMP
+    WHERE id = $1
+    RETURNING updated_at`,
+		address.ID, address.Street, address.City, address.State, address.ZipCode, address.Country,
+		address.IsDefaultShipping, address.IsDefaultBilling,
+	).Scan(&address.UpdatedAt)
+	if err != nil {
+		slog.Error("Error updating address", "address_id", address.ID, "error", err)
+	}
+	return err
+}
+
+// IsAddressReferenced reports whether any order was shipped to the address.
+func (r *AddressRepository) IsAddressReferenced(tx *sql.Tx, addressID int) (bool, error) {
+	var referenced bool
+	err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM orders WHERE shipping_address_id = $1)", addressID).Scan(&referenced)
+	return referenced, err
+}
+
+// ArchiveAddress hides an address from the address book while keeping it for the orders that reference it.
+func (r *AddressRepository) ArchiveAddress(tx *sql.Tx, addressID int) error {
+	_, err := tx.Exec(`
+    UPDATE addresses
+    SET archived_at = CURRENT_TIMESTAMP, is_default_shipping = FALSE, is_default_billing = FALSE, updated_at = CURRENT_TIMESTAMP
+    WHERE id = $1`, addressID)
+	return err
+}
+
+// DeleteAddress deletes an address.
+func (r *AddressRepository) DeleteAddress(tx *sql.Tx, addressID int) error {
+	_, err := tx.Exec("DELETE FROM addresses WHERE id = $1", addressID)
+	return err
+}
+
+func (r *AddressRepository) getAddress(exec DBExecutor, query string, args ...interface{}) (*entities.Address, error) {
+	address, err := scanAddress(exec.QueryRow(query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		slog.Error("Error retrieving address", "error", err)
+		return nil, err
+	}
+	return &address, nil
+}
+
+// scanAddress scans a row selected with addressColumns.
+func scanAddress(row interface{ Scan(...interface{}) error }) (entities.Address, error) {
+	var address entities.Address
+	var archivedAt sql.NullTime
+	err := row.Scan(
+		&address.ID,
+		&address.UserID,
+		&address.Street,
+		&address.City,
+		&address.State,
+		&address.ZipCode,
+		&address.Country,
+		&address.IsDefaultShipping,
+		&address.IsDefaultBilling,
+		&archivedAt,
+		&address.CreatedAt,
+		&address.UpdatedAt,
+	)
+	if archivedAt.Valid {
+		address.ArchivedAt = &archivedAt.Time
+	}
+	return address, err
+}
@@ -0,0 +1,236 @@
+package repositories
+
+import (
+	"backendGoAuth/internal/entities"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// auditChainLockID is the advisory lock serializing audit chain sealing across instances.
+const auditChainLockID = 7263001
+
+// auditLogColumns lists the columns read by scanAuditLog, in order.
+const auditLogColumns = `id, user_id, actor_id, target_id, action, COALESCE(table_name, ''), row_id, old_data, new_data,
+       COALESCE(ip_address, ''), COALESCE(user_agent, ''), created_at,
+       chain_seq, COALESCE(content_digest, ''), COALESCE(prev_hash, ''), COALESCE(hash, ''), redacted_at`
+
+// AuditSealFunc computes the content digest and chain hash of an entry given its
+// position in the chain and the hash of the previous entry.
+type AuditSealFunc func(entry entities.AuditLog, chainSeq int64, prevHash string) (contentDigest, hash string)
+
+// AuditLogFilter narrows down an audit log query. Zero values are ignored.
+type AuditLogFilter struct {
+	ActorID  int
+	TargetID int
+	Action   string
+	From     time.Time
+	To       time.Time
+	Limit    int
+	Offset   int
+}
+
+// AuditRepository is the concrete struct for writing to and querying the user audit log.
+type AuditRepository struct {
+	db *sql.DB
+}
+
+// NewAuditRepository creates a new instance of AuditRepository.
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db}
+}
+
+// InsertAuditLog records an audit event and returns its ID. Pass a transaction to record
+// the event along with the change it describes.
+func (r *AuditRepository) InsertAuditLog(exec DBExecutor, entry entities.AuditLog) (int, error) {
+	if exec == nil {
+		exec = r.db
+	}
+
+	var id int
+	err := exec.QueryRow(
+		`INSERT INTO user_audit_logs (user_id, actor_id, target_id, action, table_name, row_id, old_data, new_data, ip_address, user_agent)
+VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, NULLIF($9, ''), NULLIF($10, '')) RETURNING id`,
+		entry.UserID, entry.ActorID, entry.TargetID, entry.Action, entry.TableName, entry.RowID,
+		nullableJSON(entry.OldData), nullableJSON(entry.NewData), entry.IPAddress, entry.UserAgent,
+	).Scan(&id)
+	if err != nil {
+		slog.Error("Error inserting audit log", "error", err)
+		return 0, err
+	}
+	return id, nil
+}
+
+// QueryAuditLogs retrieves the audit logs matching the filter, newest first, along with the total number of matches.
+func (r *AuditRepository) QueryAuditLogs(filter AuditLogFilter) ([]entities.AuditLog, int, error) {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.ActorID != 0 {
+		addCondition("actor_id = $%d", filter.ActorID)
+	}
+	if filter.TargetID != 0 {
+		addCondition("target_id = $%d", filter.TargetID)
+	}
+	if filter.Action != "" {
+		addCondition("action = $%d", filter.Action)
+	}
+	if !filter.From.IsZero() {
+		addCondition("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("created_at < $%d", filter.To)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM user_audit_logs"+where, args...).Scan(&total); err != nil {
+		slog.Error("Error counting audit logs", "error", err)
+		return nil, 0, err
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf("SELECT %s FROM user_audit_logs%s ORDER BY id DESC LIMIT $%d OFFSET $%d", auditLogColumns, where, len(args)-1, len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		slog.Error("Error querying audit logs", "error", err)
+		return nil, 0, err
+	}
+	defer closeRows(rows)
+
+	logs := []entities.AuditLog{}
+	for rows.Next() {
+		entry, err := scanAuditLog(rows)
+		if err != nil {
+			slog.Error("Error scanning audit log", "error", err)
+			return nil, 0, err
+		}
+		logs = append(logs, entry)
+	}
+	return logs, total, rows.Err()
+}
+
+// SealPendingAuditLogs appends up to limit unsealed entries to the hash chain, in insertion order.
+// Sealing is serialized across instances with an advisory lock.
+func (r *AuditRepository) SealPendingAuditLogs(limit int, seal AuditSealFunc) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			slog.Error("Error rolling back audit chain sealing", "error", err)
+		}
+	}()
+
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", auditChainLockID); err != nil {
+		return 0, err
+	}
+
+	var lastSeq int64
+	var prevHash string
+	err = tx.QueryRow("SELECT chain_seq, hash FROM user_audit_logs WHERE chain_seq IS NOT NULL ORDER BY chain_seq DESC LIMIT 1").Scan(&lastSeq, &prevHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	rows, err := tx.Query(fmt.Sprintf("SELECT %s FROM user_audit_logs WHERE chain_seq IS NULL ORDER BY id LIMIT $1 FOR UPDATE", auditLogColumns), limit)
+	if err != nil {
+		return 0, err
+	}
+	var pending []entities.AuditLog
+	for rows.Next() {
+		entry, err := scanAuditLog(rows)
+		if err != nil {
+			closeRows(rows)
+			return 0, err
+		}
+		pending = append(pending, entry)
+	}
+	closeRows(rows)
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, entry := range pending {
+		lastSeq++
+		contentDigest, hash := seal(entry, lastSeq, prevHash)
+		if _, err := tx.Exec(
+			"UPDATE user_audit_logs SET chain_seq = $1, content_digest = $2, prev_hash = NULLIF($3, ''), hash = $4 WHERE id = $5",
+			lastSeq, contentDigest, prevHash, hash, entry.ID,
+		); err != nil {
+			return 0, err
+		}
+		prevHash = hash
+	}
+
+	return len(pending), tx.Commit()
+}
+
+// IterateAuditChain calls fn for every sealed entry with fromSeq <= chain_seq <= toSeq,
+// in chain order. A toSeq of 0 means up to the end of the chain.
+func (r *AuditRepository) IterateAuditChain(fromSeq, toSeq int64, fn func(entry entities.AuditLog) error) error {
+	rows, err := r.db.Query(
+		fmt.Sprintf("SELECT %s FROM user_audit_logs WHERE chain_seq >= $1 AND ($2 = 0 OR chain_seq <= $2) ORDER BY chain_seq", auditLogColumns),
+		fromSeq, toSeq,
+	)
+	if err != nil {
+		return err
+	}
+	defer closeRows(rows)
+
+	for rows.Next() {
+		entry, err := scanAuditLog(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// CountUnsealedAuditLogs returns the number of entries not yet part of the hash chain.
+func (r *AuditRepository) CountUnsealedAuditLogs() (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM user_audit_logs WHERE chain_seq IS NULL").Scan(&count)
+	return count, err
+}
+
+func scanAuditLog(rows *sql.Rows) (entities.AuditLog, error) {
+	var entry entities.AuditLog
+	var oldData, newData []byte
+	err := rows.Scan(&entry.ID, &entry.UserID, &entry.ActorID, &entry.TargetID, &entry.Action, &entry.TableName, &entry.RowID,
+		&oldData, &newData, &entry.IPAddress, &entry.UserAgent, &entry.CreatedAt,
+		&entry.ChainSeq, &entry.ContentDigest, &entry.PrevHash, &entry.Hash, &entry.RedactedAt)
+	entry.OldData = oldData
+	entry.NewData = newData
+	return entry, err
+}
+
+func closeRows(rows *sql.Rows) {
+	if err := rows.Close(); err != nil {
+		slog.Error("Error closing rows", "error", err)
+	}
+}
+
+// nullableJSON turns an empty JSON payload into a SQL NULL.
+func nullableJSON(data []byte) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
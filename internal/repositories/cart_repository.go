@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"backendGoAuth/internal/entities"
+	"database/sql"
+	"errors"
+	"log/slog"
+)
+
+// CartRepository is the concrete struct for interacting with carts and cart items.
+type CartRepository struct {
+	db *sql.DB
+}
+
+// NewCartRepository creates a new instance of CartRepository.
+func NewCartRepository(db *sql.DB) *CartRepository {
+	return &CartRepository{db}
+}
+
+// DB returns the underlying database connection, for running repository methods in a transaction.
+func (r *CartRepository) DB() *sql.DB {
+	return r.db
+}
+
+// LockCart returns the user's current cart, creating it if needed, and locks it
+// until the end of the transaction so concurrent updates (e.g. two tabs) are serialized.
+func (r *CartRepository) LockCart(tx *sql.Tx, userID int) (entities.Cart, error) {
+	// Serialize cart creation for the user, since there's no row to lock yet
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('carts'), $1)", userID); err != nil {
+		return entities.Cart{}, err
+	}
+
+	cart := entities.Cart{UserID: userID}
+	err := tx.QueryRow(
+		"SELECT id, COALESCE(version, 0), created_at, updated_at FROM carts WHERE user_id = $1 ORDER BY id DESC LIMIT 1 FOR UPDATE",
+		userID,
+	).Scan(&cart.ID, &cart.Version, &cart.CreatedAt, &cart.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = tx.QueryRow(
+			"INSERT INTO carts (user_id, version) VALUES ($1, 0) RETURNING id, version, created_at, updated_at",
+			userID,
+		).Scan(&cart.ID, &cart.Version, &cart.CreatedAt, &cart.UpdatedAt)
+	}
+	if err != nil {
+		slog.Error("Error locking cart for user", "user_id", userID, "error", err)
+		return entities.Cart{}, err
+	}
+	return cart, nil
+}
+
+// GetCartItems retrieves the items of a cart priced with the products' current prices.
+func (r *CartRepository) GetCartItems(exec DBExecutor, cartID int) ([]entities.CartItem, error) {
+	if exec == nil {
+		exec = r.db
+	}
+
+	rows, err := exec.Query(`
+    SELECT ci.id, ci.cart_id, ci.product_id, p.name, p.price, ci.quantity, p.price * ci.quantity, ci.created_at, ci.updated_at
+    FROM cart_items ci JOIN products p ON p.id = ci.product_id
+    WHERE ci.cart_id = $1
+    ORDER BY ci.id`, cartID)
+	if err != nil {
+		slog.Error("Error querying cart items", "error", err)
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	items := []entities.CartItem{}
+	for rows.Next() {
+		var item entities.CartItem
+		if err := rows.Scan(
+			&item.ID,
+			&item.CartID,
+			&item.ProductID,
+			&item.Name,
+			&item.UnitPrice,
+			&item.Quantity,
+			&item.LineTotal,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetItemQuantity returns the quantity of a product in a cart, 0 if absent.
+func (r *CartRepository) GetItemQuantity(tx *sql.Tx, cartID, productID int) (int, error) {
+	var quantity int
+	err := tx.QueryRow("SELECT quantity FROM cart_items WHERE cart_id = $1 AND product_id = $2", cartID, productID).Scan(&quantity)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return quantity, err
+}
+
+// SetItemQuantity sets the quantity of a product in a cart, adding the line if needed.
+func (r *CartRepository) SetItemQuantity(tx *sql.Tx, cartID, productID, quantity int) error {
+	_, err := tx.Exec(`
+    INSERT INTO cart_items (cart_id, product_id, quantity) VALUES ($1, $2, $3)
+    ON CONFLICT (cart_id, product_id) DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = CURRENT_TIMESTAMP`,
+		cartID, productID, quantity)
+	return err
+}
+
+// RemoveItem removes a product from a cart. It returns false if the product wasn't in the cart.
+func (r *CartRepository) RemoveItem(tx *sql.Tx, cartID, productID int) (bool, error) {
+	result, err := tx.Exec("DELETE FROM cart_items WHERE cart_id = $1 AND product_id = $2", cartID, productID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ClearCart removes every item from a cart.
+func (r *CartRepository) ClearCart(tx *sql.Tx, cartID int) error {
+	_, err := tx.Exec("DELETE FROM cart_items WHERE cart_id = $1", cartID)
+	return err
+}
+
+// TouchCart bumps a cart's version and updated_at after a change.
+func (r *CartRepository) TouchCart(tx *sql.Tx, cart *entities.Cart) error {
+	return tx.QueryRow(
+		"UPDATE carts SET version = COALESCE(version, 0) + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING version, updated_at",
+		cart.ID,
+	).Scan(&cart.Version, &cart.UpdatedAt)
+}
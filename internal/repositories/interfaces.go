@@ -0,0 +1,198 @@
+package repositories
+
+import (
+	"backendGoAuth/internal/entities"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// UserRepositoryInterface stores users and their login state. DB returns nil for
+// repositories that aren't backed by Postgres.
+type UserRepositoryInterface interface {
+	DB() *sql.DB
+	GetAllUsers(ctx context.Context) ([]entities.User, error)
+	LockUser(ctx context.Context, exec DBExecutor, userID int) (*entities.User, error)
+	EditUser(ctx context.Context, exec DBExecutor, user entities.User) error
+	DeleteUser(ctx context.Context, userID int) error
+	InsertUser(ctx context.Context, exec DBExecutor, username, password, email string) (int, error)
+	AssignRole(ctx context.Context, exec DBExecutor, userID int, role string) error
+	UserExistsByUsername(ctx context.Context, username string) (bool, error)
+	UserExistsByEmail(ctx context.Context, email string) (bool, error)
+	GetUserByEmail(ctx context.Context, email string) (*entities.User, error)
+	GetUserByID(ctx context.Context, userID int) (*entities.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*entities.User, error)
+	RecordFailedLogin(ctx context.Context, exec DBExecutor, userID, maxAttempts int) (int, bool, error)
+	RecordSuccessfulLogin(ctx context.Context, userID int) error
+}
+
+// SessionRepositoryInterface stores sessions and their activity. DB returns nil for
+// repositories that aren't backed by Postgres.
+type SessionRepositoryInterface interface {
+	DB() *sql.DB
+	InsertSession(ctx context.Context, exec DBExecutor, session entities.Session) (int, error)
+	GetActiveSessions(ctx context.Context, userID int) ([]entities.Session, error)
+	RevokeSession(ctx context.Context, exec DBExecutor, sessionID int) (int, error)
+	RevokeUserSessions(ctx context.Context, exec DBExecutor, userID int) ([]int, error)
+	ExpireImpersonationSessions(ctx context.Context, exec DBExecutor, issuedBefore time.Time) ([]entities.Session, error)
+	CheckSession(ctx context.Context, sessionId int) (bool, error)
+	GetSessionByID(ctx context.Context, sessionID int) (*entities.Session, error)
+	GetSessionOwners(ctx context.Context, sessionID int) (*entities.Session, error)
+	UpdateSessionUpdatedAt(ctx context.Context, userID int) error
+	CountActiveSessions(ctx context.Context) (int, error)
+	InvalidateSession(ctx context.Context, sessionID int)
+	GetSessionActivity(ctx context.Context, sessionIDs []int) (map[int][]entities.SessionActivity, error)
+	FlushSessionActivity(ctx context.Context, updates []SessionActivityUpdate, historyLimit int) error
+}
+
+// OutboxRepositoryInterface queues domain events for webhook delivery.
+type OutboxRepositoryInterface interface {
+	Enqueue(exec DBExecutor, eventType string, payload interface{}) error
+	RedactUser(exec DBExecutor, userID int) error
+}
+
+// AuditRepositoryInterface stores and queries audit log entries.
+type AuditRepositoryInterface interface {
+	InsertAuditLog(exec DBExecutor, entry entities.AuditLog) (int, error)
+	QueryAuditLogs(filter AuditLogFilter) ([]entities.AuditLog, int, error)
+}
+
+// AuditChainRepositoryInterface seals audit log entries into the hash chain and reads the chain back.
+type AuditChainRepositoryInterface interface {
+	SealPendingAuditLogs(limit int, seal AuditSealFunc) (int, error)
+	IterateAuditChain(fromSeq, toSeq int64, fn func(entry entities.AuditLog) error) error
+	CountUnsealedAuditLogs() (int, error)
+}
+
+// PermissionRepositoryInterface resolves the roles and permissions of users.
+type PermissionRepositoryInterface interface {
+	UserHasPermission(userID int, permission string) (bool, error)
+	GetUserPermissions(userID int) ([]string, error)
+	UserHasRole(userID int, role string) (bool, error)
+	NotifyPermissionsChanged(userID int)
+}
+
+// WebhookRepositoryInterface stores webhook endpoints and the deliveries of events to them.
+type WebhookRepositoryInterface interface {
+	InsertEndpoint(endpoint entities.WebhookEndpoint) (int, error)
+	GetEndpoints() ([]entities.WebhookEndpoint, error)
+	DeactivateEndpoint(endpointID int) (bool, error)
+	GetDeliveries(status string, limit int) ([]entities.WebhookDelivery, error)
+	ReplayDelivery(deliveryID int64) (bool, error)
+	FanOutOutbox(limit int) (int, error)
+	ClaimDueDeliveries(limit int, lease time.Duration) ([]ClaimedDelivery, error)
+	MarkDelivered(deliveryID int64, statusCode int) error
+	MarkFailed(deliveryID int64, statusCode int, lastError string, nextAttemptAt *time.Time) error
+}
+
+// ProductRepositoryInterface stores the catalog and searches it.
+type ProductRepositoryInterface interface {
+	ListProducts(filter ProductFilter) ([]entities.Product, int, error)
+	GetProductByID(productID int) (*entities.Product, error)
+	InsertProduct(product entities.Product) (int, error)
+	UpdateProduct(product entities.Product) error
+	DeleteProduct(productID int) error
+	GetCategories() ([]entities.Category, error)
+	CategoryExists(categoryID int) (bool, error)
+	SearchProducts(filter ProductSearchFilter) ([]ProductSearchHit, int, error)
+	GetCategoryFacets(filter ProductSearchFilter) ([]CategoryFacet, error)
+	GetPriceFacets(filter ProductSearchFilter, bounds []float64) ([]PriceFacet, error)
+	SuggestProductNames(tsQuery string, limit int) ([]string, error)
+}
+
+// CartRepositoryInterface stores shopping carts. DB returns nil for repositories that
+// aren't backed by Postgres, whose transactions are then nil.
+type CartRepositoryInterface interface {
+	DB() *sql.DB
+	LockCart(tx *sql.Tx, userID int) (entities.Cart, error)
+	GetCartItems(exec DBExecutor, cartID int) ([]entities.CartItem, error)
+	GetItemQuantity(tx *sql.Tx, cartID, productID int) (int, error)
+	SetItemQuantity(tx *sql.Tx, cartID, productID, quantity int) error
+	RemoveItem(tx *sql.Tx, cartID, productID int) (bool, error)
+	ClearCart(tx *sql.Tx, cartID int) error
+	TouchCart(tx *sql.Tx, cart *entities.Cart) error
+}
+
+// AddressRepositoryInterface stores address books. DB returns nil for repositories that
+// aren't backed by Postgres, whose transactions are then nil.
+type AddressRepositoryInterface interface {
+	DB() *sql.DB
+	LockAddressBook(tx *sql.Tx, userID int) error
+	GetAddresses(userID int) ([]entities.Address, error)
+	GetAddress(exec DBExecutor, userID, addressID int) (*entities.Address, error)
+	GetDefaultShippingAddress(exec DBExecutor, userID int) (*entities.Address, error)
+	HasDefaults(tx *sql.Tx, userID int) (shipping, billing bool, err error)
+	ClearDefaults(tx *sql.Tx, userID int, shipping, billing bool) error
+	InsertAddress(tx *sql.Tx, address *entities.Address) error
+	UpdateAddress(tx *sql.Tx, address *entities.Address) error
+	IsAddressReferenced(tx *sql.Tx, addressID int) (bool, error)
+	ArchiveAddress(tx *sql.Tx, addressID int) error
+	DeleteAddress(tx *sql.Tx, addressID int) error
+}
+
+// InventoryRepositoryInterface tracks stock levels and the stock reserved by orders. DB
+// returns nil for repositories that aren't backed by Postgres, whose transactions are then nil.
+type InventoryRepositoryInterface interface {
+	DB() *sql.DB
+	ReserveStock(tx *sql.Tx, orderID int, items []entities.OrderItem, expiresAt time.Time) error
+	CommitReservations(tx *sql.Tx, orderID int) error
+	ReleaseReservations(tx *sql.Tx, orderID int) error
+	RestockOrder(tx *sql.Tx, orderID int) error
+	GetOrdersWithExpiredReservations(limit int) ([]int, error)
+	SetStock(productID, stockQuantity, lowStockThreshold int) (bool, error)
+	GetLowStockProducts(vendorID int) ([]entities.Product, error)
+}
+
+// OrderRepositoryInterface stores orders and their status history. DB returns nil for
+// repositories that aren't backed by Postgres, whose transactions are then nil.
+type OrderRepositoryInterface interface {
+	DB() *sql.DB
+	ListOrders(filter OrderFilter) ([]entities.Order, int, error)
+	GetOrderByID(orderID int) (*entities.Order, error)
+	GetOrderItems(orderIDs []int, vendorID int) (map[int][]entities.OrderItem, error)
+	InsertOrder(tx *sql.Tx, order *entities.Order) error
+	UpdateOrderStatus(tx *sql.Tx, orderID int, from, to, paymentStatus string, changedBy int) (bool, error)
+	SetPaymentStatus(exec DBExecutor, orderID int, paymentStatus, paymentMethod string) error
+}
+
+// PaymentRepositoryInterface stores the charges and refunds of orders. DB returns nil for
+// repositories that aren't backed by Postgres, whose transactions are then nil.
+type PaymentRepositoryInterface interface {
+	DB() *sql.DB
+	InsertPayment(payment *entities.Payment) (bool, error)
+	GetPaymentByIdempotencyKey(key string) (*entities.Payment, error)
+	GetChargeByReference(provider, reference string) (*entities.Payment, error)
+	GetSucceededCharge(orderID int) (*entities.Payment, error)
+	GetPaymentsByOrder(orderID int) ([]entities.Payment, error)
+	ClaimPayment(paymentID int, from, to string) (bool, error)
+	RetryPayment(payment *entities.Payment, from, paymentMethod string) (bool, error)
+	UpdatePayment(exec DBExecutor, payment *entities.Payment) error
+}
+
+// VendorRepositoryInterface stores vendor applications and reports on vendors' sales. DB
+// returns nil for repositories that aren't backed by Postgres, whose transactions are then nil.
+type VendorRepositoryInterface interface {
+	DB() *sql.DB
+	InsertApplication(application *entities.VendorApplication) error
+	GetApplications(userID int, status string) ([]entities.VendorApplication, error)
+	GetApprovedApplication(userID int) (*entities.VendorApplication, error)
+	ReviewApplication(tx *sql.Tx, applicationID int, status string, reviewerID int, note string) (*entities.VendorApplication, error)
+	GetDashboard(vendorID int) (VendorDashboard, error)
+}
+
+// PrivacyRepositoryInterface exports and erases the personal data of users. DB returns nil
+// for repositories that aren't backed by Postgres, whose transactions are then nil.
+type PrivacyRepositoryInterface interface {
+	DB() *sql.DB
+	GetProfile(userID int) (*entities.User, error)
+	GetSessions(userID int) ([]entities.Session, error)
+	GetAddresses(userID int) ([]entities.Address, error)
+	GetAuditLogs(userID int) ([]entities.AuditLog, error)
+	GetErasureRequest(userID int) (*entities.ErasureRequest, error)
+	ScheduleErasure(userID, requestedBy int, scheduledFor time.Time) (*entities.ErasureRequest, error)
+	CancelErasure(userID int) (bool, error)
+	GetDueErasures(limit int) ([]int, error)
+	LockCheckout(tx *sql.Tx, userID int) error
+	HasOrdersInStatus(exec DBExecutor, userID int, statuses []string) (bool, error)
+	EraseUser(tx *sql.Tx, userID, requestedBy int) ([]int, []entities.AuditLog, error)
+}
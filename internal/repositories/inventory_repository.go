@@ -0,0 +1,201 @@
+package repositories
+
+import (
+	"backendGoAuth/internal/entities"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// InsufficientStockError is returned when a product doesn't have enough available stock for a reservation.
+type InsufficientStockError struct {
+	ProductID int
+	Name      string
+}
+
+func (e *InsufficientStockError) Error() string {
+	return fmt.Sprintf("insufficient stock for product %d", e.ProductID)
+}
+
+// InventoryRepository is the concrete struct for interacting with product stock and reservations.
+type InventoryRepository struct {
+	db *sql.DB
+}
+
+// NewInventoryRepository creates a new instance of InventoryRepository.
+func NewInventoryRepository(db *sql.DB) *InventoryRepository {
+	return &InventoryRepository{db}
+}
+
+// DB returns the underlying database connection, for running repository methods in a transaction.
+func (r *InventoryRepository) DB() *sql.DB {
+	return r.db
+}
+
+// ReserveStock holds stock for an order's items until expiresAt. Each product is reserved with a
+// conditional update, so concurrent checkouts can't reserve more than is on hand. It returns an
+// *InsufficientStockError if a product runs short; the caller must roll back.
+func (r *InventoryRepository) ReserveStock(tx *sql.Tx, orderID int, items []entities.OrderItem, expiresAt time.Time) error {
+	// Lock products in a consistent order so concurrent checkouts can't deadlock
+	sorted := make([]entities.OrderItem, len(items))
+	copy(sorted, items)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ProductID < sorted[j].ProductID })
+
+	for _, item := range sorted {
+		result, err := tx.Exec(`
+        UPDATE products SET reserved_quantity = reserved_quantity + $2
+        WHERE id = $1 AND stock_quantity - reserved_quantity >= $2`,
+			item.ProductID, item.Quantity)
+		if err != nil {
+			slog.Error("Error reserving stock for product", "product_id", item.ProductID, "error", err)
+			return err
+		}
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			if err != nil {
+				return err
+			}
+			return &InsufficientStockError{ProductID: item.ProductID, Name: item.Name}
+		}
+
+		_, err = tx.Exec(
+			"INSERT INTO stock_reservations (order_id, product_id, quantity, expires_at) VALUES ($1, $2, $3, $4)",
+			orderID, item.ProductID, item.Quantity, expiresAt,
+		)
+		if err != nil {
+			slog.Error("Error inserting stock reservation", "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// CommitReservations turns an order's reservations into a stock decrement once it is paid.
+func (r *InventoryRepository) CommitReservations(tx *sql.Tx, orderID int) error {
+	return r.settleReservations(tx, orderID, "COMMITTED",
+		"UPDATE products SET stock_quantity = stock_quantity - $2, reserved_quantity = reserved_quantity - $2 WHERE id = $1")
+}
+
+// ReleaseReservations gives an unpaid order's reserved stock back.
+func (r *InventoryRepository) ReleaseReservations(tx *sql.Tx, orderID int) error {
+	return r.settleReservations(tx, orderID, "RELEASED",
+		"UPDATE products SET reserved_quantity = reserved_quantity - $2 WHERE id = $1")
+}
+
+// RestockOrder puts the stock a paid order took back on hand.
+func (r *InventoryRepository) RestockOrder(tx *sql.Tx, orderID int) error {
+	_, err := tx.Exec(`
+    UPDATE products p SET stock_quantity = p.stock_quantity + sr.quantity
+    FROM stock_reservations sr
+    WHERE sr.product_id = p.id AND sr.order_id = $1 AND sr.status = 'COMMITTED'`, orderID)
+	if err != nil {
+		slog.Error("Error restocking order", "order_id", orderID, "error", err)
+		return err
+	}
+	_, err = tx.Exec("UPDATE stock_reservations SET status = 'RELEASED', updated_at = CURRENT_TIMESTAMP WHERE order_id = $1 AND status = 'COMMITTED'", orderID)
+	return err
+}
+
+// settleReservations moves an order's active reservations to status, applying productUpdate
+// ($1 product ID, $2 quantity) to each product.
+func (r *InventoryRepository) settleReservations(tx *sql.Tx, orderID int, status, productUpdate string) error {
+	rows, err := tx.Query(`
+    UPDATE stock_reservations SET status = $2, updated_at = CURRENT_TIMESTAMP
+    WHERE order_id = $1 AND status = 'RESERVED'
+    RETURNING product_id, quantity`, orderID, status)
+	if err != nil {
+		slog.Error("Error settling reservations of order", "order_id", orderID, "error", err)
+		return err
+	}
+
+	type reservation struct{ productID, quantity int }
+	var reservations []reservation
+	for rows.Next() {
+		var res reservation
+		if err := rows.Scan(&res.productID, &res.quantity); err != nil {
+			closeRows(rows)
+			return err
+		}
+		reservations = append(reservations, res)
+	}
+	closeRows(rows)
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	sort.Slice(reservations, func(i, j int) bool { return reservations[i].productID < reservations[j].productID })
+	for _, res := range reservations {
+		if _, err := tx.Exec(productUpdate, res.productID, res.quantity); err != nil {
+			slog.Error("Error settling stock of product", "product_id", res.productID, "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// GetOrdersWithExpiredReservations returns pending orders whose reservations have expired and that
+// have no payment in flight.
+func (r *InventoryRepository) GetOrdersWithExpiredReservations(limit int) ([]int, error) {
+	rows, err := r.db.Query(`
+    SELECT DISTINCT sr.order_id
+    FROM stock_reservations sr JOIN orders o ON o.id = sr.order_id
+    WHERE sr.status = 'RESERVED' AND sr.expires_at < CURRENT_TIMESTAMP AND o.status = 'PENDING'
+      AND NOT EXISTS (SELECT 1 FROM payments pm WHERE pm.order_id = o.id AND pm.status IN ('PROCESSING', 'PENDING'))
+    ORDER BY sr.order_id
+    LIMIT $1`, limit)
+	if err != nil {
+		slog.Error("Error querying expired reservations", "error", err)
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	var orderIDs []int
+	for rows.Next() {
+		var orderID int
+		if err := rows.Scan(&orderID); err != nil {
+			return nil, err
+		}
+		orderIDs = append(orderIDs, orderID)
+	}
+	return orderIDs, rows.Err()
+}
+
+// SetStock sets a product's stock on hand and low-stock threshold. It returns false if the new
+// stock is below what unpaid orders have reserved.
+func (r *InventoryRepository) SetStock(productID, stockQuantity, lowStockThreshold int) (bool, error) {
+	result, err := r.db.Exec(`
+    UPDATE products SET stock_quantity = $2, low_stock_threshold = $3, updated_at = CURRENT_TIMESTAMP
+    WHERE id = $1 AND reserved_quantity <= $2`,
+		productID, stockQuantity, lowStockThreshold)
+	if err != nil {
+		slog.Error("Error setting stock of product", "product_id", productID, "error", err)
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetLowStockProducts retrieves a vendor's products whose available stock is at or below their
+// threshold, emptiest first.
+func (r *InventoryRepository) GetLowStockProducts(vendorID int) ([]entities.Product, error) {
+	rows, err := r.db.Query(fmt.Sprintf(`
+    SELECT %s FROM products p
+    WHERE p.user_id = $1 AND p.stock_quantity - p.reserved_quantity <= p.low_stock_threshold
+    ORDER BY p.stock_quantity - p.reserved_quantity, p.id`, productColumns), vendorID)
+	if err != nil {
+		slog.Error("Error querying low stock products", "error", err)
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	products := []entities.Product{}
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	return products, rows.Err()
+}
@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/repositories"
+	"sync"
+	"time"
+)
+
+// AuditRepository keeps the audit log in memory.
+type AuditRepository struct {
+	mu      sync.Mutex
+	entries []entities.AuditLog
+}
+
+// NewAuditRepository creates a new instance of AuditRepository.
+func NewAuditRepository() *AuditRepository {
+	return &AuditRepository{}
+}
+
+// InsertAuditLog records an audit event and returns its ID.
+func (r *AuditRepository) InsertAuditLog(exec repositories.DBExecutor, entry entities.AuditLog) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.ID = len(r.entries) + 1
+	entry.CreatedAt = time.Now()
+	r.entries = append(r.entries, entry)
+	return entry.ID, nil
+}
+
+// QueryAuditLogs retrieves the audit logs matching the filter, newest first, along with the total number of matches.
+func (r *AuditRepository) QueryAuditLogs(filter repositories.AuditLogFilter) ([]entities.AuditLog, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matches := []entities.AuditLog{}
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		if entry := r.entries[i]; matchesAuditFilter(entry, filter) {
+			matches = append(matches, entry)
+		}
+	}
+
+	total := len(matches)
+	if filter.Offset >= total {
+		return []entities.AuditLog{}, total, nil
+	}
+	matches = matches[filter.Offset:]
+	if len(matches) > filter.Limit {
+		matches = matches[:filter.Limit]
+	}
+	return matches, total, nil
+}
+
+// matchesAuditFilter checks an entry against the filter, ignoring its zero values.
+func matchesAuditFilter(entry entities.AuditLog, filter repositories.AuditLogFilter) bool {
+	switch {
+	case filter.ActorID != 0 && (entry.ActorID == nil || *entry.ActorID != filter.ActorID):
+		return false
+	case filter.TargetID != 0 && (entry.TargetID == nil || *entry.TargetID != filter.TargetID):
+		return false
+	case filter.Action != "" && entry.Action != filter.Action:
+		return false
+	case !filter.From.IsZero() && entry.CreatedAt.Before(filter.From):
+		return false
+	case !filter.To.IsZero() && !entry.CreatedAt.Before(filter.To):
+		return false
+	}
+	return true
+}
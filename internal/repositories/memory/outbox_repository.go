@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"backendGoAuth/internal/repositories"
+	"encoding/json"
+	"sync"
+)
+
+// OutboxEvent is an event written to the outbox.
+type OutboxEvent struct {
+	EventType string
+	Payload   json.RawMessage
+}
+
+// OutboxRepository keeps the events written to the outbox in memory.
+type OutboxRepository struct {
+	mu     sync.Mutex
+	events []OutboxEvent
+}
+
+// NewOutboxRepository creates a new instance of OutboxRepository.
+func NewOutboxRepository() *OutboxRepository {
+	return &OutboxRepository{}
+}
+
+// Enqueue writes an event to the outbox.
+func (r *OutboxRepository) Enqueue(exec repositories.DBExecutor, eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, OutboxEvent{EventType: eventType, Payload: data})
+	return nil
+}
+
+// RedactUser strips the personal data of a user from the events about them, and their
+// connection details from the events they made.
+func (r *OutboxRepository) RedactUser(exec repositories.DBExecutor, userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, event := range r.events {
+		var payload map[string]interface{}
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return err
+		}
+
+		var keys []string
+		if isUser(payload["user_id"], userID) {
+			keys = repositories.OutboxPIIKeys
+		} else {
+			for _, key := range repositories.OutboxActorKeys {
+				if isUser(payload[key], userID) {
+					keys = []string{"ip_address", "user_agent"}
+				}
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+
+		for _, key := range keys {
+			delete(payload, key)
+		}
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		r.events[i].Payload = data
+	}
+	return nil
+}
+
+// isUser checks if a decoded JSON value is the user's ID.
+func isUser(value interface{}, userID int) bool {
+	id, ok := value.(float64)
+	return ok && int(id) == userID
+}
+
+// Events returns the events written so far, in order.
+func (r *OutboxRepository) Events() []OutboxEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]OutboxEvent(nil), r.events...)
+}
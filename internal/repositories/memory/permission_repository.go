@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"sort"
+)
+
+// PermissionRepository resolves permissions from the roles assigned in a UserRepository.
+type PermissionRepository struct {
+	users *UserRepository
+}
+
+// NewPermissionRepository creates a new instance of PermissionRepository.
+func NewPermissionRepository(users *UserRepository) *PermissionRepository {
+	return &PermissionRepository{users}
+}
+
+// UserHasPermission checks if a user has the specified permission through one of their roles.
+func (r *PermissionRepository) UserHasPermission(userID int, permission string) (bool, error) {
+	return r.users.hasPermission(userID, permission), nil
+}
+
+// GetUserPermissions retrieves the names of all permissions a user has through their roles.
+func (r *PermissionRepository) GetUserPermissions(userID int) ([]string, error) {
+	permissions := r.users.permissions(userID)
+	sort.Strings(permissions)
+	return permissions, nil
+}
+
+// UserHasRole checks if a user has the named role.
+func (r *PermissionRepository) UserHasRole(userID int, role string) (bool, error) {
+	return r.users.hasRole(userID, role), nil
+}
+
+// NotifyPermissionsChanged does nothing, as there are no other instances to notify.
+func (r *PermissionRepository) NotifyPermissionsChanged(userID int) {}
@@ -0,0 +1,228 @@
+package memory
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/repositories"
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// SessionRepository keeps sessions and their activity in memory.
+type SessionRepository struct {
+	mu             sync.Mutex
+	nextID         int
+	nextActivityID int
+	sessions       map[int]*entities.Session
+	activity       map[int][]entities.SessionActivity
+}
+
+// NewSessionRepository creates a new instance of SessionRepository.
+func NewSessionRepository() *SessionRepository {
+	return &SessionRepository{
+		sessions: make(map[int]*entities.Session),
+		activity: make(map[int][]entities.SessionActivity),
+	}
+}
+
+// DB returns nil, running transactions directly.
+func (r *SessionRepository) DB() *sql.DB {
+	return nil
+}
+
+// InsertSession stores a session and returns its ID.
+func (r *SessionRepository) InsertSession(ctx context.Context, exec repositories.DBExecutor, session entities.Session) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	session.ID = r.nextID
+	session.IsActive = true
+	r.sessions[session.ID] = &session
+	return session.ID, nil
+}
+
+// GetActiveSessions retrieves the sessions of a user that haven't been revoked.
+func (r *SessionRepository) GetActiveSessions(ctx context.Context, userID int) ([]entities.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var sessions []entities.Session
+	for id := 1; id <= r.nextID; id++ {
+		if session, ok := r.sessions[id]; ok && session.UserID == userID && session.IsActive {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions, nil
+}
+
+// RevokeSession marks a session as inactive and returns the ID of its user.
+// It returns sql.ErrNoRows if the session doesn't exist.
+func (r *SessionRepository) RevokeSession(ctx context.Context, exec repositories.DBExecutor, sessionID int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[sessionID]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	session.IsActive = false
+	return session.UserID, nil
+}
+
+// RevokeUserSessions marks all active sessions of a user as inactive and returns their IDs.
+func (r *SessionRepository) RevokeUserSessions(ctx context.Context, exec repositories.DBExecutor, userID int) ([]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var sessionIDs []int
+	for id := 1; id <= r.nextID; id++ {
+		session, ok := r.sessions[id]
+		if !ok || session.UserID != userID || !session.IsActive {
+			continue
+		}
+		session.IsActive = false
+		sessionIDs = append(sessionIDs, id)
+	}
+	return sessionIDs, nil
+}
+
+// ExpireImpersonationSessions marks the impersonation sessions created before issuedBefore as
+// inactive and returns them.
+func (r *SessionRepository) ExpireImpersonationSessions(ctx context.Context, exec repositories.DBExecutor, issuedBefore time.Time) ([]entities.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var sessions []entities.Session
+	for id := 1; id <= r.nextID; id++ {
+		session, ok := r.sessions[id]
+		if !ok || session.ImpersonatorID == nil || !session.IsActive || !session.CreatedAt.Before(issuedBefore) {
+			continue
+		}
+		session.IsActive = false
+		sessions = append(sessions, *session)
+	}
+	return sessions, nil
+}
+
+// CheckSession checks if a session exists and hasn't been revoked.
+func (r *SessionRepository) CheckSession(ctx context.Context, sessionId int) (bool, error) {
+	session, err := r.GetSessionByID(ctx, sessionId)
+	return session != nil && session.IsActive, err
+}
+
+// GetSessionByID retrieves the ID and state of a session, or nil if there is none. Like the
+// Postgres repository, whose cache only knows the state, it leaves the other fields empty.
+func (r *SessionRepository) GetSessionByID(ctx context.Context, sessionID int) (*entities.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[sessionID]
+	if !ok {
+		return nil, nil
+	}
+	return &entities.Session{ID: session.ID, IsActive: session.IsActive}, nil
+}
+
+// GetSessionOwners retrieves a session by its ID, or nil if there is none.
+func (r *SessionRepository) GetSessionOwners(ctx context.Context, sessionID int) (*entities.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[sessionID]
+	if !ok {
+		return nil, nil
+	}
+	found := *session
+	return &found, nil
+}
+
+// UpdateSessionUpdatedAt touches every session of a user.
+func (r *SessionRepository) UpdateSessionUpdatedAt(ctx context.Context, userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, session := range r.sessions {
+		if session.UserID == userID {
+			session.UpdatedAt = now
+		}
+	}
+	return nil
+}
+
+// CountActiveSessions returns the number of sessions that haven't been revoked.
+func (r *SessionRepository) CountActiveSessions(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	for _, session := range r.sessions {
+		if session.IsActive {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// InvalidateSession does nothing, as sessions aren't cached.
+func (r *SessionRepository) InvalidateSession(ctx context.Context, sessionID int) {}
+
+// GetSessionActivity retrieves the IP / user agent change history of the given sessions, oldest first.
+func (r *SessionRepository) GetSessionActivity(ctx context.Context, sessionIDs []int) (map[int][]entities.SessionActivity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	activity := make(map[int][]entities.SessionActivity)
+	for _, sessionID := range sessionIDs {
+		if entries := r.activity[sessionID]; len(entries) > 0 {
+			activity[sessionID] = append([]entities.SessionActivity(nil), entries...)
+		}
+	}
+	return activity, nil
+}
+
+// FlushSessionActivity records a batch of session activity. A history entry is only
+// recorded when the IP address or user agent differs from the previous one, and each
+// session keeps at most historyLimit entries.
+func (r *SessionRepository) FlushSessionActivity(ctx context.Context, updates []repositories.SessionActivityUpdate, historyLimit int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, update := range updates {
+		session, ok := r.sessions[update.SessionID]
+		if !ok {
+			continue
+		}
+
+		lastIP, lastUserAgent := session.LastIP, session.LastUserAgent
+		if lastIP == "" {
+			lastIP = session.IPAddress
+		}
+		for _, fingerprint := range update.Fingerprints {
+			if fingerprint.IPAddress == lastIP && fingerprint.UserAgent == lastUserAgent {
+				continue
+			}
+			r.nextActivityID++
+			r.activity[session.ID] = append(r.activity[session.ID], entities.SessionActivity{
+				ID:        r.nextActivityID,
+				SessionID: session.ID,
+				IPAddress: fingerprint.IPAddress,
+				UserAgent: fingerprint.UserAgent,
+				CreatedAt: fingerprint.SeenAt,
+			})
+			lastIP, lastUserAgent = fingerprint.IPAddress, fingerprint.UserAgent
+		}
+		if entries := r.activity[session.ID]; len(entries) > historyLimit {
+			r.activity[session.ID] = entries[len(entries)-historyLimit:]
+		}
+
+		lastSeenAt := update.LastSeenAt
+		session.LastSeenAt = &lastSeenAt
+		session.LastIP = lastIP
+		session.LastUserAgent = lastUserAgent
+		session.RequestCount += update.RequestCount
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+// Package memory implements the repositories services can be given in memory, so the
+// service can run without Postgres, such as in tests. Transactions aren't emulated: writes
+// made in a transaction that is rolled back are kept.
+package memory
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/repositories"
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RolePermissions are the roles and their permissions seeded by the migrations.
+var RolePermissions = map[string][]string{
+	"Admin":    {"CREATE_PRODUCT", "VIEW_PRODUCT", "DELETE_PRODUCT", "MANAGE_USERS", "IMPERSONATE_USERS"},
+	"Vendor":   {"CREATE_PRODUCT", "VIEW_PRODUCT"},
+	"Customer": {"VIEW_PRODUCT", "PLACE_ORDER"},
+	"Guest":    {"VIEW_PRODUCT"},
+}
+
+// UserRepository keeps users and their roles in memory.
+type UserRepository struct {
+	mu     sync.Mutex
+	nextID int
+	users  map[int]*entities.User
+	roles  map[int]map[string]bool
+}
+
+// NewUserRepository creates a new instance of UserRepository.
+func NewUserRepository() *UserRepository {
+	return &UserRepository{
+		users: make(map[int]*entities.User),
+		roles: make(map[int]map[string]bool),
+	}
+}
+
+// DB returns nil, running transactions directly.
+func (r *UserRepository) DB() *sql.DB {
+	return nil
+}
+
+// GetAllUsers retrieves all users, in the order they were inserted.
+func (r *UserRepository) GetAllUsers(ctx context.Context) ([]entities.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var users []entities.User
+	for id := 1; id <= r.nextID; id++ {
+		if user, ok := r.users[id]; ok {
+			users = append(users, withoutPassword(*user))
+		}
+	}
+	return users, nil
+}
+
+// LockUser retrieves a user by their ID, or nil if there is none. There is nothing to lock in memory.
+func (r *UserRepository) LockUser(ctx context.Context, exec repositories.DBExecutor, userID int) (*entities.User, error) {
+	return r.GetUserByID(ctx, userID)
+}
+
+// EditUser updates a user's details.
+func (r *UserRepository) EditUser(ctx context.Context, exec repositories.DBExecutor, user entities.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[user.ID]
+	if !ok {
+		return nil
+	}
+	stored.Username = user.Username
+	stored.Email = user.Email
+	stored.IsBlocked = user.IsBlocked
+	stored.LoginAttempts = user.LoginAttempts
+	stored.UpdatedAt = user.UpdatedAt
+	return nil
+}
+
+// DeleteUser performs a soft delete on a user by marking them inactive.
+func (r *UserRepository) DeleteUser(ctx context.Context, userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user, ok := r.users[userID]; ok {
+		user.IsActive = false
+	}
+	return nil
+}
+
+// InsertUser adds a new user and returns the new user's ID.
+func (r *UserRepository) InsertUser(ctx context.Context, exec repositories.DBExecutor, username, password, email string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if user.Username == username || user.Email == email {
+			return 0, fmt.Errorf("user %s already exists", username)
+		}
+	}
+
+	r.nextID++
+	now := time.Now()
+	r.users[r.nextID] = &entities.User{
+		ID:        r.nextID,
+		Username:  username,
+		Password:  password,
+		Email:     email,
+		IsActive:  true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	return r.nextID, nil
+}
+
+// AssignRole gives a user the named role, if they don't have it yet.
+func (r *UserRepository) AssignRole(ctx context.Context, exec repositories.DBExecutor, userID int, role string) error {
+	if _, ok := RolePermissions[role]; !ok {
+		return fmt.Errorf("role %s not found", role)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.roles[userID] == nil {
+		r.roles[userID] = make(map[string]bool)
+	}
+	r.roles[userID][role] = true
+	return nil
+}
+
+// UserExistsByUsername checks if a user exists by their username.
+func (r *UserRepository) UserExistsByUsername(ctx context.Context, username string) (bool, error) {
+	user, err := r.GetUserByUsername(ctx, username)
+	return user != nil, err
+}
+
+// UserExistsByEmail checks if a user exists by their email.
+func (r *UserRepository) UserExistsByEmail(ctx context.Context, email string) (bool, error) {
+	user, err := r.GetUserByEmail(ctx, email)
+	return user != nil, err
+}
+
+// GetUserByEmail retrieves a user by their email, or nil if there is none.
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*entities.User, error) {
+	return r.find(func(user *entities.User) bool { return user.Email == email }), nil
+}
+
+// GetUserByID retrieves a user by their ID, or nil if there is none.
+func (r *UserRepository) GetUserByID(ctx context.Context, userID int) (*entities.User, error) {
+	user := r.find(func(user *entities.User) bool { return user.ID == userID })
+	if user != nil {
+		*user = withoutPassword(*user)
+	}
+	return user, nil
+}
+
+// GetUserByUsername retrieves a user by their username, or nil if there is none.
+func (r *UserRepository) GetUserByUsername(ctx context.Context, username string) (*entities.User, error) {
+	return r.find(func(user *entities.User) bool { return user.Username == username }), nil
+}
+
+// RecordFailedLogin increments a user's failed login attempts, blocking the account once
+// maxAttempts is reached. It returns the attempt count and whether this call blocked the account.
+func (r *UserRepository) RecordFailedLogin(ctx context.Context, exec repositories.DBExecutor, userID, maxAttempts int) (int, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return 0, false, sql.ErrNoRows
+	}
+	wasBlocked := user.IsBlocked
+	user.LoginAttempts++
+	user.IsBlocked = user.IsBlocked || user.LoginAttempts >= maxAttempts
+	return user.LoginAttempts, user.IsBlocked && !wasBlocked, nil
+}
+
+// RecordSuccessfulLogin resets a user's failed login attempts and sets their last login time.
+func (r *UserRepository) RecordSuccessfulLogin(ctx context.Context, userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user, ok := r.users[userID]; ok {
+		user.LoginAttempts = 0
+		user.LastLogin = time.Now()
+	}
+	return nil
+}
+
+// hasPermission checks if a user has the permission through one of their roles.
+func (r *UserRepository) hasPermission(userID int, permission string) bool {
+	for _, userPermission := range r.permissions(userID) {
+		if userPermission == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// permissions returns the permissions a user has through their roles, without duplicates.
+func (r *UserRepository) permissions(userID int) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seen := make(map[string]bool)
+	permissions := []string{}
+	for role := range r.roles[userID] {
+		for _, permission := range RolePermissions[role] {
+			if !seen[permission] {
+				seen[permission] = true
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	return permissions
+}
+
+// hasRole checks if a user has the named role.
+func (r *UserRepository) hasRole(userID int, role string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.roles[userID][role]
+}
+
+// find returns a copy of the first user matching, or nil if there is none.
+func (r *UserRepository) find(match func(user *entities.User) bool) *entities.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if match(user) {
+			found := *user
+			return &found
+		}
+	}
+	return nil
+}
+
+// withoutPassword clears the password hash, which Postgres queries listing users don't select.
+func withoutPassword(user entities.User) entities.User {
+	user.Password = ""
+	return user
+}
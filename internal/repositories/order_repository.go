@@ -0,0 +1,259 @@
+package repositories
+
+import (
+	"backendGoAuth/internal/entities"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"log/slog"
+	"strings"
+)
+
+// OrderFilter narrows down an order listing. Zero values are ignored.
+type OrderFilter struct {
+	UserID   int
+	VendorID int // orders containing at least one of the vendor's products
+	Status   string
+	Limit    int
+	Offset   int
+}
+
+// orderColumns lists the columns read by scanOrder, in order.
+const orderColumns = "o.id, o.user_id, o.total_amount, o.status, COALESCE(o.payment_status, ''), o.payment_method, o.shipping_address_id, o.created_at, o.updated_at"
+
+// OrderRepository is the concrete struct for interacting with orders and order items.
+type OrderRepository struct {
+	db *sql.DB
+}
+
+// NewOrderRepository creates a new instance of OrderRepository.
+func NewOrderRepository(db *sql.DB) *OrderRepository {
+	return &OrderRepository{db}
+}
+
+// DB returns the underlying database connection, for running repository methods in a transaction.
+func (r *OrderRepository) DB() *sql.DB {
+	return r.db
+}
+
+// ListOrders retrieves the orders matching the filter, newest first, along with the total number of matches.
+// Items are not loaded.
+func (r *OrderRepository) ListOrders(filter OrderFilter) ([]entities.Order, int, error) {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.UserID != 0 {
+		addCondition("o.user_id = $%d", filter.UserID)
+	}
+	if filter.VendorID != 0 {
+		addCondition(`EXISTS (
+        SELECT 1 FROM order_items oi JOIN products p ON p.id = oi.product_id
+        WHERE oi.order_id = o.id AND p.user_id = $%d)`, filter.VendorID)
+	}
+	if filter.Status != "" {
+		addCondition("o.status = $%d", filter.Status)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM orders o"+where, args...).Scan(&total); err != nil {
+		slog.Error("Error counting orders", "error", err)
+		return nil, 0, err
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf("SELECT %s FROM orders o%s ORDER BY o.created_at DESC, o.id DESC LIMIT $%d OFFSET $%d", orderColumns, where, len(args)-1, len(args))
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		slog.Error("Error querying orders", "error", err)
+		return nil, 0, err
+	}
+	defer closeRows(rows)
+
+	orders := []entities.Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, total, rows.Err()
+}
+
+// GetOrderByID retrieves an order with its items. It returns nil if the order doesn't exist.
+func (r *OrderRepository) GetOrderByID(orderID int) (*entities.Order, error) {
+	row := r.db.QueryRow("SELECT "+orderColumns+" FROM orders o WHERE o.id = $1", orderID)
+	order, err := scanOrder(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		slog.Error("Error retrieving order", "order_id", orderID, "error", err)
+		return nil, err
+	}
+
+	items, err := r.GetOrderItems([]int{orderID}, 0)
+	if err != nil {
+		return nil, err
+	}
+	order.Items = items[orderID]
+	return &order, nil
+}
+
+// GetOrderItems retrieves the items of the given orders, keyed by order ID. If vendorID is set,
+// only that vendor's products are returned.
+func (r *OrderRepository) GetOrderItems(orderIDs []int, vendorID int) (map[int][]entities.OrderItem, error) {
+	items := make(map[int][]entities.OrderItem, len(orderIDs))
+	if len(orderIDs) == 0 {
+		return items, nil
+	}
+
+	rows, err := r.db.Query(`
+    SELECT oi.id, oi.order_id, oi.product_id, COALESCE(p.name, ''), COALESCE(p.user_id, 0),
+           oi.quantity, oi.price_at_order, oi.created_at, oi.updated_at
+    FROM order_items oi LEFT JOIN products p ON p.id = oi.product_id
+    WHERE oi.order_id = ANY($1) AND ($2 = 0 OR p.user_id = $2)
+    ORDER BY oi.order_id, oi.id`, pq.Array(orderIDs), vendorID)
+	if err != nil {
+		slog.Error("Error querying order items", "error", err)
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	for rows.Next() {
+		var item entities.OrderItem
+		if err := rows.Scan(
+			&item.ID,
+			&item.OrderID,
+			&item.ProductID,
+			&item.Name,
+			&item.VendorID,
+			&item.Quantity,
+			&item.PriceAtOrder,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items[item.OrderID] = append(items[item.OrderID], item)
+	}
+	return items, rows.Err()
+}
+
+// InsertOrder inserts an order with its items and records its initial status.
+func (r *OrderRepository) InsertOrder(tx *sql.Tx, order *entities.Order) error {
+	err := tx.QueryRow(`
+    INSERT INTO orders (user_id, total_amount, status, payment_status, payment_method, shipping_address_id)
+    VALUES ($1, $2, $3, $4, $5, $6)
+    RETURNING id, created_at, updated_at`,
+		order.UserID, order.TotalAmount, order.Status, order.PaymentStatus, order.PaymentMethod, order.ShippingAddressID,
+	).Scan(&order.ID, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		slog.Error("Error inserting order", "error", err)
+		return err
+	}
+
+	for i := range order.Items {
+		item := &order.Items[i]
+		item.OrderID = order.ID
+		err := tx.QueryRow(`
+        INSERT INTO order_items (order_id, product_id, quantity, price_at_order)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at, updated_at`,
+			order.ID, item.ProductID, item.Quantity, item.PriceAtOrder,
+		).Scan(&item.ID, &item.CreatedAt, &item.UpdatedAt)
+		if err != nil {
+			slog.Error("Error inserting order item", "error", err)
+			return err
+		}
+	}
+
+	return r.insertStatusHistory(tx, order.ID, nil, order.Status, order.UserID)
+}
+
+// UpdateOrderStatus moves an order from one status to another, only if it is still in the
+// expected status. An empty paymentStatus leaves the payment status unchanged. It returns
+// false if the order was not in the expected status.
+func (r *OrderRepository) UpdateOrderStatus(tx *sql.Tx, orderID int, from, to, paymentStatus string, changedBy int) (bool, error) {
+	result, err := tx.Exec(`
+    UPDATE orders
+    SET status = $3, payment_status = COALESCE(NULLIF($4, ''), payment_status), updated_at = CURRENT_TIMESTAMP
+    WHERE id = $1 AND status = $2`,
+		orderID, from, to, paymentStatus)
+	if err != nil {
+		slog.Error("Error updating order status", "order_id", orderID, "error", err)
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	return true, r.insertStatusHistory(tx, orderID, &from, to, changedBy)
+}
+
+// SetPaymentStatus updates an order's payment status and method without changing its status.
+func (r *OrderRepository) SetPaymentStatus(exec DBExecutor, orderID int, paymentStatus, paymentMethod string) error {
+	if exec == nil {
+		exec = r.db
+	}
+
+	_, err := exec.Exec(`
+    UPDATE orders
+    SET payment_status = $2, payment_method = COALESCE(NULLIF($3, ''), payment_method), updated_at = CURRENT_TIMESTAMP
+    WHERE id = $1`,
+		orderID, paymentStatus, paymentMethod)
+	if err != nil {
+		slog.Error("Error updating order payment status", "order_id", orderID, "error", err)
+	}
+	return err
+}
+
+func (r *OrderRepository) insertStatusHistory(tx *sql.Tx, orderID int, from *string, to string, changedBy int) error {
+	var changedByID *int
+	if changedBy != 0 {
+		changedByID = &changedBy
+	}
+	_, err := tx.Exec(
+		"INSERT INTO order_status_history (order_id, from_status, to_status, changed_by) VALUES ($1, $2, $3, $4)",
+		orderID, from, to, changedByID,
+	)
+	return err
+}
+
+// scanOrder scans a row selected with orderColumns.
+func scanOrder(row interface{ Scan(...interface{}) error }) (entities.Order, error) {
+	var order entities.Order
+	var paymentMethod sql.NullString
+	var shippingAddressID sql.NullInt64
+	err := row.Scan(
+		&order.ID,
+		&order.UserID,
+		&order.TotalAmount,
+		&order.Status,
+		&order.PaymentStatus,
+		&paymentMethod,
+		&shippingAddressID,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+	)
+	if paymentMethod.Valid {
+		order.PaymentMethod = &paymentMethod.String
+	}
+	if shippingAddressID.Valid {
+		id := int(shippingAddressID.Int64)
+		order.ShippingAddressID = &id
+	}
+	order.Items = []entities.OrderItem{}
+	return order, err
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/lib/pq"
+	"log/slog"
+	"strconv"
+)
+
+// OutboxPIIKeys are the event payload keys holding personal data, stripped from the events
+// about a user when they are erased.
+var OutboxPIIKeys = []string{"identifier", "ip_address", "user_agent", "browser", "device"}
+
+// OutboxActorKeys are the event payload keys naming the user who made the change. Events a
+// user made to someone else only lose their connection details when they are erased.
+var OutboxActorKeys = []string{"revoked_by", "changed_by", "erased_by", "impersonator_id"}
+
+// OutboxRepository writes events to the transactional outbox.
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+// NewOutboxRepository creates a new instance of OutboxRepository.
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{db}
+}
+
+// Enqueue writes an event to the outbox. Pass the transaction making the change
+// the event describes so both are committed or rolled back together.
+func (r *OutboxRepository) Enqueue(exec DBExecutor, eventType string, payload interface{}) error {
+	if exec == nil {
+		exec = r.db
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = exec.Exec("INSERT INTO event_outbox (event_type, payload) VALUES ($1, $2)", eventType, string(data))
+	if err != nil {
+		slog.Error("Error enqueuing event", "event_type", eventType, "error", err)
+	}
+	return err
+}
+
+// RedactUser strips the personal data of a user from the events about them, and their
+// connection details from the events they made. Webhook deliveries send the outbox payload,
+// so the deliveries still pending go out redacted too.
+func (r *OutboxRepository) RedactUser(exec DBExecutor, userID int) error {
+	if exec == nil {
+		exec = r.db
+	}
+
+	id := strconv.Itoa(userID)
+	_, err := exec.Exec("UPDATE event_outbox SET payload = payload - $2::text[] WHERE payload->>'user_id' = $1",
+		id, pq.Array(OutboxPIIKeys))
+	if err != nil {
+		slog.Error("Error redacting user events", "user_id", userID, "error", err)
+		return err
+	}
+
+	_, err = exec.Exec(`UPDATE event_outbox SET payload = payload - '{ip_address,user_agent}'::text[]
+    WHERE EXISTS (SELECT 1 FROM unnest($2::text[]) AS k(key) WHERE payload->>k.key = $1)`,
+		id, pq.Array(OutboxActorKeys))
+	if err != nil {
+		slog.Error("Error redacting events made by user", "user_id", userID, "error", err)
+	}
+	return err
+}
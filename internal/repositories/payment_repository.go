@@ -0,0 +1,166 @@
+package repositories
+
+import (
+	"backendGoAuth/internal/entities"
+	"database/sql"
+	"errors"
+	"log/slog"
+)
+
+// paymentColumns lists the columns read by scanPayment, in order.
+const paymentColumns = "id, order_id, provider, COALESCE(provider_ref, ''), idempotency_key, kind, amount, COALESCE(payment_method, ''), status, COALESCE(error, ''), attempt, created_at, updated_at"
+
+// PaymentRepository is the concrete struct for interacting with payments.
+type PaymentRepository struct {
+	db *sql.DB
+}
+
+// NewPaymentRepository creates a new instance of PaymentRepository.
+func NewPaymentRepository(db *sql.DB) *PaymentRepository {
+	return &PaymentRepository{db}
+}
+
+// DB returns the underlying database connection, for running repository methods in a transaction.
+func (r *PaymentRepository) DB() *sql.DB {
+	return r.db
+}
+
+// InsertPayment records a payment unless one with the same idempotency key exists.
+// It returns false, leaving the payment untouched, if the key was already used.
+func (r *PaymentRepository) InsertPayment(payment *entities.Payment) (bool, error) {
+	err := r.db.QueryRow(`
+    INSERT INTO payments (order_id, provider, idempotency_key, kind, amount, payment_method, status)
+    VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+    ON CONFLICT (idempotency_key) DO NOTHING
+    RETURNING id, attempt, created_at, updated_at`,
+		payment.OrderID, payment.Provider, payment.IdempotencyKey, payment.Kind, payment.Amount, payment.PaymentMethod, payment.Status,
+	).Scan(&payment.ID, &payment.Attempt, &payment.CreatedAt, &payment.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		slog.Error("Error inserting payment", "error", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// GetPaymentByIdempotencyKey retrieves a payment by its idempotency key. It returns nil if there is none.
+func (r *PaymentRepository) GetPaymentByIdempotencyKey(key string) (*entities.Payment, error) {
+	return r.getPayment("SELECT "+paymentColumns+" FROM payments WHERE idempotency_key = $1", key)
+}
+
+// GetChargeByReference retrieves a charge by the provider's reference. It returns nil if there is none.
+func (r *PaymentRepository) GetChargeByReference(provider, reference string) (*entities.Payment, error) {
+	return r.getPayment("SELECT "+paymentColumns+" FROM payments WHERE provider = $1 AND provider_ref = $2 AND kind = 'CHARGE'", provider, reference)
+}
+
+// GetSucceededCharge retrieves the charge that paid for an order. It returns nil if there is none.
+func (r *PaymentRepository) GetSucceededCharge(orderID int) (*entities.Payment, error) {
+	return r.getPayment("SELECT "+paymentColumns+" FROM payments WHERE order_id = $1 AND kind = 'CHARGE' AND status = 'SUCCEEDED' ORDER BY id DESC LIMIT 1", orderID)
+}
+
+// GetPaymentsByOrder retrieves all charges and refunds of an order, oldest first.
+func (r *PaymentRepository) GetPaymentsByOrder(orderID int) ([]entities.Payment, error) {
+	rows, err := r.db.Query("SELECT "+paymentColumns+" FROM payments WHERE order_id = $1 ORDER BY id", orderID)
+	if err != nil {
+		slog.Error("Error querying payments", "error", err)
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	payments := []entities.Payment{}
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+	return payments, rows.Err()
+}
+
+// ClaimPayment moves a payment from one status to another, only if it is still in the expected
+// status, so concurrent webhooks and requests process it once. It returns false if it wasn't.
+func (r *PaymentRepository) ClaimPayment(paymentID int, from, to string) (bool, error) {
+	result, err := r.db.Exec("UPDATE payments SET status = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND status = $2", paymentID, from, to)
+	if err != nil {
+		slog.Error("Error claiming payment", "payment_id", paymentID, "error", err)
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// RetryPayment claims a declined or failed charge for another attempt, only if it is still in
+// the expected status, storing the payment method of the new attempt and counting it. It
+// returns false, leaving the payment untouched, if it wasn't.
+func (r *PaymentRepository) RetryPayment(payment *entities.Payment, from, paymentMethod string) (bool, error) {
+	err := r.db.QueryRow(`
+    UPDATE payments
+    SET status = 'PROCESSING', payment_method = NULLIF($3, ''), error = NULL, attempt = attempt + 1, updated_at = CURRENT_TIMESTAMP
+    WHERE id = $1 AND status = $2
+    RETURNING attempt, updated_at`,
+		payment.ID, from, paymentMethod,
+	).Scan(&payment.Attempt, &payment.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		slog.Error("Error retrying payment", "payment_id", payment.ID, "error", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// UpdatePayment stores the outcome of a provider call.
+func (r *PaymentRepository) UpdatePayment(exec DBExecutor, payment *entities.Payment) error {
+	if exec == nil {
+		exec = r.db
+	}
+
+	err := exec.QueryRow(`
+    UPDATE payments
+    SET status = $2, provider_ref = NULLIF($3, ''), error = NULLIF($4, ''), updated_at = CURRENT_TIMESTAMP
+    WHERE id = $1
+    RETURNING updated_at`,
+		payment.ID, payment.Status, payment.ProviderRef, payment.Error,
+	).Scan(&payment.UpdatedAt)
+	if err != nil {
+		slog.Error("Error updating payment", "payment_id", payment.ID, "error", err)
+	}
+	return err
+}
+
+func (r *PaymentRepository) getPayment(query string, args ...interface{}) (*entities.Payment, error) {
+	payment, err := scanPayment(r.db.QueryRow(query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		slog.Error("Error retrieving payment", "error", err)
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// scanPayment scans a row selected with paymentColumns.
+func scanPayment(row interface{ Scan(...interface{}) error }) (entities.Payment, error) {
+	var payment entities.Payment
+	err := row.Scan(
+		&payment.ID,
+		&payment.OrderID,
+		&payment.Provider,
+		&payment.ProviderRef,
+		&payment.IdempotencyKey,
+		&payment.Kind,
+		&payment.Amount,
+		&payment.PaymentMethod,
+		&payment.Status,
+		&payment.Error,
+		&payment.Attempt,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+	return payment, err
+}
@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strconv"
+)
+
+// PermissionsChangedChannel is the Postgres NOTIFY channel on which the ID of a user
+// whose roles changed is broadcast, so every instance refreshes their permissions.
+const PermissionsChangedChannel = "permissions_changed"
+
+// PermissionRepository is the concrete struct for interacting with roles and permissions.
+type PermissionRepository struct {
+	db *sql.DB
+}
+
+// NewPermissionRepository creates a new instance of PermissionRepository.
+func NewPermissionRepository(db *sql.DB) *PermissionRepository {
+	return &PermissionRepository{db}
+}
+
+// UserHasPermission checks if a user has the specified permission through one of their roles.
+func (r *PermissionRepository) UserHasPermission(userID int, permission string) (bool, error) {
+	query := `
+    SELECT 1
+    FROM user_roles ur
+    JOIN role_permissions rp ON ur.role_id = rp.role_id
+    JOIN permissions p ON rp.permission_id = p.id
+    WHERE ur.user_id = $1 AND p.name = $2
+    LIMIT 1`
+	var exists int
+	err := r.db.QueryRow(query, userID, permission).Scan(&exists)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		slog.Error("Error checking permission for user", "permission", permission, "user_id", userID, "error", err)
+		return false, err
+	}
+	return exists == 1, nil
+}
+
+// GetUserPermissions retrieves the names of all permissions a user has through their roles.
+func (r *PermissionRepository) GetUserPermissions(userID int) ([]string, error) {
+	rows, err := r.db.Query(`
+    SELECT DISTINCT p.name
+    FROM user_roles ur
+    JOIN role_permissions rp ON ur.role_id = rp.role_id
+    JOIN permissions p ON rp.permission_id = p.id
+    WHERE ur.user_id = $1
+    ORDER BY p.name`, userID)
+	if err != nil {
+		slog.Error("Error querying permissions of user", "user_id", userID, "error", err)
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	permissions := []string{}
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+	return permissions, rows.Err()
+}
+
+// UserHasRole checks if a user has the named role.
+func (r *PermissionRepository) UserHasRole(userID int, role string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(`
+    SELECT EXISTS (SELECT 1 FROM user_roles ur JOIN roles ro ON ro.id = ur.role_id WHERE ur.user_id = $1 AND ro.name = $2)`,
+		userID, role).Scan(&exists)
+	if err != nil {
+		slog.Error("Error checking role for user", "role", role, "user_id", userID, "error", err)
+	}
+	return exists, err
+}
+
+// NotifyPermissionsChanged tells every instance that a user's roles changed.
+func (r *PermissionRepository) NotifyPermissionsChanged(userID int) {
+	if _, err := r.db.Exec("SELECT pg_notify($1, $2)", PermissionsChangedChannel, strconv.Itoa(userID)); err != nil {
+		slog.Error("Error notifying permission change for user", "user_id", userID, "error", err)
+	}
+}
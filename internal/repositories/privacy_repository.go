@@ -0,0 +1,320 @@
+package repositories
+
+import (
+	"backendGoAuth/internal/entities"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"log/slog"
+	"time"
+)
+
+// RedactedValue replaces erased personal data in columns that can't be NULL.
+const RedactedValue = "[redacted]"
+
+// ErrAlreadyErased is returned when erasing a user whose account was already anonymized.
+var ErrAlreadyErased = errors.New("user already erased")
+
+// auditPIIKeys are the audit payload keys holding personal data, stripped when a user is erased.
+var auditPIIKeys = []string{
+	"username", "email", "password", "identifier", "ip_address", "user_agent", "last_ip", "last_user_agent",
+	"location", "device_connected", "browser_used", "street", "city", "zip_code", "contact_email",
+}
+
+// erasureRequestColumns lists the columns read by scanErasureRequest, in order.
+const erasureRequestColumns = "user_id, requested_by, requested_at, scheduled_for, erased_at"
+
+// PrivacyRepository is the concrete struct for exporting and erasing a user's personal data.
+type PrivacyRepository struct {
+	db *sql.DB
+}
+
+// NewPrivacyRepository creates a new instance of PrivacyRepository.
+func NewPrivacyRepository(db *sql.DB) *PrivacyRepository {
+	return &PrivacyRepository{db}
+}
+
+// DB returns the underlying database connection, for running repository methods in a transaction.
+func (r *PrivacyRepository) DB() *sql.DB {
+	return r.db
+}
+
+// GetProfile retrieves a user's account row, without the password. It returns nil if the user doesn't exist.
+func (r *PrivacyRepository) GetProfile(userID int) (*entities.User, error) {
+	var user entities.User
+	var lastLogin sql.NullTime
+	err := r.db.QueryRow(`
+    SELECT id, username, email, is_blocked, is_active, login_attempts, last_login, created_at, updated_at
+    FROM users WHERE id = $1`, userID).Scan(
+		&user.ID, &user.Username, &user.Email, &user.IsBlocked, &user.IsActive, &user.LoginAttempts,
+		&lastLogin, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		slog.Error("Error retrieving profile of user", "user_id", userID, "error", err)
+		return nil, err
+	}
+	user.LastLogin = lastLogin.Time
+	return &user, nil
+}
+
+// GetSessions retrieves all of a user's sessions, active or not, oldest first.
+func (r *PrivacyRepository) GetSessions(userID int) ([]entities.Session, error) {
+	rows, err := r.db.Query(`
+    SELECT id, user_id, ip_address, location, COALESCE(device_connected, ''), COALESCE(browser_used, ''), is_active,
+           created_at, updated_at, last_seen_at, COALESCE(last_ip, ''), COALESCE(last_user_agent, ''),
+           COALESCE(request_count, 0), impersonator_id
+    FROM user_sessions WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		slog.Error("Error querying sessions of user", "user_id", userID, "error", err)
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	sessions := []entities.Session{}
+	for rows.Next() {
+		var session entities.Session
+		if err := rows.Scan(
+			&session.ID, &session.UserID, &session.IPAddress, &session.Location, &session.DeviceConnected,
+			&session.BrowserUsed, &session.IsActive, &session.CreatedAt, &session.UpdatedAt, &session.LastSeenAt,
+			&session.LastIP, &session.LastUserAgent, &session.RequestCount, &session.ImpersonatorID,
+		); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// GetAddresses retrieves all of a user's addresses, including archived ones.
+func (r *PrivacyRepository) GetAddresses(userID int) ([]entities.Address, error) {
+	rows, err := r.db.Query("SELECT "+addressColumns+" FROM addresses WHERE user_id = $1 ORDER BY id", userID)
+	if err != nil {
+		slog.Error("Error querying addresses of user", "user_id", userID, "error", err)
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	addresses := []entities.Address{}
+	for rows.Next() {
+		address, err := scanAddress(rows)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, rows.Err()
+}
+
+// GetAuditLogs retrieves the audit entries about a user or performed by them, oldest first.
+func (r *PrivacyRepository) GetAuditLogs(userID int) ([]entities.AuditLog, error) {
+	rows, err := r.db.Query(fmt.Sprintf(`
+    SELECT %s FROM user_audit_logs
+    WHERE user_id = $1 OR actor_id = $1 OR target_id = $1 OR (table_name = 'users' AND row_id = $1)
+    ORDER BY id`, auditLogColumns), userID)
+	if err != nil {
+		slog.Error("Error querying audit logs of user", "user_id", userID, "error", err)
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	logs := []entities.AuditLog{}
+	for rows.Next() {
+		entry, err := scanAuditLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, entry)
+	}
+	return logs, rows.Err()
+}
+
+// GetErasureRequest retrieves a user's erasure request. It returns nil if there is none.
+func (r *PrivacyRepository) GetErasureRequest(userID int) (*entities.ErasureRequest, error) {
+	request, err := scanErasureRequest(r.db.QueryRow("SELECT "+erasureRequestColumns+" FROM user_erasure_requests WHERE user_id = $1", userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		slog.Error("Error retrieving erasure request of user", "user_id", userID, "error", err)
+		return nil, err
+	}
+	return &request, nil
+}
+
+// ScheduleErasure requests a user's erasure at scheduledFor. An existing request is kept
+// as is, so asking twice doesn't push the date back.
+func (r *PrivacyRepository) ScheduleErasure(userID, requestedBy int, scheduledFor time.Time) (*entities.ErasureRequest, error) {
+	_, err := r.db.Exec(`
+    INSERT INTO user_erasure_requests (user_id, requested_by, scheduled_for) VALUES ($1, NULLIF($2, 0), $3)
+    ON CONFLICT (user_id) DO NOTHING`, userID, requestedBy, scheduledFor)
+	if err != nil {
+		slog.Error("Error scheduling erasure of user", "user_id", userID, "error", err)
+		return nil, err
+	}
+	return r.GetErasureRequest(userID)
+}
+
+// CancelErasure withdraws a pending erasure request. It returns false if there was none.
+func (r *PrivacyRepository) CancelErasure(userID int) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM user_erasure_requests WHERE user_id = $1 AND erased_at IS NULL", userID)
+	if err != nil {
+		slog.Error("Error canceling erasure of user", "user_id", userID, "error", err)
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetDueErasures returns up to limit users whose grace period is over and who aren't erased yet.
+func (r *PrivacyRepository) GetDueErasures(limit int) ([]int, error) {
+	rows, err := r.db.Query(`
+    SELECT user_id FROM user_erasure_requests
+    WHERE erased_at IS NULL AND scheduled_for <= NOW()
+    ORDER BY scheduled_for LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// LockCheckout waits for a checkout in progress for the user and blocks new ones until
+// the end of the transaction, using the same lock as CartRepository.LockCart.
+func (r *PrivacyRepository) LockCheckout(tx *sql.Tx, userID int) error {
+	_, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('carts'), $1)", userID)
+	return err
+}
+
+// HasOrdersInStatus checks if the user has an order in one of the given statuses.
+func (r *PrivacyRepository) HasOrdersInStatus(exec DBExecutor, userID int, statuses []string) (bool, error) {
+	if exec == nil {
+		exec = r.db
+	}
+	var exists bool
+	err := exec.QueryRow("SELECT EXISTS (SELECT 1 FROM orders WHERE user_id = $1 AND status = ANY($2))", userID, pq.Array(statuses)).Scan(&exists)
+	return exists, err
+}
+
+// EraseUser anonymizes a user's personal data and returns the IDs of the sessions it ended
+// along with the audit entries it redacted. The users row, orders and the addresses orders
+// were shipped to are kept, with their personal fields overwritten, so order accounting still
+// adds up. Audit entries about the user lose their personal fields and are marked redacted;
+// the caller must record the redaction in the audit chain in the same transaction. It returns
+// ErrAlreadyErased if the user was already erased.
+func (r *PrivacyRepository) EraseUser(tx *sql.Tx, userID, requestedBy int) ([]int, []entities.AuditLog, error) {
+	// Marking the request erased first also locks it against a concurrent erasure
+	var erased int
+	err := tx.QueryRow(`
+    INSERT INTO user_erasure_requests (user_id, requested_by, scheduled_for, erased_at) VALUES ($1, NULLIF($2, 0), NOW(), NOW())
+    ON CONFLICT (user_id) DO UPDATE SET erased_at = NOW() WHERE user_erasure_requests.erased_at IS NULL
+    RETURNING user_id`, userID, requestedBy).Scan(&erased)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrAlreadyErased
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := tx.Query("SELECT id FROM user_sessions WHERE user_id = $1 AND is_active = true", userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sessionIDs []int
+	for rows.Next() {
+		var sessionID int
+		if err := rows.Scan(&sessionID); err != nil {
+			closeRows(rows)
+			return nil, nil, err
+		}
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+	closeRows(rows)
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`UPDATE users SET username = 'erased-user-' || id, email = 'erased-user-' || id || '@erased.invalid', password = '',
+          is_active = false, is_blocked = true, login_attempts = 0, last_login = NULL WHERE id = $1`, []interface{}{userID}},
+		{`UPDATE user_sessions SET ip_address = $2, location = $2, device_connected = '', browser_used = '',
+          last_ip = NULL, last_user_agent = NULL, is_active = false WHERE user_id = $1`, []interface{}{userID, RedactedValue}},
+		{"DELETE FROM user_session_activity WHERE session_id IN (SELECT id FROM user_sessions WHERE user_id = $1)", []interface{}{userID}},
+		{"DELETE FROM carts WHERE user_id = $1", []interface{}{userID}},
+		{"DELETE FROM user_roles WHERE user_id = $1", []interface{}{userID}},
+		{"DELETE FROM addresses a WHERE user_id = $1 AND NOT EXISTS (SELECT 1 FROM orders o WHERE o.shipping_address_id = a.id)", []interface{}{userID}},
+		// Country and state are kept for tax reporting
+		{`UPDATE addresses SET street = $2, city = $2, zip_code = $2, is_default_shipping = false, is_default_billing = false,
+          archived_at = COALESCE(archived_at, NOW()) WHERE user_id = $1`, []interface{}{userID, RedactedValue}},
+		{"UPDATE vendor_applications SET contact_email = $2, description = NULL WHERE user_id = $1", []interface{}{userID, RedactedValue}},
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement.query, statement.args...); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	redactions := []struct {
+		query string
+		args  []interface{}
+	}{
+		// Entries about the user, including the one the users trigger just wrote with the old values
+		{`UPDATE user_audit_logs SET old_data = old_data - $2::text[], new_data = new_data - $2::text[],
+          ip_address = NULL, user_agent = NULL, redacted_at = NOW()
+          WHERE user_id = $1 OR target_id = $1 OR (table_name = 'users' AND row_id = $1)`, []interface{}{userID, pq.Array(auditPIIKeys)}},
+		// Entries where the user acted on someone else only lose the user's own connection details
+		{`UPDATE user_audit_logs SET ip_address = NULL, user_agent = NULL, redacted_at = NOW()
+          WHERE actor_id = $1 AND redacted_at IS NULL AND (ip_address IS NOT NULL OR user_agent IS NOT NULL)`, []interface{}{userID}},
+	}
+	var redacted []entities.AuditLog
+	for _, redaction := range redactions {
+		rows, err := tx.Query(redaction.query+" RETURNING "+auditLogColumns, redaction.args...)
+		if err != nil {
+			return nil, nil, err
+		}
+		for rows.Next() {
+			entry, err := scanAuditLog(rows)
+			if err != nil {
+				closeRows(rows)
+				return nil, nil, err
+			}
+			redacted = append(redacted, entry)
+		}
+		closeRows(rows)
+		if err := rows.Err(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return sessionIDs, redacted, nil
+}
+
+// scanErasureRequest scans a row selected with erasureRequestColumns.
+func scanErasureRequest(row interface{ Scan(...interface{}) error }) (entities.ErasureRequest, error) {
+	var request entities.ErasureRequest
+	var requestedBy sql.NullInt64
+	var erasedAt sql.NullTime
+	err := row.Scan(&request.UserID, &requestedBy, &request.RequestedAt, &request.ScheduledFor, &erasedAt)
+	if requestedBy.Valid {
+		id := int(requestedBy.Int64)
+		request.RequestedBy = &id
+	}
+	if erasedAt.Valid {
+		request.ErasedAt = &erasedAt.Time
+	}
+	return request, err
+}
@@ -0,0 +1,364 @@
+package repositories
+
+import (
+	"backendGoAuth/internal/entities"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"log/slog"
+	"strings"
+)
+
+// ProductFilter narrows down a product listing. Zero values are ignored.
+type ProductFilter struct {
+	CategoryID int
+	UserID     int
+	Query      string // case-insensitive match on name and description
+	Limit      int
+	Offset     int
+}
+
+// ProductSearchFilter narrows down a full-text product search. Zero values are ignored.
+type ProductSearchFilter struct {
+	TSQuery    string // to_tsquery('english') syntax, matched against name, description and category
+	CategoryID int
+	MinPrice   float64
+	MaxPrice   float64
+	Limit      int
+	Offset     int
+}
+
+// ProductSearchHit is a product matching a search, with its relevance.
+type ProductSearchHit struct {
+	Product entities.Product
+	Rank    float64
+}
+
+// CategoryFacet is the number of search matches in a category.
+type CategoryFacet struct {
+	CategoryID int
+	Name       string
+	Count      int
+}
+
+// PriceFacet is the number of search matches in a price bucket. Bucket 0 is below the first
+// bound, bucket len(bounds) is at or above the last one.
+type PriceFacet struct {
+	Bucket int
+	Count  int
+}
+
+// productColumns lists the columns read by scanProduct, in order.
+const productColumns = "p.id, p.name, COALESCE(p.description, ''), p.price, p.created_at, p.updated_at, COALESCE(p.category_id, 0), COALESCE(p.user_id, 0), p.stock_quantity, p.reserved_quantity, p.low_stock_threshold"
+
+// ProductRepository is the concrete struct for interacting with products and categories.
+type ProductRepository struct {
+	db *sql.DB
+}
+
+// NewProductRepository creates a new instance of ProductRepository.
+func NewProductRepository(db *sql.DB) *ProductRepository {
+	return &ProductRepository{db}
+}
+
+// ListProducts retrieves the products matching the filter, ordered by ID, along with the total number of matches.
+func (r *ProductRepository) ListProducts(filter ProductFilter) ([]entities.Product, int, error) {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.CategoryID != 0 {
+		addCondition("p.category_id = $%d", filter.CategoryID)
+	}
+	if filter.UserID != 0 {
+		addCondition("p.user_id = $%d", filter.UserID)
+	}
+	if filter.Query != "" {
+		addCondition("(p.name ILIKE $%[1]d OR p.description ILIKE $%[1]d)", "%"+escapeLike(filter.Query)+"%")
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM products p"+where, args...).Scan(&total); err != nil {
+		slog.Error("Error counting products", "error", err)
+		return nil, 0, err
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf("SELECT %s FROM products p%s ORDER BY p.id LIMIT $%d OFFSET $%d", productColumns, where, len(args)-1, len(args))
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		slog.Error("Error querying products", "error", err)
+		return nil, 0, err
+	}
+	defer closeRows(rows)
+
+	products := []entities.Product{}
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			slog.Error("Error scanning product", "error", err)
+			return nil, 0, err
+		}
+		products = append(products, product)
+	}
+	return products, total, rows.Err()
+}
+
+// GetProductByID retrieves a product by its ID, or nil if it doesn't exist.
+func (r *ProductRepository) GetProductByID(productID int) (*entities.Product, error) {
+	rows, err := r.db.Query(fmt.Sprintf("SELECT %s FROM products p WHERE p.id = $1", productColumns), productID)
+	if err != nil {
+		slog.Error("Error retrieving product by ID", "error", err)
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	product, err := scanProduct(rows)
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// InsertProduct adds a new product and returns its ID.
+func (r *ProductRepository) InsertProduct(product entities.Product) (int, error) {
+	var productID int
+	err := r.db.QueryRow(
+		"INSERT INTO products (name, description, price, category_id, user_id) VALUES ($1, $2, $3, NULLIF($4, 0), $5) RETURNING id",
+		product.Name, product.Description, product.Price, product.CategoryID, product.UserID,
+	).Scan(&productID)
+	if err != nil {
+		slog.Error("Error inserting product", "error", err)
+		return 0, err
+	}
+	return productID, nil
+}
+
+// UpdateProduct updates a product's details.
+func (r *ProductRepository) UpdateProduct(product entities.Product) error {
+	_, err := r.db.Exec(
+		"UPDATE products SET name = $1, description = $2, price = $3, category_id = NULLIF($4, 0), updated_at = CURRENT_TIMESTAMP WHERE id = $5",
+		product.Name, product.Description, product.Price, product.CategoryID, product.ID,
+	)
+	if err != nil {
+		slog.Error("Error updating product", "error", err)
+	}
+	return err
+}
+
+// DeleteProduct deletes a product.
+func (r *ProductRepository) DeleteProduct(productID int) error {
+	_, err := r.db.Exec("DELETE FROM products WHERE id = $1", productID)
+	if err != nil {
+		slog.Error("Error deleting product", "error", err)
+	}
+	return err
+}
+
+// GetCategories retrieves all categories.
+func (r *ProductRepository) GetCategories() ([]entities.Category, error) {
+	rows, err := r.db.Query("SELECT id, name, COALESCE(description, '') FROM categories ORDER BY name")
+	if err != nil {
+		slog.Error("Error querying categories", "error", err)
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	categories := []entities.Category{}
+	for rows.Next() {
+		var category entities.Category
+		if err := rows.Scan(&category.ID, &category.Name, &category.Description); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+// CategoryExists checks if a category exists by its ID.
+func (r *ProductRepository) CategoryExists(categoryID int) (bool, error) {
+	var exists int
+	err := r.db.QueryRow("SELECT 1 FROM categories WHERE id = $1", categoryID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// SearchProducts retrieves the products matching a full-text search, most relevant first, along
+// with the total number of matches. Products whose category name matches get a small boost.
+func (r *ProductRepository) SearchProducts(filter ProductSearchFilter) ([]ProductSearchHit, int, error) {
+	where, args := productSearchConditions(filter, true, true)
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM products p LEFT JOIN categories c ON c.id = p.category_id"+where, args...).Scan(&total); err != nil {
+		slog.Error("Error counting product search results", "error", err)
+		return nil, 0, err
+	}
+
+	rank := "0"
+	if filter.TSQuery != "" {
+		// The text query is always the first argument
+		rank = `ts_rank(p.search_vector, to_tsquery('english', $1)) +
+        CASE WHEN to_tsvector('english', COALESCE(c.name, '')) @@ to_tsquery('english', $1) THEN 0.1 ELSE 0 END`
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`
+    SELECT %s, %s AS rank
+    FROM products p LEFT JOIN categories c ON c.id = p.category_id%s
+    ORDER BY rank DESC, p.id
+    LIMIT $%d OFFSET $%d`, productColumns, rank, where, len(args)-1, len(args))
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		slog.Error("Error searching products", "error", err)
+		return nil, 0, err
+	}
+	defer closeRows(rows)
+
+	hits := []ProductSearchHit{}
+	for rows.Next() {
+		var hit ProductSearchHit
+		product := &hit.Product
+		err := rows.Scan(&product.ID, &product.Name, &product.Description, &product.Price, &product.CreatedAt, &product.UpdatedAt, &product.CategoryID, &product.UserID,
+			&product.StockQuantity, &product.ReservedQuantity, &product.LowStockThreshold, &hit.Rank)
+		if err != nil {
+			return nil, 0, err
+		}
+		product.AvailableQuantity = product.StockQuantity - product.ReservedQuantity
+		hits = append(hits, hit)
+	}
+	return hits, total, rows.Err()
+}
+
+// GetCategoryFacets counts the search matches per category, ignoring the category filter so
+// every category the user could switch to is listed.
+func (r *ProductRepository) GetCategoryFacets(filter ProductSearchFilter) ([]CategoryFacet, error) {
+	where, args := productSearchConditions(filter, false, true)
+	rows, err := r.db.Query(`
+    SELECT c.id, c.name, COUNT(*)
+    FROM products p JOIN categories c ON c.id = p.category_id`+where+`
+    GROUP BY c.id, c.name
+    ORDER BY COUNT(*) DESC, c.name`, args...)
+	if err != nil {
+		slog.Error("Error querying category facets", "error", err)
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	facets := []CategoryFacet{}
+	for rows.Next() {
+		var facet CategoryFacet
+		if err := rows.Scan(&facet.CategoryID, &facet.Name, &facet.Count); err != nil {
+			return nil, err
+		}
+		facets = append(facets, facet)
+	}
+	return facets, rows.Err()
+}
+
+// GetPriceFacets counts the search matches per price bucket delimited by bounds (ascending),
+// ignoring the price filter.
+func (r *ProductRepository) GetPriceFacets(filter ProductSearchFilter, bounds []float64) ([]PriceFacet, error) {
+	where, args := productSearchConditions(filter, true, false)
+	args = append(args, pq.Array(bounds))
+	rows, err := r.db.Query(fmt.Sprintf(`
+    SELECT width_bucket(p.price, $%d::numeric[]) AS bucket, COUNT(*)
+    FROM products p LEFT JOIN categories c ON c.id = p.category_id%s
+    GROUP BY bucket
+    ORDER BY bucket`, len(args), where), args...)
+	if err != nil {
+		slog.Error("Error querying price facets", "error", err)
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	facets := []PriceFacet{}
+	for rows.Next() {
+		var facet PriceFacet
+		if err := rows.Scan(&facet.Bucket, &facet.Count); err != nil {
+			return nil, err
+		}
+		facets = append(facets, facet)
+	}
+	return facets, rows.Err()
+}
+
+// SuggestProductNames returns the names of the products best matching a prefix query, for autocomplete.
+func (r *ProductRepository) SuggestProductNames(tsQuery string, limit int) ([]string, error) {
+	rows, err := r.db.Query(`
+    SELECT p.name
+    FROM products p
+    WHERE p.search_vector @@ to_tsquery('english', $1)
+    ORDER BY ts_rank(setweight(to_tsvector('english', p.name), 'A'), to_tsquery('english', $1)) DESC, p.name
+    LIMIT $2`, tsQuery, limit)
+	if err != nil {
+		slog.Error("Error querying product suggestions", "error", err)
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// productSearchConditions builds the WHERE clause of a product search. The text query, if any,
+// is always $1. The category and price filters can be left out to compute facets.
+func productSearchConditions(filter ProductSearchFilter, withCategory, withPrice bool) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.TSQuery != "" {
+		addCondition(`(p.search_vector @@ to_tsquery('english', $%[1]d)
+        OR to_tsvector('english', COALESCE(c.name, '')) @@ to_tsquery('english', $%[1]d))`, filter.TSQuery)
+	}
+	if withCategory && filter.CategoryID != 0 {
+		addCondition("p.category_id = $%d", filter.CategoryID)
+	}
+	if withPrice && filter.MinPrice > 0 {
+		addCondition("p.price >= $%d", filter.MinPrice)
+	}
+	if withPrice && filter.MaxPrice > 0 {
+		addCondition("p.price <= $%d", filter.MaxPrice)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func scanProduct(rows *sql.Rows) (entities.Product, error) {
+	var product entities.Product
+	err := rows.Scan(&product.ID, &product.Name, &product.Description, &product.Price, &product.CreatedAt, &product.UpdatedAt, &product.CategoryID, &product.UserID,
+		&product.StockQuantity, &product.ReservedQuantity, &product.LowStockThreshold)
+	product.AvailableQuantity = product.StockQuantity - product.ReservedQuantity
+	return product, err
+}
+
+// escapeLike escapes the LIKE wildcards in user input.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -1,29 +1,70 @@
 package repositories
 
 import (
+	"backendGoAuth/internal/cache"
 	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/logging"
+	"context"
 	"database/sql"
 	"errors"
+	"github.com/lib/pq"
 	"log"
+	"strconv"
 	"time"
 )
 
+// SessionRevokedChannel is the Postgres NOTIFY channel used to tell other
+// instances that a session was revoked.
+const SessionRevokedChannel = "session_revoked"
+
+// SessionActivityUpdate is the activity recorded for one session since the last flush.
+type SessionActivityUpdate struct {
+	SessionID    int
+	RequestCount int64
+	LastSeenAt   time.Time
+	Fingerprints []SessionFingerprint // in the order they were observed
+}
+
+// SessionFingerprint is the IP address and user agent a request was made from.
+type SessionFingerprint struct {
+	IPAddress string
+	UserAgent string
+	SeenAt    time.Time
+}
+
 type SessionRepository struct {
-	DB *sql.DB
+	db    *sql.DB
+	Cache *cache.SessionCache
 }
 
 func NewSessionRepository(db *sql.DB) *SessionRepository {
 	if db == nil {
 		log.Fatal("Database connection is nil in NewSessionRepository")
 	}
-	return &SessionRepository{DB: db}
+	return &SessionRepository{db: db}
 }
 
-func (r *SessionRepository) InsertSession(session entities.Session) (int, error) {
+// DB returns the database the repository runs on, to start transactions spanning repositories.
+func (r *SessionRepository) DB() *sql.DB {
+	return r.db
+}
+
+// WithCache puts a session validity cache in front of the repository.
+func (r *SessionRepository) WithCache(sessionCache *cache.SessionCache) *SessionRepository {
+	r.Cache = sessionCache
+	return r
+}
+
+func (r *SessionRepository) InsertSession(ctx context.Context, exec DBExecutor, session entities.Session) (int, error) {
+	if exec == nil {
+		exec = r.db
+	}
+	exec = traced(ctx, exec, "SessionRepository.InsertSession")
+
 	var sessionID int
-	err := r.DB.QueryRow(
-		"INSERT INTO user_sessions (user_id, ip_address, location, created_at, updated_at, device_connected, browser_used) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
-		session.UserID, session.IPAddress, session.Location, session.CreatedAt, session.UpdatedAt, session.DeviceConnected, session.BrowserUsed,
+	err := exec.QueryRow(
+		"INSERT INTO user_sessions (user_id, ip_address, location, created_at, updated_at, device_connected, browser_used, impersonator_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id",
+		session.UserID, session.IPAddress, session.Location, session.CreatedAt, session.UpdatedAt, session.DeviceConnected, session.BrowserUsed, session.ImpersonatorID,
 	).Scan(&sessionID)
 	if err != nil {
 		return 0, err
@@ -31,31 +72,134 @@ func (r *SessionRepository) InsertSession(session entities.Session) (int, error)
 	return sessionID, nil
 }
 
-func (r *SessionRepository) GetActiveSessions(userID int) (*sql.Rows, error) {
-	rows, err := r.DB.Query(
-		"SELECT id, ip_address, created_at, updated_at, location, device_connected, browser_used, is_active FROM user_sessions WHERE user_id = $1 AND is_active = true",
+// GetActiveSessions retrieves the sessions of a user that haven't been revoked.
+func (r *SessionRepository) GetActiveSessions(ctx context.Context, userID int) ([]entities.Session, error) {
+	rows, err := traced(ctx, r.db, "SessionRepository.GetActiveSessions").Query(
+		`SELECT id, user_id, ip_address, created_at, updated_at, location, device_connected, browser_used, is_active,
+       last_seen_at, COALESCE(last_ip, ''), COALESCE(last_user_agent, ''), COALESCE(request_count, 0)
+FROM user_sessions WHERE user_id = $1 AND is_active = true`,
 		userID,
 	)
 	if err != nil {
 		return nil, err
 	}
-	return rows, nil
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			logging.FromContext(ctx).Error("Error closing rows", "error", closeErr)
+		}
+	}()
+
+	var sessions []entities.Session
+	for rows.Next() {
+		var session entities.Session
+		if err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.IPAddress,
+			&session.CreatedAt,
+			&session.UpdatedAt,
+			&session.Location,
+			&session.DeviceConnected,
+			&session.BrowserUsed,
+			&session.IsActive,
+			&session.LastSeenAt,
+			&session.LastIP,
+			&session.LastUserAgent,
+			&session.RequestCount,
+		); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
 }
 
-func (r *SessionRepository) RevokeCurrentSession(sessionID int) error {
-	_, err := r.DB.Exec(
-		"UPDATE user_sessions SET is_active = false WHERE id = $1",
+// RevokeSession marks a session as inactive and returns the ID of its user.
+// It returns sql.ErrNoRows if the session doesn't exist. Call InvalidateSession
+// once the transaction is committed.
+func (r *SessionRepository) RevokeSession(ctx context.Context, exec DBExecutor, sessionID int) (int, error) {
+	if exec == nil {
+		exec = r.db
+	}
+	exec = traced(ctx, exec, "SessionRepository.RevokeSession")
+
+	var userID int
+	err := exec.QueryRow(
+		"UPDATE user_sessions SET is_active = false WHERE id = $1 RETURNING user_id",
 		sessionID,
+	).Scan(&userID)
+	return userID, err
+}
+
+// RevokeUserSessions marks all active sessions of a user as inactive and returns their IDs.
+// Call InvalidateSession for each of them once the transaction is committed.
+func (r *SessionRepository) RevokeUserSessions(ctx context.Context, exec DBExecutor, userID int) ([]int, error) {
+	if exec == nil {
+		exec = r.db
+	}
+	exec = traced(ctx, exec, "SessionRepository.RevokeUserSessions")
+
+	rows, err := exec.Query("UPDATE user_sessions SET is_active = false WHERE user_id = $1 AND is_active = true RETURNING id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	var sessionIDs []int
+	for rows.Next() {
+		var sessionID int
+		if err := rows.Scan(&sessionID); err != nil {
+			return nil, err
+		}
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+	return sessionIDs, rows.Err()
+}
+
+// ExpireImpersonationSessions marks the impersonation sessions created before issuedBefore as
+// inactive and returns them, so each is only expired once. Call InvalidateSession for each of
+// them once the transaction is committed.
+func (r *SessionRepository) ExpireImpersonationSessions(ctx context.Context, exec DBExecutor, issuedBefore time.Time) ([]entities.Session, error) {
+	if exec == nil {
+		exec = r.db
+	}
+	exec = traced(ctx, exec, "SessionRepository.ExpireImpersonationSessions")
+
+	rows, err := exec.Query(
+		`UPDATE user_sessions SET is_active = false
+WHERE impersonator_id IS NOT NULL AND is_active = true AND created_at < $1
+RETURNING id, user_id, impersonator_id, created_at`,
+		issuedBefore,
 	)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			logging.FromContext(ctx).Error("Error closing rows", "error", closeErr)
+		}
+	}()
+
+	var sessions []entities.Session
+	for rows.Next() {
+		var session entities.Session
+		if err := rows.Scan(&session.ID, &session.UserID, &session.ImpersonatorID, &session.CreatedAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
 	}
-	return nil
+	return sessions, rows.Err()
 }
 
-func (r *SessionRepository) CheckSession(sessionId int) (bool, error) {
+func (r *SessionRepository) CheckSession(ctx context.Context, sessionId int) (bool, error) {
+	if r.Cache != nil {
+		if isActive, ok := r.Cache.Get(sessionId); ok {
+			return isActive, nil
+		}
+	}
+
 	var session entities.Session
-	err := r.DB.QueryRow(`
+	err := traced(ctx, r.db, "SessionRepository.CheckSession").QueryRow(`
     SELECT id, user_id, ip_address, is_active, created_at, updated_at, location, device_connected, browser_used
     FROM user_sessions WHERE id = $1
 `, sessionId).Scan(
@@ -73,50 +217,176 @@ func (r *SessionRepository) CheckSession(sessionId int) (bool, error) {
 		if errors.Is(err, sql.ErrNoRows) {
 			return false, nil
 		}
-		log.Printf("Error retrieving session: %v\n", err)
+		logging.FromContext(ctx).Error("Error retrieving session", "error", err)
 		return false, err
 	}
+	if r.Cache != nil {
+		r.Cache.Set(session.ID, session.IsActive)
+	}
 	return session.IsActive, nil
 }
 
-func (r *SessionRepository) GetSessionByID(sessionID int) (*entities.Session, error) {
-	if r.DB == nil {
+func (r *SessionRepository) GetSessionByID(ctx context.Context, sessionID int) (*entities.Session, error) {
+	if r.db == nil {
 		return nil, errors.New("database connection is nil")
 	}
 
+	if r.Cache != nil {
+		if isActive, ok := r.Cache.Get(sessionID); ok {
+			return &entities.Session{ID: sessionID, IsActive: isActive}, nil
+		}
+	}
+
 	var session entities.Session
 	// Query the session from the database
-	err := r.DB.QueryRow("SELECT id, is_active FROM user_sessions WHERE id = $1", sessionID).Scan(&session.ID, &session.IsActive)
+	err := traced(ctx, r.db, "SessionRepository.GetSessionByID").QueryRow("SELECT id, is_active FROM user_sessions WHERE id = $1", sessionID).Scan(&session.ID, &session.IsActive)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			// Return nil if no session is found
 			return nil, nil
 		}
 		// Log and return the error if there is any other issue
-		log.Printf("Error retrieving session with ID %d: %v", sessionID, err)
+		logging.FromContext(ctx).Error("Error retrieving session", "session_id", sessionID, "error", err)
 		return nil, err
 	}
+	if r.Cache != nil {
+		r.Cache.Set(session.ID, session.IsActive)
+	}
 	// Return the session object
 	return &session, nil
 }
 
-func (r *SessionRepository) UpdateSessionUpdatedAt(userID int) error {
+// GetSessionOwners retrieves the ID, user, impersonator and state of a session, or nil if there
+// is none. Unlike GetSessionByID it always reads the database, as the cache only knows the state.
+func (r *SessionRepository) GetSessionOwners(ctx context.Context, sessionID int) (*entities.Session, error) {
+	var session entities.Session
+	err := traced(ctx, r.db, "SessionRepository.GetSessionOwners").QueryRow(
+		"SELECT id, user_id, impersonator_id, is_active FROM user_sessions WHERE id = $1",
+		sessionID,
+	).Scan(&session.ID, &session.UserID, &session.ImpersonatorID, &session.IsActive)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *SessionRepository) UpdateSessionUpdatedAt(ctx context.Context, userID int) error {
 	currentTime := time.Now()
-	_, err := r.DB.Exec(
+	_, err := traced(ctx, r.db, "SessionRepository.UpdateSessionUpdatedAt").Exec(
 		"UPDATE user_sessions SET updated_at = $1 WHERE user_id = $2",
 		currentTime, userID,
 	)
 	return err
 }
 
-// fixme check if this actually works, instead of id should be session token?
-func (r *SessionRepository) RevokeSession(sessionID string) error {
-	_, err := r.DB.Exec(
-		"UPDATE user_sessions SET is_active = false WHERE id = $1",
-		sessionID,
+// CountActiveSessions returns the number of sessions that haven't been revoked.
+func (r *SessionRepository) CountActiveSessions(ctx context.Context) (int, error) {
+	var count int
+	err := traced(ctx, r.db, "SessionRepository.CountActiveSessions").QueryRow("SELECT COUNT(*) FROM user_sessions WHERE is_active = true").Scan(&count)
+	return count, err
+}
+
+// InvalidateSession drops a revoked session from the local cache and notifies
+// the other instances so they drop it too.
+func (r *SessionRepository) InvalidateSession(ctx context.Context, sessionID int) {
+	if r.Cache != nil {
+		r.Cache.Invalidate(sessionID)
+	}
+	if _, err := traced(ctx, r.db, "SessionRepository.InvalidateSession").Exec("SELECT pg_notify($1, $2)", SessionRevokedChannel, strconv.Itoa(sessionID)); err != nil {
+		logging.FromContext(ctx).Error("Error notifying session revocation", "session_id", sessionID, "error", err)
+	}
+}
+
+// GetSessionActivity retrieves the IP / user agent change history of the given sessions, oldest first.
+func (r *SessionRepository) GetSessionActivity(ctx context.Context, sessionIDs []int) (map[int][]entities.SessionActivity, error) {
+	rows, err := traced(ctx, r.db, "SessionRepository.GetSessionActivity").Query(
+		"SELECT id, session_id, ip_address, COALESCE(user_agent, ''), created_at FROM user_session_activity WHERE session_id = ANY($1) ORDER BY id",
+		pq.Array(sessionIDs),
 	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			logging.FromContext(ctx).Error("Error closing rows", "error", closeErr)
+		}
+	}()
+
+	activity := make(map[int][]entities.SessionActivity)
+	for rows.Next() {
+		var entry entities.SessionActivity
+		if err := rows.Scan(&entry.ID, &entry.SessionID, &entry.IPAddress, &entry.UserAgent, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		activity[entry.SessionID] = append(activity[entry.SessionID], entry)
+	}
+	return activity, rows.Err()
+}
+
+// FlushSessionActivity writes a batch of session activity in a single transaction.
+// A history entry is only recorded when the IP address or user agent differs from
+// the previous one, and each session keeps at most historyLimit entries.
+func (r *SessionRepository) FlushSessionActivity(ctx context.Context, updates []SessionActivityUpdate, historyLimit int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	return nil
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			logging.FromContext(ctx).Error("Error rolling back session activity flush", "error", err)
+		}
+	}()
+
+	exec := traced(ctx, tx, "SessionRepository.FlushSessionActivity")
+	for _, update := range updates {
+		var lastIP, lastUserAgent string
+		err := exec.QueryRow(
+			"SELECT COALESCE(last_ip, ip_address), COALESCE(last_user_agent, '') FROM user_sessions WHERE id = $1 FOR UPDATE",
+			update.SessionID,
+		).Scan(&lastIP, &lastUserAgent)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		changed := false
+		for _, fingerprint := range update.Fingerprints {
+			if fingerprint.IPAddress == lastIP && fingerprint.UserAgent == lastUserAgent {
+				continue
+			}
+			if _, err := exec.Exec(
+				"INSERT INTO user_session_activity (session_id, ip_address, user_agent, created_at) VALUES ($1, $2, $3, $4)",
+				update.SessionID, fingerprint.IPAddress, fingerprint.UserAgent, fingerprint.SeenAt,
+			); err != nil {
+				return err
+			}
+			lastIP, lastUserAgent = fingerprint.IPAddress, fingerprint.UserAgent
+			changed = true
+		}
+
+		if _, err := exec.Exec(
+			"UPDATE user_sessions SET last_seen_at = $1, last_ip = $2, last_user_agent = $3, request_count = COALESCE(request_count, 0) + $4 WHERE id = $5",
+			update.LastSeenAt, lastIP, lastUserAgent, update.RequestCount, update.SessionID,
+		); err != nil {
+			return err
+		}
+
+		if changed {
+			if _, err := exec.Exec(
+				`DELETE FROM user_session_activity WHERE session_id = $1 AND id NOT IN (
+    SELECT id FROM user_session_activity WHERE session_id = $1 ORDER BY id DESC LIMIT $2
+)`,
+				update.SessionID, historyLimit,
+			); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
 }
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"backendGoAuth/internal/tracing"
+	"context"
+	"database/sql"
+)
+
+// tracedExecutor issues each statement under its own span, named after the repository
+// method running it, as a child of the span in ctx.
+type tracedExecutor struct {
+	ctx  context.Context
+	exec DBExecutor
+	name string
+}
+
+// traced wraps exec so the statements run by the named repository method are traced.
+func traced(ctx context.Context, exec DBExecutor, name string) *tracedExecutor {
+	return &tracedExecutor{ctx: ctx, exec: exec, name: name}
+}
+
+func (t *tracedExecutor) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return t.ExecContext(t.ctx, query, args...)
+}
+
+func (t *tracedExecutor) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return t.QueryContext(t.ctx, query, args...)
+}
+
+func (t *tracedExecutor) QueryRow(query string, args ...interface{}) *sql.Row {
+	return t.QueryRowContext(t.ctx, query, args...)
+}
+
+func (t *tracedExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := tracing.StartQuery(ctx, t.name, query)
+	result, err := t.exec.ExecContext(ctx, query, args...)
+	tracing.End(span, err)
+	return result, err
+}
+
+// QueryContext only covers running the query, not reading the rows.
+func (t *tracedExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := tracing.StartQuery(ctx, t.name, query)
+	rows, err := t.exec.QueryContext(ctx, query, args...)
+	tracing.End(span, err)
+	return rows, err
+}
+
+func (t *tracedExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := tracing.StartQuery(ctx, t.name, query)
+	row := t.exec.QueryRowContext(ctx, query, args...)
+	// Finding no rows isn't reported until Scan, so it doesn't fail the span
+	tracing.End(span, row.Err())
+	return row
+}
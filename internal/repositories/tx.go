@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+)
+
+// DBExecutor is implemented by both *sql.DB and *sql.Tx, so repository methods
+// can run inside or outside a transaction.
+type DBExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// RunInTx runs fn in a transaction, committing if it returns nil and rolling back otherwise.
+// Without a database, as when repositories are kept in memory, fn runs directly with a nil tx.
+func RunInTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	if db == nil {
+		return fn(nil)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			slog.Error("Error rolling back transaction", "error", err)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -2,9 +2,11 @@ package repositories
 
 import (
 	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/logging"
+	"context"
 	"database/sql"
 	"errors"
-	"log"
+	"fmt"
 )
 
 // UserRepository is the concrete struct for interacting with user-related data.
@@ -17,18 +19,23 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{db}
 }
 
+// DB returns the underlying database connection, for running repository methods in a transaction.
+func (r *UserRepository) DB() *sql.DB {
+	return r.db
+}
+
 // GetAllUsers retrieves all active users from the database.
-func (r *UserRepository) GetAllUsers() ([]entities.User, error) {
+func (r *UserRepository) GetAllUsers(ctx context.Context) ([]entities.User, error) {
 	query := "SELECT id, username, email, is_blocked, login_attempts, last_login, created_at, updated_at, is_active FROM users"
-	rows, err := r.db.Query(query)
+	rows, err := traced(ctx, r.db, "UserRepository.GetAllUsers").Query(query)
 	if err != nil {
-		log.Println("Error querying all users:", err)
+		logging.FromContext(ctx).Error("Error querying all users", "error", err)
 		return nil, err
 	}
 	defer func(rows *sql.Rows) {
 		err := rows.Close()
 		if err != nil {
-			log.Println("Error closing rows:", err)
+			logging.FromContext(ctx).Error("Error closing rows", "error", err)
 		}
 	}(rows)
 
@@ -36,7 +43,7 @@ func (r *UserRepository) GetAllUsers() ([]entities.User, error) {
 	for rows.Next() {
 		var user entities.User
 		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.IsBlocked, &user.LoginAttempts, &user.LastLogin, &user.CreatedAt, &user.UpdatedAt, &user.IsActive); err != nil {
-			log.Println("Error scanning user:", err)
+			logging.FromContext(ctx).Error("Error scanning user", "error", err)
 			return nil, err
 		}
 		users = append(users, user)
@@ -44,105 +51,204 @@ func (r *UserRepository) GetAllUsers() ([]entities.User, error) {
 	return users, nil
 }
 
+// LockUser retrieves a user by their ID, locking their row until the transaction ends.
+// It returns nil if there is no such user.
+func (r *UserRepository) LockUser(ctx context.Context, exec DBExecutor, userID int) (*entities.User, error) {
+	if exec == nil {
+		exec = r.db
+	}
+
+	var user entities.User
+	err := traced(ctx, exec, "UserRepository.LockUser").QueryRow("SELECT id, username, email, is_blocked, login_attempts, is_active FROM users WHERE id = $1 FOR UPDATE", userID).Scan(&user.ID, &user.Username, &user.Email, &user.IsBlocked, &user.LoginAttempts, &user.IsActive)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		logging.FromContext(ctx).Error("Error locking user", "error", err)
+		return nil, err
+	}
+	return &user, nil
+}
+
 // EditUser updates a user's details in the database.
-func (r *UserRepository) EditUser(user entities.User) error {
+func (r *UserRepository) EditUser(ctx context.Context, exec DBExecutor, user entities.User) error {
+	if exec == nil {
+		exec = r.db
+	}
+
 	query := "UPDATE users SET username = $1, email = $2, is_blocked = $3, login_attempts = $4, updated_at = $5 WHERE id = $6"
-	_, err := r.db.Exec(query, user.Username, user.Email, user.IsBlocked, user.LoginAttempts, user.UpdatedAt, user.ID)
+	_, err := traced(ctx, exec, "UserRepository.EditUser").Exec(query, user.Username, user.Email, user.IsBlocked, user.LoginAttempts, user.UpdatedAt, user.ID)
 	if err != nil {
-		log.Println("Error updating user:", err)
+		logging.FromContext(ctx).Error("Error updating user", "error", err)
 		return err
 	}
 	return nil
 }
 
 // DeleteUser performs a soft delete on a user by setting is_active to FALSE.
-func (r *UserRepository) DeleteUser(userID int) error {
+func (r *UserRepository) DeleteUser(ctx context.Context, userID int) error {
 	query := "UPDATE users SET is_active = FALSE WHERE id = $1"
-	_, err := r.db.Exec(query, userID)
+	_, err := traced(ctx, r.db, "UserRepository.DeleteUser").Exec(query, userID)
 	if err != nil {
-		log.Println("Error deleting (soft delete) user:", err)
+		logging.FromContext(ctx).Error("Error deleting (soft delete) user", "error", err)
 		return err
 	}
 	return nil
 }
 
 // InsertUser adds a new user to the database and returns the new user's ID.
-func (r *UserRepository) InsertUser(username, password, email string) (int, error) {
-	_, err := r.db.Exec("INSERT INTO users (username, password, email) VALUES ($1, $2, $3)", username, password, email)
+func (r *UserRepository) InsertUser(ctx context.Context, exec DBExecutor, username, password, email string) (int, error) {
+	if exec == nil {
+		exec = r.db
+	}
+	exec = traced(ctx, exec, "UserRepository.InsertUser")
+	_, err := exec.Exec("INSERT INTO users (username, password, email) VALUES ($1, $2, $3)", username, password, email)
 	if err != nil {
-		log.Printf("Error inserting user into database: %v\n", err)
+		logging.FromContext(ctx).Error("Error inserting user into database", "error", err)
 		return 0, err
 	}
 
 	var userID int
-	err = r.db.QueryRow("SELECT id FROM users WHERE username = $1", username).Scan(&userID)
+	err = exec.QueryRow("SELECT id FROM users WHERE username = $1", username).Scan(&userID)
 	if err != nil {
-		log.Printf("Error retrieving userID: %v\n", err)
+		logging.FromContext(ctx).Error("Error retrieving userID", "error", err)
 		return 0, err
 	}
 
-	log.Println("User inserted successfully")
+	logging.FromContext(ctx).Info("User inserted successfully")
 	return userID, nil
 }
 
+// AssignRole gives a user the named role, if they don't have it yet.
+func (r *UserRepository) AssignRole(ctx context.Context, exec DBExecutor, userID int, role string) error {
+	if exec == nil {
+		exec = r.db
+	}
+	exec = traced(ctx, exec, "UserRepository.AssignRole")
+
+	var roleID int
+	if err := exec.QueryRow("SELECT id FROM roles WHERE name = $1", role).Scan(&roleID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("role %s not found", role)
+		}
+		return err
+	}
+
+	_, err := exec.Exec(`
+    INSERT INTO user_roles (user_id, role_id)
+    SELECT $1, $2 WHERE NOT EXISTS (SELECT 1 FROM user_roles WHERE user_id = $1 AND role_id = $2)`,
+		userID, roleID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error assigning role to user", "role", role, "user_id", userID, "error", err)
+	}
+	return err
+}
+
 // UserExistsByUsername checks if a user exists by their username.
-func (r *UserRepository) UserExistsByUsername(username string) (bool, error) {
+func (r *UserRepository) UserExistsByUsername(ctx context.Context, username string) (bool, error) {
 	var count int
-	err := r.db.QueryRow("SELECT COUNT(*) FROM users WHERE username = $1", username).Scan(&count)
+	err := traced(ctx, r.db, "UserRepository.UserExistsByUsername").QueryRow("SELECT COUNT(*) FROM users WHERE username = $1", username).Scan(&count)
 	if err != nil {
-		log.Printf("Error checking if user exists by username: %v\n", err)
+		logging.FromContext(ctx).Error("Error checking if user exists by username", "error", err)
 		return false, err
 	}
 	return count > 0, nil
 }
 
 // UserExistsByEmail checks if a user exists by their email.
-func (r *UserRepository) UserExistsByEmail(email string) (bool, error) {
+func (r *UserRepository) UserExistsByEmail(ctx context.Context, email string) (bool, error) {
 	var count int
-	err := r.db.QueryRow("SELECT COUNT(*) FROM users WHERE email = $1", email).Scan(&count)
+	err := traced(ctx, r.db, "UserRepository.UserExistsByEmail").QueryRow("SELECT COUNT(*) FROM users WHERE email = $1", email).Scan(&count)
 	if err != nil {
-		log.Printf("Error checking if user exists by email: %v\n", err)
+		logging.FromContext(ctx).Error("Error checking if user exists by email", "error", err)
 		return false, err
 	}
 	return count > 0, nil
 }
 
 // GetUserByEmail retrieves a user by their email.
-func (r *UserRepository) GetUserByEmail(email string) (*entities.User, error) {
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*entities.User, error) {
 	var user entities.User
-	err := r.db.QueryRow("SELECT id, username, password, email FROM users WHERE email = $1", email).Scan(&user.ID, &user.Username, &user.Password, &user.Email)
+	err := traced(ctx, r.db, "UserRepository.GetUserByEmail").QueryRow("SELECT id, username, password, email, is_blocked FROM users WHERE email = $1", email).Scan(&user.ID, &user.Username, &user.Password, &user.Email, &user.IsBlocked)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
-		log.Printf("Error retrieving user by email: %v\n", err)
+		logging.FromContext(ctx).Error("Error retrieving user by email", "error", err)
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByID retrieves a user by their ID.
+func (r *UserRepository) GetUserByID(ctx context.Context, userID int) (*entities.User, error) {
+	var user entities.User
+	err := traced(ctx, r.db, "UserRepository.GetUserByID").QueryRow("SELECT id, username, email, is_blocked, login_attempts, is_active FROM users WHERE id = $1", userID).Scan(&user.ID, &user.Username, &user.Email, &user.IsBlocked, &user.LoginAttempts, &user.IsActive)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		logging.FromContext(ctx).Error("Error retrieving user by ID", "error", err)
 		return nil, err
 	}
 	return &user, nil
 }
 
 // GetUserByUsername retrieves a user by their username.
-func (r *UserRepository) GetUserByUsername(username string) (*entities.User, error) {
+func (r *UserRepository) GetUserByUsername(ctx context.Context, username string) (*entities.User, error) {
 	var user entities.User
-	err := r.db.QueryRow("SELECT id, username, password, email FROM users WHERE username = $1", username).Scan(&user.ID, &user.Username, &user.Password, &user.Email)
+	err := traced(ctx, r.db, "UserRepository.GetUserByUsername").QueryRow("SELECT id, username, password, email, is_blocked FROM users WHERE username = $1", username).Scan(&user.ID, &user.Username, &user.Password, &user.Email, &user.IsBlocked)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
-		log.Printf("Error retrieving user by username: %v\n", err)
+		logging.FromContext(ctx).Error("Error retrieving user by username", "error", err)
 		return nil, err
 	}
 	return &user, nil
 }
 
+// RecordFailedLogin increments a user's failed login attempts, blocking the account once
+// maxAttempts is reached. It returns the attempt count and whether this call blocked the account.
+func (r *UserRepository) RecordFailedLogin(ctx context.Context, exec DBExecutor, userID, maxAttempts int) (int, bool, error) {
+	if exec == nil {
+		exec = r.db
+	}
+	exec = traced(ctx, exec, "UserRepository.RecordFailedLogin")
+
+	var attempts int
+	var isBlocked, wasBlocked bool
+	err := exec.QueryRow(`
+    UPDATE users u SET login_attempts = u.login_attempts + 1, is_blocked = u.is_blocked OR u.login_attempts + 1 >= $2
+    FROM (SELECT id, is_blocked FROM users WHERE id = $1 FOR UPDATE) previous
+    WHERE u.id = previous.id
+    RETURNING u.login_attempts, u.is_blocked, previous.is_blocked`,
+		userID, maxAttempts,
+	).Scan(&attempts, &isBlocked, &wasBlocked)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error recording failed login", "error", err)
+		return 0, false, err
+	}
+	return attempts, isBlocked && !wasBlocked, nil
+}
+
+// RecordSuccessfulLogin resets a user's failed login attempts and sets their last login time.
+func (r *UserRepository) RecordSuccessfulLogin(ctx context.Context, userID int) error {
+	_, err := traced(ctx, r.db, "UserRepository.RecordSuccessfulLogin").Exec("UPDATE users SET login_attempts = 0, last_login = CURRENT_TIMESTAMP WHERE id = $1", userID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error recording successful login", "error", err)
+	}
+	return err
+}
+
 // RevokeUser revokes (soft deletes) a user by setting is_active to FALSE.
-func (r *UserRepository) RevokeUser(userID int) error {
-	_, err := r.db.Exec(
+func (r *UserRepository) RevokeUser(ctx context.Context, userID int) error {
+	_, err := traced(ctx, r.db, "UserRepository.RevokeUser").Exec(
 		"UPDATE users SET is_active = false WHERE id = $1",
 		userID,
 	)
 	if err != nil {
-		log.Printf("Error revoking user: %v\n", err)
+		logging.FromContext(ctx).Error("Error revoking user", "error", err)
 	}
 	return err
 }
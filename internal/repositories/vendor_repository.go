@@ -0,0 +1,197 @@
+package repositories
+
+import (
+	"backendGoAuth/internal/entities"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"log/slog"
+)
+
+// vendorApplicationColumns lists the columns read by scanVendorApplication, in order.
+const vendorApplicationColumns = "id, user_id, store_name, COALESCE(description, ''), contact_email, status, reviewed_by, COALESCE(review_note, ''), reviewed_at, created_at, updated_at"
+
+// ErrPendingApplicationExists is returned when a user submits a second application before the first was reviewed.
+var ErrPendingApplicationExists = errors.New("a vendor application is already pending")
+
+// VendorDashboard summarizes a vendor's catalog and sales.
+type VendorDashboard struct {
+	ProductCount    int
+	LowStockCount   int
+	OutOfStockCount int
+	OrdersByStatus  map[string]int
+	UnitsSold       int
+	Revenue         float64
+}
+
+// VendorRepository is the concrete struct for interacting with vendor applications and vendor statistics.
+type VendorRepository struct {
+	db *sql.DB
+}
+
+// NewVendorRepository creates a new instance of VendorRepository.
+func NewVendorRepository(db *sql.DB) *VendorRepository {
+	return &VendorRepository{db}
+}
+
+// DB returns the underlying database connection, for running repository methods in a transaction.
+func (r *VendorRepository) DB() *sql.DB {
+	return r.db
+}
+
+// InsertApplication inserts a pending vendor application. It returns ErrPendingApplicationExists
+// if the user already has one.
+func (r *VendorRepository) InsertApplication(application *entities.VendorApplication) error {
+	err := r.db.QueryRow(`
+    INSERT INTO vendor_applications (user_id, store_name, description, contact_email)
+    VALUES ($1, $2, $3, $4)
+    RETURNING id, status, created_at, updated_at`,
+		application.UserID, application.StoreName, application.Description, application.ContactEmail,
+	).Scan(&application.ID, &application.Status, &application.CreatedAt, &application.UpdatedAt)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrPendingApplicationExists
+	}
+	if err != nil {
+		slog.Error("Error inserting vendor application", "error", err)
+	}
+	return err
+}
+
+// GetApplications retrieves vendor applications, optionally only those of a user or in a status,
+// oldest first.
+func (r *VendorRepository) GetApplications(userID int, status string) ([]entities.VendorApplication, error) {
+	rows, err := r.db.Query(`
+    SELECT `+vendorApplicationColumns+` FROM vendor_applications
+    WHERE ($1 = 0 OR user_id = $1) AND ($2 = '' OR status = $2)
+    ORDER BY created_at, id`, userID, status)
+	if err != nil {
+		slog.Error("Error querying vendor applications", "error", err)
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	applications := []entities.VendorApplication{}
+	for rows.Next() {
+		application, err := scanVendorApplication(rows)
+		if err != nil {
+			return nil, err
+		}
+		applications = append(applications, application)
+	}
+	return applications, rows.Err()
+}
+
+// GetApprovedApplication retrieves the application that made a user a vendor. It returns nil if there is none.
+func (r *VendorRepository) GetApprovedApplication(userID int) (*entities.VendorApplication, error) {
+	application, err := scanVendorApplication(r.db.QueryRow(
+		"SELECT "+vendorApplicationColumns+" FROM vendor_applications WHERE user_id = $1 AND status = 'APPROVED' ORDER BY reviewed_at DESC LIMIT 1",
+		userID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		slog.Error("Error retrieving approved vendor application", "error", err)
+		return nil, err
+	}
+	return &application, nil
+}
+
+// ReviewApplication approves or rejects a pending application. It returns nil if the
+// application doesn't exist or was already reviewed.
+func (r *VendorRepository) ReviewApplication(tx *sql.Tx, applicationID int, status string, reviewerID int, note string) (*entities.VendorApplication, error) {
+	application, err := scanVendorApplication(tx.QueryRow(`
+    UPDATE vendor_applications
+    SET status = $2, reviewed_by = $3, review_note = NULLIF($4, ''), reviewed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+    WHERE id = $1 AND status = 'PENDING'
+    RETURNING `+vendorApplicationColumns,
+		applicationID, status, reviewerID, note,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		slog.Error("Error reviewing vendor application", "application_id", applicationID, "error", err)
+		return nil, err
+	}
+	return &application, nil
+}
+
+// GetDashboard summarizes a vendor's products and the orders containing them. Revenue and units
+// only count orders that were paid and not refunded.
+func (r *VendorRepository) GetDashboard(vendorID int) (VendorDashboard, error) {
+	dashboard := VendorDashboard{OrdersByStatus: map[string]int{}}
+
+	err := r.db.QueryRow(`
+    SELECT COUNT(*),
+           COUNT(*) FILTER (WHERE stock_quantity - reserved_quantity <= low_stock_threshold),
+           COUNT(*) FILTER (WHERE stock_quantity - reserved_quantity <= 0)
+    FROM products WHERE user_id = $1`, vendorID,
+	).Scan(&dashboard.ProductCount, &dashboard.LowStockCount, &dashboard.OutOfStockCount)
+	if err != nil {
+		slog.Error("Error summarizing products of vendor", "vendor_id", vendorID, "error", err)
+		return dashboard, err
+	}
+
+	rows, err := r.db.Query(`
+    SELECT o.status,
+           COUNT(DISTINCT o.id),
+           COALESCE(SUM(oi.quantity), 0),
+           COALESCE(SUM(oi.quantity * oi.price_at_order), 0)
+    FROM orders o
+    JOIN order_items oi ON oi.order_id = o.id
+    JOIN products p ON p.id = oi.product_id
+    WHERE p.user_id = $1
+    GROUP BY o.status`, vendorID)
+	if err != nil {
+		slog.Error("Error summarizing orders of vendor", "vendor_id", vendorID, "error", err)
+		return dashboard, err
+	}
+	defer closeRows(rows)
+
+	for rows.Next() {
+		var status string
+		var orders, units int
+		var revenue float64
+		if err := rows.Scan(&status, &orders, &units, &revenue); err != nil {
+			return dashboard, err
+		}
+		dashboard.OrdersByStatus[status] = orders
+		switch status {
+		case "PAID", "SHIPPED", "COMPLETED":
+			dashboard.UnitsSold += units
+			dashboard.Revenue += revenue
+		}
+	}
+	return dashboard, rows.Err()
+}
+
+// scanVendorApplication scans a row selected with vendorApplicationColumns.
+func scanVendorApplication(row interface{ Scan(...interface{}) error }) (entities.VendorApplication, error) {
+	var application entities.VendorApplication
+	var reviewedBy sql.NullInt64
+	var reviewedAt sql.NullTime
+	err := row.Scan(
+		&application.ID,
+		&application.UserID,
+		&application.StoreName,
+		&application.Description,
+		&application.ContactEmail,
+		&application.Status,
+		&reviewedBy,
+		&application.ReviewNote,
+		&reviewedAt,
+		&application.CreatedAt,
+		&application.UpdatedAt,
+	)
+	if reviewedBy.Valid {
+		id := int(reviewedBy.Int64)
+		application.ReviewedBy = &id
+	}
+	if reviewedAt.Valid {
+		application.ReviewedAt = &reviewedAt.Time
+	}
+	return application, err
+}
@@ -0,0 +1,227 @@
+package repositories
+
+import (
+	"backendGoAuth/internal/entities"
+	"database/sql"
+	"github.com/lib/pq"
+	"log/slog"
+	"time"
+)
+
+// Webhook delivery statuses
+const (
+	DeliveryStatusPending   = "PENDING"
+	DeliveryStatusDelivered = "DELIVERED"
+	DeliveryStatusDead      = "DEAD"
+)
+
+// ClaimedDelivery is a delivery leased by a dispatcher, with everything needed to send it.
+type ClaimedDelivery struct {
+	ID       int64
+	Attempts int
+	URL      string
+	Secret   string
+	Event    entities.OutboxEvent
+}
+
+// WebhookRepository is the concrete struct for webhook endpoints and deliveries.
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+// NewWebhookRepository creates a new instance of WebhookRepository.
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db}
+}
+
+// InsertEndpoint registers a webhook endpoint and returns its ID.
+func (r *WebhookRepository) InsertEndpoint(endpoint entities.WebhookEndpoint) (int, error) {
+	var id int
+	err := r.db.QueryRow(
+		"INSERT INTO webhook_endpoints (url, secret, event_types, created_by) VALUES ($1, $2, $3, $4) RETURNING id",
+		endpoint.URL, endpoint.Secret, pq.Array(endpoint.EventTypes), endpoint.CreatedBy,
+	).Scan(&id)
+	if err != nil {
+		slog.Error("Error inserting webhook endpoint", "error", err)
+		return 0, err
+	}
+	return id, nil
+}
+
+// GetEndpoints retrieves all active webhook endpoints.
+func (r *WebhookRepository) GetEndpoints() ([]entities.WebhookEndpoint, error) {
+	rows, err := r.db.Query("SELECT id, url, event_types, is_active, created_by, created_at, updated_at FROM webhook_endpoints WHERE is_active = true ORDER BY id")
+	if err != nil {
+		slog.Error("Error querying webhook endpoints", "error", err)
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	endpoints := []entities.WebhookEndpoint{}
+	for rows.Next() {
+		var endpoint entities.WebhookEndpoint
+		if err := rows.Scan(&endpoint.ID, &endpoint.URL, pq.Array(&endpoint.EventTypes), &endpoint.IsActive, &endpoint.CreatedBy, &endpoint.CreatedAt, &endpoint.UpdatedAt); err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, rows.Err()
+}
+
+// DeactivateEndpoint stops deliveries to an endpoint. It returns false if no active endpoint has that ID.
+func (r *WebhookRepository) DeactivateEndpoint(endpointID int) (bool, error) {
+	result, err := r.db.Exec("UPDATE webhook_endpoints SET is_active = false, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND is_active = true", endpointID)
+	if err != nil {
+		slog.Error("Error deactivating webhook endpoint", "error", err)
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetDeliveries retrieves the most recent deliveries, optionally filtered by status.
+func (r *WebhookRepository) GetDeliveries(status string, limit int) ([]entities.WebhookDelivery, error) {
+	rows, err := r.db.Query(`
+    SELECT d.id, d.outbox_id, d.endpoint_id, o.event_type, d.status, d.attempts, d.next_attempt_at,
+           d.last_status_code, COALESCE(d.last_error, ''), d.delivered_at, d.created_at
+    FROM webhook_deliveries d JOIN event_outbox o ON o.id = d.outbox_id
+    WHERE ($1 = '' OR d.status = $1)
+    ORDER BY d.id DESC LIMIT $2`, status, limit)
+	if err != nil {
+		slog.Error("Error querying webhook deliveries", "error", err)
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	deliveries := []entities.WebhookDelivery{}
+	for rows.Next() {
+		var delivery entities.WebhookDelivery
+		if err := rows.Scan(&delivery.ID, &delivery.OutboxID, &delivery.EndpointID, &delivery.EventType, &delivery.Status, &delivery.Attempts,
+			&delivery.NextAttemptAt, &delivery.LastStatusCode, &delivery.LastError, &delivery.DeliveredAt, &delivery.CreatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// ReplayDelivery schedules a delivery to be sent again immediately, with a fresh retry budget.
+// It returns false if the delivery doesn't exist.
+func (r *WebhookRepository) ReplayDelivery(deliveryID int64) (bool, error) {
+	result, err := r.db.Exec(
+		"UPDATE webhook_deliveries SET status = $1, attempts = 0, next_attempt_at = CURRENT_TIMESTAMP, delivered_at = NULL WHERE id = $2",
+		DeliveryStatusPending, deliveryID,
+	)
+	if err != nil {
+		slog.Error("Error replaying webhook delivery", "error", err)
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// FanOutOutbox creates deliveries for up to limit undispatched outbox events, one per
+// subscribed active endpoint, and marks the events as dispatched.
+func (r *WebhookRepository) FanOutOutbox(limit int) (int, error) {
+	var dispatched int
+	err := RunInTx(r.db, func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT id FROM event_outbox WHERE dispatched_at IS NULL ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED", limit)
+		if err != nil {
+			return err
+		}
+		var ids []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				closeRows(rows)
+				return err
+			}
+			ids = append(ids, id)
+		}
+		closeRows(rows)
+		if err := rows.Err(); err != nil || len(ids) == 0 {
+			return err
+		}
+
+		if _, err := tx.Exec(`
+    INSERT INTO webhook_deliveries (outbox_id, endpoint_id)
+    SELECT o.id, e.id
+    FROM event_outbox o JOIN webhook_endpoints e ON e.is_active AND (o.event_type = ANY (e.event_types) OR '*' = ANY (e.event_types))
+    WHERE o.id = ANY ($1)
+    ON CONFLICT (outbox_id, endpoint_id) DO NOTHING`, pq.Array(ids)); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("UPDATE event_outbox SET dispatched_at = CURRENT_TIMESTAMP WHERE id = ANY ($1)", pq.Array(ids)); err != nil {
+			return err
+		}
+		dispatched = len(ids)
+		return nil
+	})
+	return dispatched, err
+}
+
+// ClaimDueDeliveries leases up to limit pending deliveries whose next attempt is due,
+// pushing their next attempt back by lease so other dispatchers skip them meanwhile.
+// Deliveries to endpoints that were deactivated are left alone.
+func (r *WebhookRepository) ClaimDueDeliveries(limit int, lease time.Duration) ([]ClaimedDelivery, error) {
+	rows, err := r.db.Query(`
+    WITH due AS (
+        SELECT d.id FROM webhook_deliveries d
+        JOIN webhook_endpoints e ON e.id = d.endpoint_id
+        WHERE d.status = $1 AND d.next_attempt_at <= CURRENT_TIMESTAMP AND e.is_active
+        ORDER BY d.next_attempt_at LIMIT $2
+        FOR UPDATE OF d SKIP LOCKED
+    ), claimed AS (
+        UPDATE webhook_deliveries d SET next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $3)
+        FROM due WHERE d.id = due.id
+        RETURNING d.id, d.attempts, d.outbox_id, d.endpoint_id
+    )
+    SELECT c.id, c.attempts, e.url, e.secret, o.id, o.event_type, o.payload, o.created_at
+    FROM claimed c
+    JOIN webhook_endpoints e ON e.id = c.endpoint_id
+    JOIN event_outbox o ON o.id = c.outbox_id`, DeliveryStatusPending, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	var claimed []ClaimedDelivery
+	for rows.Next() {
+		var delivery ClaimedDelivery
+		var payload []byte
+		if err := rows.Scan(&delivery.ID, &delivery.Attempts, &delivery.URL, &delivery.Secret,
+			&delivery.Event.ID, &delivery.Event.EventType, &payload, &delivery.Event.CreatedAt); err != nil {
+			return nil, err
+		}
+		delivery.Event.Payload = payload
+		claimed = append(claimed, delivery)
+	}
+	return claimed, rows.Err()
+}
+
+// MarkDelivered records a successful delivery.
+func (r *WebhookRepository) MarkDelivered(deliveryID int64, statusCode int) error {
+	_, err := r.db.Exec(
+		"UPDATE webhook_deliveries SET status = $1, attempts = attempts + 1, last_status_code = $2, last_error = NULL, delivered_at = CURRENT_TIMESTAMP WHERE id = $3",
+		DeliveryStatusDelivered, statusCode, deliveryID,
+	)
+	return err
+}
+
+// MarkFailed records a failed attempt, scheduling a retry at nextAttemptAt or
+// dead-lettering the delivery when nextAttemptAt is nil.
+func (r *WebhookRepository) MarkFailed(deliveryID int64, statusCode int, lastError string, nextAttemptAt *time.Time) error {
+	status := DeliveryStatusPending
+	if nextAttemptAt == nil {
+		status = DeliveryStatusDead
+	}
+	_, err := r.db.Exec(
+		"UPDATE webhook_deliveries SET status = $1, attempts = attempts + 1, last_status_code = NULLIF($2, 0), last_error = $3, next_attempt_at = COALESCE($4, next_attempt_at) WHERE id = $5",
+		status, statusCode, lastError, nextAttemptAt, deliveryID,
+	)
+	if err != nil {
+		slog.Error("Error recording failed webhook delivery", "delivery_id", deliveryID, "error", err)
+	}
+	return err
+}
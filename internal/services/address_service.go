@@ -0,0 +1,191 @@
+package services
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/repositories"
+	"database/sql"
+	"log/slog"
+	"strings"
+)
+
+// AddressService manages users' address books.
+type AddressService struct {
+	AddressRepo repositories.AddressRepositoryInterface
+}
+
+// NewAddressService creates a new instance of AddressService.
+func NewAddressService(addressRepo repositories.AddressRepositoryInterface) *AddressService {
+	return &AddressService{
+		AddressRepo: addressRepo,
+	}
+}
+
+// GetAddresses returns the user's address book.
+func (s *AddressService) GetAddresses(userID int) ([]entities.Address, error) {
+	addresses, err := s.AddressRepo.GetAddresses(userID)
+	if err != nil {
+		return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve addresses")
+	}
+	return addresses, nil
+}
+
+// GetAddress returns one of the user's addresses.
+func (s *AddressService) GetAddress(userID, addressID int) (*entities.Address, error) {
+	address, err := s.AddressRepo.GetAddress(nil, userID, addressID)
+	if err != nil {
+		return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve address")
+	}
+	if address == nil {
+		return nil, goAuthException.NewCustomError(goAuthException.NotFoundCode, "Address not found")
+	}
+	return address, nil
+}
+
+// CreateAddress adds an address to the user's address book. The user's first address becomes
+// their default shipping and billing address.
+func (s *AddressService) CreateAddress(userID int, req models.AddressRequest) (*entities.Address, error) {
+	address, err := newAddress(userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.updateAddressBook(userID, func(tx *sql.Tx) error {
+		hasShipping, hasBilling, err := s.AddressRepo.HasDefaults(tx, userID)
+		if err != nil {
+			return err
+		}
+		address.IsDefaultShipping = address.IsDefaultShipping || !hasShipping
+		address.IsDefaultBilling = address.IsDefaultBilling || !hasBilling
+
+		if err := s.clearDefaultsFor(tx, address); err != nil {
+			return err
+		}
+		return s.AddressRepo.InsertAddress(tx, address)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+// UpdateAddress changes one of the user's addresses. Addresses that orders were shipped to are
+// archived and replaced by an updated copy, so past orders keep the address they were sent to.
+func (s *AddressService) UpdateAddress(userID, addressID int, req models.AddressRequest) (*entities.Address, error) {
+	address, err := newAddress(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	address.ID = addressID
+
+	err = s.updateAddressBook(userID, func(tx *sql.Tx) error {
+		existing, err := s.AddressRepo.GetAddress(tx, userID, addressID)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return goAuthException.NewCustomError(goAuthException.NotFoundCode, "Address not found")
+		}
+
+		referenced, err := s.AddressRepo.IsAddressReferenced(tx, addressID)
+		if err != nil {
+			return err
+		}
+		if referenced {
+			if err := s.AddressRepo.ArchiveAddress(tx, addressID); err != nil {
+				return err
+			}
+		}
+		if err := s.clearDefaultsFor(tx, address); err != nil {
+			return err
+		}
+		if referenced {
+			return s.AddressRepo.InsertAddress(tx, address)
+		}
+
+		address.CreatedAt = existing.CreatedAt
+		return s.AddressRepo.UpdateAddress(tx, address)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+// DeleteAddress removes one of the user's addresses. Addresses that orders were shipped to are
+// archived instead. It returns whether the address was archived.
+func (s *AddressService) DeleteAddress(userID, addressID int) (bool, error) {
+	archived := false
+	err := s.updateAddressBook(userID, func(tx *sql.Tx) error {
+		existing, err := s.AddressRepo.GetAddress(tx, userID, addressID)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return goAuthException.NewCustomError(goAuthException.NotFoundCode, "Address not found")
+		}
+
+		archived, err = s.AddressRepo.IsAddressReferenced(tx, addressID)
+		if err != nil {
+			return err
+		}
+		if archived {
+			return s.AddressRepo.ArchiveAddress(tx, addressID)
+		}
+		return s.AddressRepo.DeleteAddress(tx, addressID)
+	})
+	return archived, err
+}
+
+// clearDefaultsFor unsets the user's current defaults that the address is about to take over.
+func (s *AddressService) clearDefaultsFor(tx *sql.Tx, address *entities.Address) error {
+	if !address.IsDefaultShipping && !address.IsDefaultBilling {
+		return nil
+	}
+	return s.AddressRepo.ClearDefaults(tx, address.UserID, address.IsDefaultShipping, address.IsDefaultBilling)
+}
+
+// updateAddressBook runs fn with the user's address book locked.
+func (s *AddressService) updateAddressBook(userID int, fn func(tx *sql.Tx) error) error {
+	err := repositories.RunInTx(s.AddressRepo.DB(), func(tx *sql.Tx) error {
+		if err := s.AddressRepo.LockAddressBook(tx, userID); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+	if err != nil {
+		if _, ok := err.(*goAuthException.CustomError); ok {
+			return err
+		}
+		slog.Error("Error updating addresses of user", "user_id", userID, "error", err)
+		return goAuthException.NewCustomError(goAuthException.InternalServerErrorCode, "Failed to update address")
+	}
+	return nil
+}
+
+// newAddress validates and normalizes an address request.
+func newAddress(userID int, req models.AddressRequest) (*entities.Address, error) {
+	if strings.TrimSpace(req.Street) == "" || strings.TrimSpace(req.City) == "" {
+		return nil, goAuthException.NewCustomError(goAuthException.BadRequestCode, "Street and city are required")
+	}
+	country, ok := normalizeCountry(req.Country)
+	if !ok {
+		return nil, goAuthException.NewCustomError(goAuthException.BadRequestCode, "Country must be a two-letter ISO 3166-1 code")
+	}
+	zipCode, ok := normalizePostalCode(country, req.ZipCode)
+	if !ok {
+		return nil, goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid postal code for "+country)
+	}
+
+	return &entities.Address{
+		UserID:            userID,
+		Street:            strings.TrimSpace(req.Street),
+		City:              strings.TrimSpace(req.City),
+		State:             strings.TrimSpace(req.State),
+		ZipCode:           zipCode,
+		Country:           country,
+		IsDefaultShipping: req.IsDefaultShipping,
+		IsDefaultBilling:  req.IsDefaultBilling,
+	}, nil
+}
@@ -2,33 +2,90 @@ package services
 
 import (
 	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/metrics"
 	"backendGoAuth/internal/repositories"
+	"context"
+	"database/sql"
 	"time"
 )
 
 type AdminService interface {
-	GetAllUsers() ([]entities.User, error)
-	EditUser(user entities.User) error
-	DeleteUser(userID int) error
+	GetAllUsers(ctx context.Context) ([]entities.User, error)
+	GetUserByID(ctx context.Context, userID int) (*entities.User, error)
+	EditUser(ctx context.Context, user entities.User) error
+	DeleteUser(ctx context.Context, userID int) error
 }
 
 type adminService struct {
-	repo repositories.UserRepository
+	repo     repositories.UserRepositoryInterface
+	sessions repositories.SessionRepositoryInterface
+	outbox   repositories.OutboxRepositoryInterface
+	metrics  *metrics.Metrics
 }
 
-func NewAdminService(repo repositories.UserRepository) AdminService {
-	return &adminService{repo}
+func NewAdminService(repo repositories.UserRepositoryInterface, sessions repositories.SessionRepositoryInterface, outbox repositories.OutboxRepositoryInterface, m *metrics.Metrics) AdminService {
+	return &adminService{repo, sessions, outbox, m}
 }
 
-func (s *adminService) GetAllUsers() ([]entities.User, error) {
-	return s.repo.GetAllUsers()
+func (s *adminService) GetAllUsers(ctx context.Context) ([]entities.User, error) {
+	return s.repo.GetAllUsers(ctx)
 }
 
-func (s *adminService) EditUser(user entities.User) error {
-	user.UpdatedAt = time.Now()
-	return s.repo.EditUser(user)
+func (s *adminService) GetUserByID(ctx context.Context, userID int) (*entities.User, error) {
+	return s.repo.GetUserByID(ctx, userID)
 }
 
-func (s *adminService) DeleteUser(userID int) error {
-	return s.repo.DeleteUser(userID)
+// EditUser updates a user. Blocking them ends their sessions and emits user.locked, in the
+// same transaction as the update.
+func (s *adminService) EditUser(ctx context.Context, user entities.User) error {
+	var locked bool
+	var sessionIDs []int
+	err := repositories.RunInTx(s.repo.DB(), func(tx *sql.Tx) error {
+		// Locking the row keeps concurrent edits from both seeing the user unblocked
+		before, err := s.repo.LockUser(ctx, tx, user.ID)
+		if err != nil || before == nil {
+			return err
+		}
+
+		user.UpdatedAt = time.Now()
+		if err := s.repo.EditUser(ctx, tx, user); err != nil {
+			return err
+		}
+		if before.IsBlocked || !user.IsBlocked {
+			return nil
+		}
+
+		// Tokens stay valid while their session is active, so blocking has to end the sessions
+		locked = true
+		if sessionIDs, err = s.sessions.RevokeUserSessions(ctx, tx, user.ID); err != nil {
+			return err
+		}
+		for _, sessionID := range sessionIDs {
+			err := s.outbox.Enqueue(tx, EventSessionRevoked, map[string]interface{}{
+				"user_id":    user.ID,
+				"session_id": sessionID,
+				"reason":     "user_locked",
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return s.outbox.Enqueue(tx, EventUserLocked, map[string]interface{}{"user_id": user.ID})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, sessionID := range sessionIDs {
+		s.sessions.InvalidateSession(ctx, sessionID)
+	}
+	if locked {
+		s.metrics.AccountLocked()
+		s.metrics.SessionsRevoked("user_locked", len(sessionIDs))
+	}
+	return nil
+}
+
+func (s *adminService) DeleteUser(ctx context.Context, userID int) error {
+	return s.repo.DeleteUser(ctx, userID)
 }
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/logging"
+	"backendGoAuth/internal/repositories"
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// auditSealBatchSize is the maximum number of entries sealed per transaction.
+const auditSealBatchSize = 1000
+
+// AuditChainBreak describes the first entry whose chain link doesn't verify.
+type AuditChainBreak struct {
+	ChainSeq int64  `json:"chain_seq"`
+	ID       int    `json:"id"`
+	Reason   string `json:"reason"`
+}
+
+// AuditChainVerification is the result of walking the audit hash chain.
+type AuditChainVerification struct {
+	Verified int              `json:"verified"`
+	Redacted int              `json:"redacted"`
+	Pending  int              `json:"pending"`
+	Broken   *AuditChainBreak `json:"broken,omitempty"`
+}
+
+// AuditSegment summarizes an exported range of the chain. Its signature lets a
+// reader check the export wasn't truncated or spliced.
+type AuditSegment struct {
+	FromSeq   int64  `json:"from_seq"`
+	ToSeq     int64  `json:"to_seq"`
+	Count     int    `json:"count"`
+	FirstPrev string `json:"first_prev_hash"`
+	LastHash  string `json:"last_hash"`
+	Signature string `json:"signature"`
+}
+
+// AuditChain seals audit log entries into a tamper-evident HMAC chain and verifies it.
+type AuditChain struct {
+	AuditRepo repositories.AuditChainRepositoryInterface
+	key       []byte
+}
+
+// NewAuditChain creates a new instance of AuditChain keyed with a dedicated HMAC key.
+func NewAuditChain(auditRepo repositories.AuditChainRepositoryInterface, key []byte) (*AuditChain, error) {
+	if len(key) == 0 {
+		return nil, errors.New("audit chain requires a non-empty HMAC key")
+	}
+	return &AuditChain{
+		AuditRepo: auditRepo,
+		key:       key,
+	}, nil
+}
+
+// auditContent is the canonical form of an entry hashed into its content digest.
+// user_id is left out since its foreign key nulls it when a user row is deleted.
+type auditContent struct {
+	ID        int    `json:"id"`
+	ActorID   *int   `json:"actor_id"`
+	TargetID  *int   `json:"target_id"`
+	Action    string `json:"action"`
+	TableName string `json:"table_name"`
+	RowID     *int   `json:"row_id"`
+	OldData   string `json:"old_data"`
+	NewData   string `json:"new_data"`
+	IPAddress string `json:"ip_address"`
+	UserAgent string `json:"user_agent"`
+	CreatedAt string `json:"created_at"`
+}
+
+// auditRedactionRecord is the payload of an AUDIT_REDACTED entry: the content digest of each
+// redacted entry after redaction, keyed by entry ID.
+type auditRedactionRecord struct {
+	Redacted map[string]string `json:"redacted"`
+}
+
+// redactedAuditEntry is a sealed entry whose content changed when it was redacted.
+type redactedAuditEntry struct {
+	chainSeq       int64
+	id             int
+	digest         string
+	redactedBefore int // redacted entries earlier in the chain
+}
+
+// Seal appends every unsealed entry to the chain and returns how many were sealed.
+func (ch *AuditChain) Seal() (int, error) {
+	total := 0
+	for {
+		sealed, err := ch.AuditRepo.SealPendingAuditLogs(auditSealBatchSize, ch.seal)
+		total += sealed
+		if err != nil || sealed < auditSealBatchSize {
+			return total, err
+		}
+	}
+}
+
+// Verify walks the whole chain and reports the first broken link, if any. Entries redacted
+// after they were sealed must match the digest a later AUDIT_REDACTED entry sealed for them.
+func (ch *AuditChain) Verify() (AuditChainVerification, error) {
+	var result AuditChainVerification
+	expectedSeq := int64(1)
+	prevHash := ""
+	var redacted []redactedAuditEntry
+	redactionDigests := make(map[string]string)
+
+	err := ch.AuditRepo.IterateAuditChain(0, 0, func(entry entities.AuditLog) error {
+		if reason := ch.checkLink(entry, expectedSeq, prevHash); reason != "" {
+			result.Broken = &AuditChainBreak{ChainSeq: *entry.ChainSeq, ID: entry.ID, Reason: reason}
+			return errStopIteration
+		}
+		if digest := auditContentDigest(entry); digest != entry.ContentDigest {
+			if entry.RedactedAt == nil {
+				result.Broken = &AuditChainBreak{ChainSeq: *entry.ChainSeq, ID: entry.ID, Reason: "content doesn't match content_digest, entry was modified"}
+				return errStopIteration
+			}
+			redacted = append(redacted, redactedAuditEntry{chainSeq: *entry.ChainSeq, id: entry.ID, digest: digest, redactedBefore: result.Redacted})
+		}
+		if entry.Action == AuditActionAuditRedacted {
+			var record auditRedactionRecord
+			if err := json.Unmarshal(entry.NewData, &record); err != nil {
+				result.Broken = &AuditChainBreak{ChainSeq: *entry.ChainSeq, ID: entry.ID, Reason: "redaction record is malformed"}
+				return errStopIteration
+			}
+			// An entry redacted again is checked against its latest redaction
+			for id, digest := range record.Redacted {
+				redactionDigests[id] = digest
+			}
+		}
+		if entry.RedactedAt != nil {
+			result.Redacted++
+		}
+		result.Verified++
+		expectedSeq++
+		prevHash = entry.Hash
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopIteration) {
+		return result, err
+	}
+
+	if result.Broken == nil {
+		for _, entry := range redacted {
+			if redactionDigests[strconv.Itoa(entry.id)] != entry.digest {
+				result.Broken = &AuditChainBreak{ChainSeq: entry.chainSeq, ID: entry.id, Reason: "redacted content doesn't match a sealed redaction record"}
+				result.Verified, result.Redacted = int(entry.chainSeq-1), entry.redactedBefore
+				break
+			}
+		}
+	}
+
+	result.Pending, err = ch.AuditRepo.CountUnsealedAuditLogs()
+	return result, err
+}
+
+// Run seals new entries every interval until ctx is cancelled, then seals one last time.
+func (ch *AuditChain) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if _, err := ch.Seal(); err != nil {
+				logging.FromContext(ctx).Error("Error sealing audit chain", "error", err)
+			}
+			return
+		case <-ticker.C:
+			if _, err := ch.Seal(); err != nil {
+				logging.FromContext(ctx).Error("Error sealing audit chain", "error", err)
+			}
+		}
+	}
+}
+
+// Export writes the sealed entries between fromSeq and toSeq (0 = end of chain) as JSONL,
+// followed by a signed segment summary line.
+func (ch *AuditChain) Export(w io.Writer, fromSeq, toSeq int64) (AuditSegment, error) {
+	if fromSeq < 1 {
+		fromSeq = 1
+	}
+	segment := AuditSegment{FromSeq: fromSeq}
+	writer := bufio.NewWriter(w)
+	encoder := json.NewEncoder(writer)
+
+	err := ch.AuditRepo.IterateAuditChain(fromSeq, toSeq, func(entry entities.AuditLog) error {
+		if segment.Count == 0 {
+			segment.FirstPrev = entry.PrevHash
+		}
+		segment.Count++
+		segment.ToSeq = *entry.ChainSeq
+		segment.LastHash = entry.Hash
+		return encoder.Encode(entry)
+	})
+	if err != nil {
+		return segment, err
+	}
+
+	segment.Signature = ch.sign(fmt.Sprintf("%d|%d|%d|%s|%s", segment.FromSeq, segment.ToSeq, segment.Count, segment.FirstPrev, segment.LastHash))
+	if err := encoder.Encode(map[string]AuditSegment{"segment": segment}); err != nil {
+		return segment, err
+	}
+	return segment, writer.Flush()
+}
+
+var errStopIteration = errors.New("stop iteration")
+
+func (ch *AuditChain) seal(entry entities.AuditLog, chainSeq int64, prevHash string) (string, string) {
+	contentDigest := auditContentDigest(entry)
+	return contentDigest, ch.linkHash(prevHash, contentDigest, chainSeq)
+}
+
+// checkLink returns why an entry's link to the previous one doesn't verify, or an empty
+// string if it does. Its content is checked against content_digest by Verify.
+func (ch *AuditChain) checkLink(entry entities.AuditLog, expectedSeq int64, prevHash string) string {
+	if *entry.ChainSeq != expectedSeq {
+		return fmt.Sprintf("expected chain_seq %d, entry missing or reordered", expectedSeq)
+	}
+	if entry.PrevHash != prevHash {
+		return "prev_hash doesn't match the previous entry's hash"
+	}
+	if !hmac.Equal([]byte(ch.linkHash(prevHash, entry.ContentDigest, expectedSeq)), []byte(entry.Hash)) {
+		return "hash doesn't match, content_digest or hash was modified"
+	}
+	return ""
+}
+
+func (ch *AuditChain) linkHash(prevHash, contentDigest string, chainSeq int64) string {
+	return ch.sign(fmt.Sprintf("%s|%s|%d", prevHash, contentDigest, chainSeq))
+}
+
+func (ch *AuditChain) sign(message string) string {
+	mac := hmac.New(sha256.New, ch.key)
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func auditContentDigest(entry entities.AuditLog) string {
+	content, _ := json.Marshal(auditContent{
+		ID:        entry.ID,
+		ActorID:   entry.ActorID,
+		TargetID:  entry.TargetID,
+		Action:    entry.Action,
+		TableName: entry.TableName,
+		RowID:     entry.RowID,
+		OldData:   string(entry.OldData),
+		NewData:   string(entry.NewData),
+		IPAddress: entry.IPAddress,
+		UserAgent: entry.UserAgent,
+		CreatedAt: entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	digest := sha256.Sum256(content)
+	return hex.EncodeToString(digest[:])
+}
@@ -0,0 +1,302 @@
+package services_test
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/repositories"
+	"backendGoAuth/internal/services"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chainRepo keeps audit entries in a slice, sealing them like the Postgres repository.
+type chainRepo struct {
+	entries []entities.AuditLog
+}
+
+func (r *chainRepo) InsertAuditLog(exec repositories.DBExecutor, entry entities.AuditLog) (int, error) {
+	entry.ID = len(r.entries) + 1
+	entry.CreatedAt = time.Date(2026, 1, 1, 0, 0, entry.ID, 0, time.UTC)
+	r.entries = append(r.entries, entry)
+	return entry.ID, nil
+}
+
+func (r *chainRepo) QueryAuditLogs(filter repositories.AuditLogFilter) ([]entities.AuditLog, int, error) {
+	return nil, 0, nil
+}
+
+func (r *chainRepo) SealPendingAuditLogs(limit int, seal repositories.AuditSealFunc) (int, error) {
+	var lastSeq int64
+	var prevHash string
+	for _, entry := range r.entries {
+		if entry.ChainSeq != nil && *entry.ChainSeq > lastSeq {
+			lastSeq, prevHash = *entry.ChainSeq, entry.Hash
+		}
+	}
+
+	sealed := 0
+	for i := range r.entries {
+		entry := &r.entries[i]
+		if entry.ChainSeq != nil || sealed == limit {
+			continue
+		}
+		lastSeq++
+		chainSeq := lastSeq
+		entry.ContentDigest, entry.Hash = seal(*entry, chainSeq, prevHash)
+		entry.ChainSeq, entry.PrevHash = &chainSeq, prevHash
+		prevHash = entry.Hash
+		sealed++
+	}
+	return sealed, nil
+}
+
+func (r *chainRepo) IterateAuditChain(fromSeq, toSeq int64, fn func(entry entities.AuditLog) error) error {
+	var chain []entities.AuditLog
+	for _, entry := range r.entries {
+		if entry.ChainSeq != nil && *entry.ChainSeq >= fromSeq && (toSeq == 0 || *entry.ChainSeq <= toSeq) {
+			chain = append(chain, entry)
+		}
+	}
+	sort.Slice(chain, func(i, j int) bool { return *chain[i].ChainSeq < *chain[j].ChainSeq })
+
+	for _, entry := range chain {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *chainRepo) CountUnsealedAuditLogs() (int, error) {
+	count := 0
+	for _, entry := range r.entries {
+		if entry.ChainSeq == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// entry returns the stored entry with the given ID.
+func (r *chainRepo) entry(id int) *entities.AuditLog {
+	for i := range r.entries {
+		if r.entries[i].ID == id {
+			return &r.entries[i]
+		}
+	}
+	return nil
+}
+
+// redact erases an entry's connection details like a user erasure does, returning it as redacted.
+func (r *chainRepo) redact(id int) entities.AuditLog {
+	entry := r.entry(id)
+	redactedAt := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	entry.IPAddress, entry.UserAgent, entry.RedactedAt = "", "", &redactedAt
+	return *entry
+}
+
+// newSealedChain records and seals five entries.
+func newSealedChain(t *testing.T) (*chainRepo, *services.AuditService, *services.AuditChain) {
+	t.Helper()
+	repo := &chainRepo{}
+	audit := services.NewAuditService(repo)
+	chain, err := services.NewAuditChain(repo, []byte("test audit key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 5; i++ {
+		err := audit.Record(services.AuditEvent{
+			Actor:    services.AuditActor{UserID: 1, IPAddress: "203.0.113.7", UserAgent: "test"},
+			TargetID: i,
+			Action:   services.AuditActionUserUpdated,
+			After:    map[string]interface{}{"email": "user@example.com"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sealed, err := chain.Seal(); err != nil || sealed != 5 {
+		t.Fatalf("sealed %d entries (%v), want 5", sealed, err)
+	}
+	return repo, audit, chain
+}
+
+func TestAuditChainVerify(t *testing.T) {
+	tests := []struct {
+		name         string
+		tamper       func(t *testing.T, repo *chainRepo, audit *services.AuditService, chain *services.AuditChain)
+		wantVerified int
+		wantRedacted int
+		wantBrokenID int
+		wantReason   string
+	}{
+		{
+			name:         "valid chain",
+			tamper:       func(*testing.T, *chainRepo, *services.AuditService, *services.AuditChain) {},
+			wantVerified: 5,
+		},
+		{
+			name: "modified row",
+			tamper: func(t *testing.T, repo *chainRepo, _ *services.AuditService, _ *services.AuditChain) {
+				repo.entry(3).NewData = json.RawMessage(`{"email":"attacker@example.com"}`)
+			},
+			wantVerified: 2,
+			wantBrokenID: 3,
+			wantReason:   "entry was modified",
+		},
+		{
+			name: "deleted row",
+			tamper: func(t *testing.T, repo *chainRepo, _ *services.AuditService, _ *services.AuditChain) {
+				repo.entries = append(repo.entries[:2], repo.entries[3:]...)
+			},
+			wantVerified: 2,
+			wantBrokenID: 4,
+			wantReason:   "missing or reordered",
+		},
+		{
+			name: "reordered rows",
+			tamper: func(t *testing.T, repo *chainRepo, _ *services.AuditService, _ *services.AuditChain) {
+				second, third := repo.entry(2), repo.entry(3)
+				second.ChainSeq, third.ChainSeq = third.ChainSeq, second.ChainSeq
+			},
+			wantVerified: 1,
+			wantBrokenID: 3,
+			wantReason:   "prev_hash",
+		},
+		{
+			name: "redacted row with a sealed redaction record",
+			tamper: func(t *testing.T, repo *chainRepo, audit *services.AuditService, chain *services.AuditChain) {
+				redacted := []entities.AuditLog{repo.redact(2), repo.redact(4)}
+				if err := audit.RecordRedactions(nil, services.AuditActor{UserID: 1}, 2, redacted); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := chain.Seal(); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantVerified: 6,
+			wantRedacted: 2,
+		},
+		{
+			name: "redacted row without a redaction record",
+			tamper: func(t *testing.T, repo *chainRepo, _ *services.AuditService, _ *services.AuditChain) {
+				repo.redact(2)
+			},
+			wantVerified: 1,
+			wantBrokenID: 2,
+			wantReason:   "redaction record",
+		},
+		{
+			name: "redacted row modified after its redaction was recorded",
+			tamper: func(t *testing.T, repo *chainRepo, audit *services.AuditService, chain *services.AuditChain) {
+				if err := audit.RecordRedactions(nil, services.AuditActor{UserID: 1}, 2, []entities.AuditLog{repo.redact(2)}); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := chain.Seal(); err != nil {
+					t.Fatal(err)
+				}
+				repo.entry(2).NewData = json.RawMessage(`{"email":"attacker@example.com"}`)
+			},
+			wantVerified: 1,
+			wantBrokenID: 2,
+			wantReason:   "redaction record",
+		},
+		{
+			name: "redaction record not sealed yet",
+			tamper: func(t *testing.T, repo *chainRepo, audit *services.AuditService, _ *services.AuditChain) {
+				if err := audit.RecordRedactions(nil, services.AuditActor{UserID: 1}, 2, []entities.AuditLog{repo.redact(2)}); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantVerified: 1,
+			wantBrokenID: 2,
+			wantReason:   "redaction record",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo, audit, chain := newSealedChain(t)
+			test.tamper(t, repo, audit, chain)
+
+			result, err := chain.Verify()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Verified != test.wantVerified || result.Redacted != test.wantRedacted {
+				t.Errorf("got %d verified and %d redacted entries, want %d and %d", result.Verified, result.Redacted, test.wantVerified, test.wantRedacted)
+			}
+			switch {
+			case test.wantBrokenID == 0 && result.Broken != nil:
+				t.Errorf("got broken link %+v, want a valid chain", *result.Broken)
+			case test.wantBrokenID != 0 && result.Broken == nil:
+				t.Errorf("got a valid chain, want entry %d reported", test.wantBrokenID)
+			case test.wantBrokenID != 0 && (result.Broken.ID != test.wantBrokenID || !strings.Contains(result.Broken.Reason, test.wantReason)):
+				t.Errorf("got broken link %+v, want entry %d with a reason mentioning %q", *result.Broken, test.wantBrokenID, test.wantReason)
+			}
+		})
+	}
+}
+
+func TestAuditChainSealOnlySealsNewEntries(t *testing.T) {
+	repo, audit, chain := newSealedChain(t)
+	if sealed, err := chain.Seal(); err != nil || sealed != 0 {
+		t.Fatalf("sealed %d entries again (%v), want 0", sealed, err)
+	}
+
+	if err := audit.Record(services.AuditEvent{Actor: services.AuditActor{UserID: 1}, Action: services.AuditActionLogout}); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := chain.Verify(); err != nil || result.Verified != 5 || result.Pending != 1 {
+		t.Fatalf("got verification %+v (%v), want 5 verified and 1 pending", result, err)
+	}
+	if sealed, err := chain.Seal(); err != nil || sealed != 1 {
+		t.Fatalf("sealed %d entries (%v), want 1", sealed, err)
+	}
+	if last := repo.entry(6); *last.ChainSeq != 6 || last.PrevHash != repo.entry(5).Hash {
+		t.Fatalf("got entry 6 at chain_seq %d after %q, want it linked after entry 5", *last.ChainSeq, last.PrevHash)
+	}
+}
+
+func TestAuditChainExport(t *testing.T) {
+	repo, _, chain := newSealedChain(t)
+
+	var buf bytes.Buffer
+	segment, err := chain.Export(&buf, 2, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if segment.FromSeq != 2 || segment.ToSeq != 4 || segment.Count != 3 {
+		t.Fatalf("got segment %+v, want entries 2 to 4", segment)
+	}
+	if segment.FirstPrev != repo.entry(1).Hash || segment.LastHash != repo.entry(4).Hash || segment.Signature == "" {
+		t.Fatalf("got segment %+v, want it to link entry 1 to entry 4 and be signed", segment)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 4 || !strings.HasPrefix(lines[3], `{"segment":`) {
+		t.Fatalf("got export %q, want 3 entries and the segment summary", lines)
+	}
+
+	// The segment is signed with the chain's key
+	otherChain, err := services.NewAuditChain(repo, []byte("another key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSegment, err := otherChain.Export(&bytes.Buffer{}, 2, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otherSegment.Signature == segment.Signature {
+		t.Fatal("segments exported with different keys have the same signature")
+	}
+}
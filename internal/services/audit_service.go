@@ -0,0 +1,236 @@
+package services
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/repositories"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Audit actions recorded by the application
+const (
+	AuditActionRegister           = "REGISTER"
+	AuditActionLogin              = "LOGIN"
+	AuditActionLoginFailed        = "LOGIN_FAILED"
+	AuditActionLogout             = "LOGOUT"
+	AuditActionAccountLocked      = "ACCOUNT_LOCKED"
+	AuditActionSessionRevoked     = "SESSION_REVOKED"
+	AuditActionImpersonationStart = "IMPERSONATION_START"
+	AuditActionImpersonationStop  = "IMPERSONATION_STOP"
+	AuditActionUserUpdated        = "USER_UPDATED"
+	AuditActionUserDeleted        = "USER_DELETED"
+	AuditActionVendorApproved     = "VENDOR_APPROVED"
+	AuditActionVendorRejected     = "VENDOR_REJECTED"
+	AuditActionDataExported       = "DATA_EXPORTED"
+	AuditActionErasureRequested   = "ERASURE_REQUESTED"
+	AuditActionErasureCanceled    = "ERASURE_CANCELED"
+	AuditActionUserErased         = "USER_ERASED"
+	AuditActionAuditRedacted      = "AUDIT_REDACTED"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+)
+
+// sensitiveAuditFields are never written to the audit log.
+var sensitiveAuditFields = []string{"password", "token", "secret"}
+
+// AuditActor identifies who performed an audited action and from where.
+type AuditActor struct {
+	UserID    int
+	IPAddress string
+	UserAgent string
+}
+
+// AuditEvent is a structured event recorded in the audit log.
+// Before and After are diffed so only changed fields are stored.
+type AuditEvent struct {
+	Actor     AuditActor
+	TargetID  int
+	Action    string
+	TableName string
+	RowID     int
+	Before    interface{}
+	After     interface{}
+}
+
+// AuditService records and queries application-level audit events.
+type AuditService struct {
+	AuditRepo repositories.AuditRepositoryInterface
+}
+
+// NewAuditService creates a new instance of AuditService.
+func NewAuditService(auditRepo repositories.AuditRepositoryInterface) *AuditService {
+	return &AuditService{
+		AuditRepo: auditRepo,
+	}
+}
+
+// Record writes an audit event.
+func (s *AuditService) Record(event AuditEvent) error {
+	return s.RecordTx(nil, event)
+}
+
+// RecordTx writes an audit event with exec, so it is committed or rolled back along with
+// the change it describes. A nil exec writes it on its own.
+func (s *AuditService) RecordTx(exec repositories.DBExecutor, event AuditEvent) error {
+	oldData, newData, err := diffAuditData(event.Before, event.After)
+	if err != nil {
+		slog.Error("Error diffing audit data", "action", event.Action, "error", err)
+		return err
+	}
+
+	entry := entities.AuditLog{
+		UserID:    optionalID(event.Actor.UserID),
+		ActorID:   optionalID(event.Actor.UserID),
+		TargetID:  optionalID(event.TargetID),
+		Action:    event.Action,
+		TableName: event.TableName,
+		RowID:     optionalID(event.RowID),
+		OldData:   oldData,
+		NewData:   newData,
+		IPAddress: event.Actor.IPAddress,
+		UserAgent: event.Actor.UserAgent,
+	}
+	if entry.UserID == nil {
+		// Events without an actor (e.g. failed logins) are about their target
+		entry.UserID = entry.TargetID
+	}
+
+	_, err = s.AuditRepo.InsertAuditLog(exec, entry)
+	return err
+}
+
+// RecordRedactions writes an entry listing the content digest each redacted entry has after
+// redaction. Once the entry is sealed, the audit chain checks redacted entries against it.
+func (s *AuditService) RecordRedactions(exec repositories.DBExecutor, actor AuditActor, targetID int, redacted []entities.AuditLog) error {
+	if len(redacted) == 0 {
+		return nil
+	}
+
+	digests := make(map[string]string, len(redacted))
+	for _, entry := range redacted {
+		digests[strconv.Itoa(entry.ID)] = auditContentDigest(entry)
+	}
+	return s.RecordTx(exec, AuditEvent{
+		Actor:     actor,
+		TargetID:  targetID,
+		Action:    AuditActionAuditRedacted,
+		TableName: "user_audit_logs",
+		After:     auditRedactionRecord{Redacted: digests},
+	})
+}
+
+// RecordBestEffort writes an audit event, only logging failures. Use it where auditing
+// must not change the outcome of the request.
+func (s *AuditService) RecordBestEffort(event AuditEvent) {
+	if err := s.Record(event); err != nil {
+		slog.Error("Error recording audit event", "action", event.Action, "error", err)
+	}
+}
+
+// QueryAuditLogs returns a page of audit logs matching the request.
+func (s *AuditService) QueryAuditLogs(req models.AuditLogQuery) (models.AuditLogPage, error) {
+	page, pageSize := normalizePage(req.Page, req.PageSize, defaultAuditPageSize, maxAuditPageSize)
+
+	filter := repositories.AuditLogFilter{
+		ActorID:  req.ActorID,
+		TargetID: req.TargetID,
+		Action:   strings.ToUpper(req.Action),
+		Limit:    pageSize,
+		Offset:   (page - 1) * pageSize,
+	}
+	if req.From != nil {
+		filter.From = *req.From
+	}
+	if req.To != nil {
+		filter.To = *req.To
+	}
+
+	logs, total, err := s.AuditRepo.QueryAuditLogs(filter)
+	if err != nil {
+		return models.AuditLogPage{}, err
+	}
+
+	return models.AuditLogPage{
+		Items:    logs,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	}, nil
+}
+
+// diffAuditData returns the fields of before and after that differ, as JSON objects.
+// Values that aren't JSON objects are stored as-is.
+func diffAuditData(before, after interface{}) (json.RawMessage, json.RawMessage, error) {
+	beforeMap, beforeIsMap, err := toAuditMap(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterMap, afterIsMap, err := toAuditMap(after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if beforeIsMap && afterIsMap {
+		for key, value := range beforeMap {
+			if afterValue, ok := afterMap[key]; ok && reflect.DeepEqual(value, afterValue) {
+				delete(beforeMap, key)
+				delete(afterMap, key)
+			}
+		}
+	}
+
+	oldData, err := marshalAuditMap(beforeMap)
+	if err != nil {
+		return nil, nil, err
+	}
+	newData, err := marshalAuditMap(afterMap)
+	if err != nil {
+		return nil, nil, err
+	}
+	return oldData, newData, nil
+}
+
+func toAuditMap(value interface{}) (map[string]interface{}, bool, error) {
+	if value == nil {
+		return nil, false, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return map[string]interface{}{"value": value}, false, nil
+	}
+	for key := range result {
+		for _, sensitive := range sensitiveAuditFields {
+			if strings.Contains(strings.ToLower(key), sensitive) {
+				delete(result, key)
+			}
+		}
+	}
+	return result, true, nil
+}
+
+func marshalAuditMap(value map[string]interface{}) (json.RawMessage, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(value)
+}
+
+func optionalID(id int) *int {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
@@ -4,70 +4,106 @@ package services
 import (
 	"backendGoAuth/internal/entities"
 	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/logging"
+	"backendGoAuth/internal/metrics"
 	"backendGoAuth/internal/models"
 	"backendGoAuth/internal/repositories"
+	"backendGoAuth/internal/tracing"
 	"backendGoAuth/internal/utils"
+	"context"
+	"database/sql"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
-	"log"
 	"strings"
 )
 
+// DefaultUserRole is the role given to newly registered users, letting them browse and place orders.
+const DefaultUserRole = "Customer"
+
 // AuthService provides authentication-related services.
 type AuthService struct {
-	UserRepo       *repositories.UserRepository
-	SessionService *SessionService // Corrected reference
+	UserRepo         repositories.UserRepositoryInterface
+	SessionService   *SessionService // Corrected reference
+	AuditService     *AuditService
+	Outbox           repositories.OutboxRepositoryInterface
+	Tokens           *utils.TokenManager
+	Metrics          *metrics.Metrics
+	MaxLoginAttempts int // failed logins before the account is locked
 }
 
 // NewAuthService creates a new instance of AuthService.
-func NewAuthService(userRepo *repositories.UserRepository, sessionService *SessionService) *AuthService {
+func NewAuthService(userRepo repositories.UserRepositoryInterface, sessionService *SessionService, auditService *AuditService, outbox repositories.OutboxRepositoryInterface, tokens *utils.TokenManager, m *metrics.Metrics) *AuthService {
 	return &AuthService{
-		UserRepo:       userRepo, // Initialize UserRepo here
-		SessionService: sessionService,
+		UserRepo:         userRepo, // Initialize UserRepo here
+		SessionService:   sessionService,
+		AuditService:     auditService,
+		Outbox:           outbox,
+		Tokens:           tokens,
+		Metrics:          m,
+		MaxLoginAttempts: 5,
 	}
 }
 
 // RegisterUser registers a new user with the provided details.
-func (svc *AuthService) RegisterUser(req models.RegistrationRequest, ipAddress, browser, device string) (models.AuthResponse, error) {
+func (svc *AuthService) RegisterUser(ctx context.Context, req models.RegistrationRequest, ipAddress, browser, device string) (response models.AuthResponse, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.RegisterUser")
+	defer func() { tracing.End(span, err) }()
+
 	// Check if username already exists
-	exists, err := svc.UserRepo.UserExistsByUsername(req.Username)
+	exists, err := svc.UserRepo.UserExistsByUsername(ctx, req.Username)
 	if err != nil {
 		return models.AuthResponse{}, err
 	}
 	if exists {
-		return models.AuthResponse{}, goAuthException.NewCustomError(goAuthException.BadRequestCode, goAuthException.UsernameExistsMessage)
+		return models.AuthResponse{}, goAuthException.NewCustomError(goAuthException.BadRequestCode, goAuthException.UsernameExistsMessage).WithErrorCode(goAuthException.ErrorCodeUsernameTaken)
 	}
 
 	// Check if email already exists
-	existsEmail, err := svc.UserRepo.UserExistsByEmail(req.Email)
+	existsEmail, err := svc.UserRepo.UserExistsByEmail(ctx, req.Email)
 	if err != nil {
 		return models.AuthResponse{}, err
 	}
 	if existsEmail {
-		return models.AuthResponse{}, goAuthException.NewCustomError(goAuthException.BadRequestCode, goAuthException.EmailExistsMessage)
+		return models.AuthResponse{}, goAuthException.NewCustomError(goAuthException.BadRequestCode, goAuthException.EmailExistsMessage).WithErrorCode(goAuthException.ErrorCodeEmailTaken)
 	}
 
 	// Hash the password
 	hashedPassword, err := hashPassword(req.Password)
 	if err != nil {
-		return models.AuthResponse{}, goAuthException.NewCustomError(goAuthException.Teapot, goAuthException.HashingError)
+		return models.AuthResponse{}, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, goAuthException.HashingError)
 	}
 
-	// Create a new user
-	user, err := svc.createUser(req.Username, hashedPassword, req.Email)
+	// Create the user with the default role, so there are no users without one
+	var user models.UserData
+	err = repositories.RunInTx(svc.UserRepo.DB(), func(tx *sql.Tx) error {
+		if user, err = svc.createUser(ctx, tx, req.Username, hashedPassword, req.Email); err != nil {
+			return err
+		}
+		return svc.UserRepo.AssignRole(ctx, tx, user.ID, DefaultUserRole)
+	})
 	if err != nil {
-		return models.AuthResponse{}, goAuthException.NewCustomError(goAuthException.Teapot, goAuthException.UserCreationError)
+		return models.AuthResponse{}, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, goAuthException.UserCreationError)
 	}
+	svc.Metrics.UserRegistered()
+
+	svc.AuditService.RecordBestEffort(AuditEvent{
+		Actor:     AuditActor{UserID: user.ID, IPAddress: ipAddress, UserAgent: describeClient(browser, device)},
+		TargetID:  user.ID,
+		Action:    AuditActionRegister,
+		TableName: "users",
+		RowID:     user.ID,
+		After:     user,
+	})
 
 	// Insert session into the database
-	session, err := svc.SessionService.InsertSession(user.ID, ipAddress, browser, device)
+	session, err := svc.SessionService.InsertSession(ctx, user.ID, ipAddress, browser, device)
 	if err != nil {
-		return models.AuthResponse{}, goAuthException.NewCustomError(goAuthException.Teapot, goAuthException.SessionInsertionError)
+		return models.AuthResponse{}, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, goAuthException.SessionInsertionError)
 	}
 
 	// Generate JWT with session ID included in the claims
 	//todo also here should set cookie
-	_, err = utils.GenerateJWT(map[string]interface{}{
+	_, err = svc.Tokens.GenerateJWT(map[string]interface{}{
 		"user_id":    user.ID,
 		"session_id": session.ID, // Include session ID in the token claims
 	}, "access", session.ID)
@@ -78,54 +114,79 @@ func (svc *AuthService) RegisterUser(req models.RegistrationRequest, ipAddress,
 }
 
 // AuthenticateUser authenticates a user based on the provided credentials.
-func (svc *AuthService) AuthenticateUser(identifier, password, ipAddress, browser, device string, c *gin.Context) (models.AuthResponse, error) {
+func (svc *AuthService) AuthenticateUser(ctx context.Context, identifier, password, ipAddress, browser, device string, c *gin.Context) (response models.AuthResponse, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.AuthenticateUser")
+	defer func() { tracing.End(span, err) }()
+
 	var user *entities.User
-	var err error
 
 	if strings.Contains(identifier, "@") {
-		user, err = svc.UserRepo.GetUserByEmail(identifier)
+		user, err = svc.UserRepo.GetUserByEmail(ctx, identifier)
 		if err != nil {
 			return models.AuthResponse{}, goAuthException.NewCustomError(goAuthException.NotFoundCode, goAuthException.UsernameCheckError)
 		}
 	} else {
-		user, err = svc.UserRepo.GetUserByUsername(identifier)
+		user, err = svc.UserRepo.GetUserByUsername(ctx, identifier)
 		if err != nil {
 			return models.AuthResponse{}, goAuthException.NewCustomError(goAuthException.NotFoundCode, goAuthException.EmailCheckError)
 		}
 	}
 
+	actor := AuditActor{IPAddress: ipAddress, UserAgent: describeClient(browser, device)}
+
 	if user == nil {
-		return models.AuthResponse{}, goAuthException.NewCustomError(goAuthException.NotFoundCode, "User doesn't exist")
+		svc.recordLoginFailure(ctx, nil, identifier, "unknown_user", actor)
+		return models.AuthResponse{}, goAuthException.NewCustomError(goAuthException.NotFoundCode, "User doesn't exist").WithErrorCode(goAuthException.ErrorCodeUserNotFound)
 	}
 
-	// Compare hashed passwords
+	// Compare hashed passwords first, so only the right password reveals that the account is locked
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
-		log.Printf("Password comparison failed for user: %s\n", identifier)
-		return models.AuthResponse{}, goAuthException.NewCustomError(goAuthException.UnauthorizedCode, "Invalid credentials")
+		logging.FromContext(ctx).Info("Password comparison failed for user", "identifier", identifier)
+		svc.recordLoginFailure(ctx, user, identifier, "invalid_password", actor)
+		return models.AuthResponse{}, goAuthException.NewCustomError(goAuthException.UnauthorizedCode, "Invalid credentials").WithErrorCode(goAuthException.ErrorCodeInvalidCredentials)
+	}
+
+	if user.IsBlocked {
+		svc.recordLoginFailure(ctx, user, identifier, "account_locked", actor)
+		return models.AuthResponse{}, goAuthException.NewCustomError(goAuthException.ForbiddenCode, "Account locked").WithErrorCode(goAuthException.ErrorCodeAccountLocked)
 	}
 
-	session, err := svc.SessionService.InsertSession(user.ID, ipAddress, browser, device)
+	if err := svc.UserRepo.RecordSuccessfulLogin(ctx, user.ID); err != nil {
+		return models.AuthResponse{}, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, goAuthException.InternalErrorMessage)
+	}
+
+	session, err := svc.SessionService.InsertSession(ctx, user.ID, ipAddress, browser, device)
 	if err != nil {
-		return models.AuthResponse{}, goAuthException.NewCustomError(goAuthException.InternalServerErrorCode, goAuthException.SessionInsertionError)
+		return models.AuthResponse{}, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, goAuthException.SessionInsertionError)
 	}
 
+	svc.Metrics.LoginSucceeded()
+	actor.UserID = user.ID
+	svc.AuditService.RecordBestEffort(AuditEvent{
+		Actor:     actor,
+		TargetID:  user.ID,
+		Action:    AuditActionLogin,
+		TableName: "user_sessions",
+		RowID:     session.ID,
+	})
+
 	// Generate short-lived JWT token with the user's ID
-	accessToken, err := utils.GenerateJWT(map[string]interface{}{
+	accessToken, err := svc.Tokens.GenerateJWT(map[string]interface{}{
 		"user_id":    user.ID,
 		"session_id": session.ID, // Include session ID in the token claims
 	}, "access", session.ID)
 	if err != nil {
-		return models.AuthResponse{}, goAuthException.NewCustomError(goAuthException.Teapot, goAuthException.TokenGenerationError)
+		return models.AuthResponse{}, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, goAuthException.TokenGenerationError)
 	}
 
 	// Store session in the database with the refresh token, if applicable
-	//refreshToken, err := utils.GenerateJWT(map[string]interface{}{
+	//refreshToken, err := svc.Tokens.GenerateJWT(map[string]interface{}{
 	//	"user_id":    user.ID,
 	//	"session_id": session.ID, // Include session ID in the token claims
 	//}, "refresh", session.ID)
 	//if err != nil {
-	//	return models.AuthResponse{}, goAuthException.NewCustomError(goAuthException.Teapot, goAuthException.TokenGenerationError)
+	//	return models.AuthResponse{}, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, goAuthException.TokenGenerationError)
 	//}
 
 	authResponse := models.AuthResponse{
@@ -136,16 +197,86 @@ func (svc *AuthService) AuthenticateUser(identifier, password, ipAddress, browse
 		},
 	}
 
-	utils.SetJWTTokenCookies(c, accessToken)
+	svc.Tokens.SetJWTTokenCookies(c, accessToken)
 
 	return authResponse, nil
 }
 
+// recordLoginFailure audits a failed login and emits its security events. Wrong passwords
+// count towards locking the account, in the same transaction as the events.
+func (svc *AuthService) recordLoginFailure(ctx context.Context, user *entities.User, identifier, reason string, actor AuditActor) {
+	svc.Metrics.LoginFailed(reason)
+	event := map[string]interface{}{
+		"identifier": identifier,
+		"reason":     reason,
+		"ip_address": actor.IPAddress,
+		"user_agent": actor.UserAgent,
+	}
+	auditEvent := AuditEvent{
+		Actor:  actor,
+		Action: AuditActionLoginFailed,
+		After:  map[string]string{"identifier": identifier, "reason": reason},
+	}
+
+	if user == nil || reason != "invalid_password" {
+		if user != nil {
+			event["user_id"] = user.ID
+			auditEvent.TargetID = user.ID
+		}
+		if err := svc.Outbox.Enqueue(nil, EventUserLoginFailed, event); err != nil {
+			logging.FromContext(ctx).Error("Error recording failed login event", "error", err)
+		}
+		svc.AuditService.RecordBestEffort(auditEvent)
+		return
+	}
+
+	event["user_id"] = user.ID
+	auditEvent.TargetID = user.ID
+	locked := false
+	err := repositories.RunInTx(svc.UserRepo.DB(), func(tx *sql.Tx) error {
+		attempts, lockedNow, err := svc.UserRepo.RecordFailedLogin(ctx, tx, user.ID, svc.MaxLoginAttempts)
+		if err != nil {
+			return err
+		}
+		event["attempts"] = attempts
+		if err := svc.Outbox.Enqueue(tx, EventUserLoginFailed, event); err != nil {
+			return err
+		}
+
+		locked = lockedNow
+		if !lockedNow {
+			return nil
+		}
+		return svc.Outbox.Enqueue(tx, EventUserLocked, map[string]interface{}{
+			"user_id":    user.ID,
+			"attempts":   attempts,
+			"ip_address": actor.IPAddress,
+		})
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("Error recording failed login", "error", err)
+	}
+
+	svc.AuditService.RecordBestEffort(auditEvent)
+	if locked {
+		svc.Metrics.AccountLocked()
+		svc.AuditService.RecordBestEffort(AuditEvent{
+			Actor:     actor,
+			TargetID:  user.ID,
+			Action:    AuditActionAccountLocked,
+			TableName: "users",
+			RowID:     user.ID,
+			Before:    map[string]bool{"is_blocked": false},
+			After:     map[string]bool{"is_blocked": true},
+		})
+	}
+}
+
 // createUser creates a new user in the database.
-func (svc *AuthService) createUser(username, password, email string) (models.UserData, error) {
-	userID, err := svc.UserRepo.InsertUser(username, password, email)
+func (svc *AuthService) createUser(ctx context.Context, exec repositories.DBExecutor, username, password, email string) (models.UserData, error) {
+	userID, err := svc.UserRepo.InsertUser(ctx, exec, username, password, email)
 	if err != nil {
-		return models.UserData{}, goAuthException.NewCustomError(goAuthException.InternalServerErrorCode, goAuthException.UserCreationError)
+		return models.UserData{}, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, goAuthException.UserCreationError)
 	}
 
 	return models.UserData{
@@ -158,17 +289,28 @@ func (svc *AuthService) createUser(username, password, email string) (models.Use
 func hashPassword(password string) (string, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		return "", goAuthException.NewCustomError(goAuthException.InternalServerErrorCode, goAuthException.SessionInsertionError)
+		return "", goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, goAuthException.SessionInsertionError)
 	}
 	return string(hashedPassword), nil
 }
 
-func (svc *AuthService) RevokeSession(sessionID string) error {
+func (svc *AuthService) RevokeSession(ctx context.Context, sessionID string, actor AuditActor) (err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.RevokeSession")
+	defer func() { tracing.End(span, err) }()
+
 	// Call the relevant method to revoke the session in your service layer
-	err := svc.SessionService.RevokeSession(sessionID)
+	err = svc.SessionService.RevokeSession(ctx, sessionID, actor)
 	if err != nil {
 		// Handle any errors that occur during session revocation
 		return err
 	}
 	return nil
 }
+
+// describeClient summarizes the parsed user agent for audit logs.
+func describeClient(browser, device string) string {
+	if device == "" {
+		return browser
+	}
+	return browser + " (" + device + ")"
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/repositories"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+)
+
+// MaxCartItemQuantity is the most units of a single product a cart may hold.
+const MaxCartItemQuantity = 99
+
+// CartService manages the current user's shopping cart.
+type CartService struct {
+	CartRepo    repositories.CartRepositoryInterface
+	ProductRepo repositories.ProductRepositoryInterface
+}
+
+// NewCartService creates a new instance of CartService.
+func NewCartService(cartRepo repositories.CartRepositoryInterface, productRepo repositories.ProductRepositoryInterface) *CartService {
+	return &CartService{
+		CartRepo:    cartRepo,
+		ProductRepo: productRepo,
+	}
+}
+
+// GetCart returns the user's cart, creating an empty one on first use.
+func (s *CartService) GetCart(userID int) (*entities.Cart, error) {
+	return s.updateCart(userID, nil, func(tx *sql.Tx, cart *entities.Cart) (bool, error) {
+		return false, nil
+	})
+}
+
+// AddItem adds units of a product to the cart, on top of any already in it.
+// If expectedVersion is set, the update is rejected when the cart changed since the client read it.
+func (s *CartService) AddItem(userID int, req models.CartItemRequest, expectedVersion *int) (*entities.Cart, error) {
+	if err := s.validateItem(req.ProductID, req.Quantity); err != nil {
+		return nil, err
+	}
+
+	return s.updateCart(userID, expectedVersion, func(tx *sql.Tx, cart *entities.Cart) (bool, error) {
+		current, err := s.CartRepo.GetItemQuantity(tx, cart.ID, req.ProductID)
+		if err != nil {
+			return false, err
+		}
+		if current+req.Quantity > MaxCartItemQuantity {
+			return false, goAuthException.NewCustomError(goAuthException.BadRequestCode,
+				fmt.Sprintf("A cart can hold at most %d units of a product", MaxCartItemQuantity))
+		}
+		return true, s.CartRepo.SetItemQuantity(tx, cart.ID, req.ProductID, current+req.Quantity)
+	})
+}
+
+// UpdateItem sets the quantity of a product in the cart. A quantity of 0 removes it.
+func (s *CartService) UpdateItem(userID, productID, quantity int, expectedVersion *int) (*entities.Cart, error) {
+	if quantity == 0 {
+		return s.RemoveItem(userID, productID, expectedVersion)
+	}
+	if err := s.validateItem(productID, quantity); err != nil {
+		return nil, err
+	}
+
+	return s.updateCart(userID, expectedVersion, func(tx *sql.Tx, cart *entities.Cart) (bool, error) {
+		return true, s.CartRepo.SetItemQuantity(tx, cart.ID, productID, quantity)
+	})
+}
+
+// RemoveItem removes a product from the cart.
+func (s *CartService) RemoveItem(userID, productID int, expectedVersion *int) (*entities.Cart, error) {
+	return s.updateCart(userID, expectedVersion, func(tx *sql.Tx, cart *entities.Cart) (bool, error) {
+		removed, err := s.CartRepo.RemoveItem(tx, cart.ID, productID)
+		if err != nil {
+			return false, err
+		}
+		if !removed {
+			return false, goAuthException.NewCustomError(goAuthException.NotFoundCode, "Product not in cart")
+		}
+		return true, nil
+	})
+}
+
+// ClearCart removes every item from the cart.
+func (s *CartService) ClearCart(userID int, expectedVersion *int) (*entities.Cart, error) {
+	return s.updateCart(userID, expectedVersion, func(tx *sql.Tx, cart *entities.Cart) (bool, error) {
+		return true, s.CartRepo.ClearCart(tx, cart.ID)
+	})
+}
+
+// MergeGuestCart adds the items of a guest cart to the user's cart after login. Quantities of
+// products in both carts are summed and capped; products that no longer exist are skipped.
+func (s *CartService) MergeGuestCart(userID int, items []models.CartItemRequest) (*entities.Cart, error) {
+	quantities := make(map[int]int, len(items))
+	var productIDs []int
+	for _, item := range items {
+		if item.ProductID <= 0 || item.Quantity <= 0 {
+			continue
+		}
+		if _, seen := quantities[item.ProductID]; !seen {
+			productIDs = append(productIDs, item.ProductID)
+		}
+		quantities[item.ProductID] += item.Quantity
+	}
+
+	return s.updateCart(userID, nil, func(tx *sql.Tx, cart *entities.Cart) (bool, error) {
+		changed := false
+		for _, productID := range productIDs {
+			product, err := s.ProductRepo.GetProductByID(productID)
+			if err != nil {
+				return false, err
+			}
+			if product == nil {
+				slog.Warn("Skipping unknown product in guest cart for user", "product_id", productID, "user_id", userID)
+				continue
+			}
+
+			current, err := s.CartRepo.GetItemQuantity(tx, cart.ID, productID)
+			if err != nil {
+				return false, err
+			}
+			quantity := current + quantities[productID]
+			if quantity > MaxCartItemQuantity {
+				quantity = MaxCartItemQuantity
+			}
+			if quantity == current {
+				continue
+			}
+			if err := s.CartRepo.SetItemQuantity(tx, cart.ID, productID, quantity); err != nil {
+				return false, err
+			}
+			changed = true
+		}
+		return changed, nil
+	})
+}
+
+// updateCart runs fn on the user's cart while it is locked, so concurrent requests (e.g. from
+// two tabs) apply one after the other, then returns the cart priced at the current product prices.
+func (s *CartService) updateCart(userID int, expectedVersion *int, fn func(tx *sql.Tx, cart *entities.Cart) (bool, error)) (*entities.Cart, error) {
+	var cart entities.Cart
+	err := repositories.RunInTx(s.CartRepo.DB(), func(tx *sql.Tx) error {
+		var err error
+		cart, err = s.CartRepo.LockCart(tx, userID)
+		if err != nil {
+			return err
+		}
+		if expectedVersion != nil && *expectedVersion != cart.Version {
+			return goAuthException.NewCustomError(goAuthException.ConflictCode, "Cart was modified by another request").WithErrorCode(goAuthException.ErrorCodeCartModified)
+		}
+
+		changed, err := fn(tx, &cart)
+		if err != nil {
+			return err
+		}
+		if changed {
+			if err := s.CartRepo.TouchCart(tx, &cart); err != nil {
+				return err
+			}
+		}
+
+		cart.Items, err = s.CartRepo.GetCartItems(tx, cart.ID)
+		return err
+	})
+	if err != nil {
+		if _, ok := err.(*goAuthException.CustomError); ok {
+			return nil, err
+		}
+		slog.Error("Error updating cart for user", "user_id", userID, "error", err)
+		return nil, goAuthException.NewCustomError(goAuthException.InternalServerErrorCode, "Failed to update cart")
+	}
+
+	cart.Total = cartTotal(cart.Items)
+	return &cart, nil
+}
+
+func (s *CartService) validateItem(productID, quantity int) error {
+	if quantity < 1 || quantity > MaxCartItemQuantity {
+		return goAuthException.NewCustomError(goAuthException.BadRequestCode,
+			fmt.Sprintf("Quantity must be between 1 and %d", MaxCartItemQuantity))
+	}
+
+	product, err := s.ProductRepo.GetProductByID(productID)
+	if err != nil {
+		return goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve product")
+	}
+	if product == nil {
+		return goAuthException.NewCustomError(goAuthException.NotFoundCode, "Product not found")
+	}
+	return nil
+}
+
+// cartTotal sums the line totals in cents to avoid accumulating float rounding errors.
+func cartTotal(items []entities.CartItem) float64 {
+	var cents int64
+	for _, item := range items {
+		cents += int64(math.Round(item.LineTotal * 100))
+	}
+	return float64(cents) / 100
+}
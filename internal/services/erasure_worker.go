@@ -0,0 +1,65 @@
+package services
+
+import (
+	"backendGoAuth/internal/logging"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// erasureBatchSize bounds how many users are erased per pass.
+const erasureBatchSize = 100
+
+// ErasureWorker erases users whose erasure grace period is over.
+type ErasureWorker struct {
+	PrivacyService *PrivacyService
+	Interval       time.Duration
+}
+
+// NewErasureWorker creates a new instance of ErasureWorker.
+func NewErasureWorker(privacyService *PrivacyService, interval time.Duration) *ErasureWorker {
+	return &ErasureWorker{
+		PrivacyService: privacyService,
+		Interval:       interval,
+	}
+}
+
+// EraseDue erases one batch of users whose grace period is over and returns how many it erased.
+// Users with orders still being fulfilled are retried on a later pass.
+func (w *ErasureWorker) EraseDue() (int, error) {
+	userIDs, err := w.PrivacyService.PrivacyRepo.GetDueErasures(erasureBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	erased := 0
+	for _, userID := range userIDs {
+		if err := w.PrivacyService.EraseUser(AuditActor{}, userID); err != nil {
+			slog.Error("Error erasing user", "user_id", userID, "error", err)
+			continue
+		}
+		erased++
+	}
+	return erased, nil
+}
+
+// Run erases due users every Interval until ctx is cancelled.
+func (w *ErasureWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			erased, err := w.EraseDue()
+			if err != nil {
+				logging.FromContext(ctx).Error("Error erasing users", "error", err)
+			}
+			if erased > 0 {
+				logging.FromContext(ctx).Info("Erased users whose erasure grace period is over", "count", erased)
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/logging"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/repositories"
+	"backendGoAuth/internal/utils"
+	"context"
+	"strconv"
+	"time"
+)
+
+// ImpersonationService lets support admins log in as another user.
+type ImpersonationService struct {
+	UserRepo       repositories.UserRepositoryInterface
+	SessionService *SessionService
+	AuditService   *AuditService
+	Tokens         *utils.TokenManager
+}
+
+// NewImpersonationService creates a new instance of ImpersonationService.
+func NewImpersonationService(userRepo repositories.UserRepositoryInterface, sessionService *SessionService, auditService *AuditService, tokens *utils.TokenManager) *ImpersonationService {
+	return &ImpersonationService{
+		UserRepo:       userRepo,
+		SessionService: sessionService,
+		AuditService:   auditService,
+		Tokens:         tokens,
+	}
+}
+
+// StartImpersonation creates an impersonation session for the target user and
+// returns a short-lived token carrying the impersonator in its act claim.
+func (svc *ImpersonationService) StartImpersonation(ctx context.Context, impersonator AuditActor, targetUserID int, browser, device string) (string, models.UserData, error) {
+	impersonatorID := impersonator.UserID
+	if impersonatorID == targetUserID {
+		return "", models.UserData{}, goAuthException.NewCustomError(goAuthException.BadRequestCode, "Cannot impersonate yourself")
+	}
+
+	user, err := svc.UserRepo.GetUserByID(ctx, targetUserID)
+	if err != nil {
+		return "", models.UserData{}, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, goAuthException.InternalErrorMessage)
+	}
+	if user == nil || !user.IsActive {
+		return "", models.UserData{}, goAuthException.NewCustomError(goAuthException.NotFoundCode, "User doesn't exist").WithErrorCode(goAuthException.ErrorCodeUserNotFound)
+	}
+
+	session, err := svc.SessionService.InsertImpersonationSession(ctx, user.ID, impersonatorID, impersonator.IPAddress, browser, device)
+	if err != nil {
+		return "", models.UserData{}, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, goAuthException.SessionInsertionError)
+	}
+
+	// Impersonation must never happen without an audit trail
+	err = svc.AuditService.Record(AuditEvent{
+		Actor:     impersonator,
+		TargetID:  user.ID,
+		Action:    AuditActionImpersonationStart,
+		TableName: "user_sessions",
+		RowID:     session.ID,
+	})
+	if err != nil {
+		if revokeErr := svc.SessionService.RevokeSession(ctx, strconv.Itoa(session.ID), impersonator); revokeErr != nil {
+			logging.FromContext(ctx).Error("Error revoking unaudited impersonation session", "session_id", session.ID, "error", revokeErr)
+		}
+		return "", models.UserData{}, goAuthException.NewCustomError(goAuthException.InternalServerErrorCode, goAuthException.InternalErrorMessage)
+	}
+
+	token, err := svc.Tokens.GenerateJWT(map[string]interface{}{
+		"user_id":         user.ID,
+		"session_id":      session.ID,
+		"impersonator_id": impersonatorID,
+	}, "impersonation", session.ID)
+	if err != nil {
+		return "", models.UserData{}, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, goAuthException.TokenGenerationError)
+	}
+
+	return token, models.UserData{
+		ID:       user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+	}, nil
+}
+
+// StopImpersonation revokes the impersonation session and records it in the audit log.
+func (svc *ImpersonationService) StopImpersonation(ctx context.Context, impersonator AuditActor, userID, sessionID int) error {
+	if err := svc.SessionService.RevokeSession(ctx, strconv.Itoa(sessionID), impersonator); err != nil {
+		return goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to revoke impersonation session")
+	}
+
+	err := svc.AuditService.Record(AuditEvent{
+		Actor:     impersonator,
+		TargetID:  userID,
+		Action:    AuditActionImpersonationStop,
+		TableName: "user_sessions",
+		RowID:     sessionID,
+	})
+	if err != nil {
+		return goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, goAuthException.InternalErrorMessage)
+	}
+	return nil
+}
+
+// ExpireImpersonations ends the impersonation sessions whose tokens have expired, recording
+// each in the audit log like an explicit stop. It returns how many it ended.
+func (svc *ImpersonationService) ExpireImpersonations(ctx context.Context) (int, error) {
+	sessions, err := svc.SessionService.ExpireImpersonationSessions(ctx, svc.Tokens.ImpersonationDuration)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, session := range sessions {
+		svc.AuditService.RecordBestEffort(AuditEvent{
+			Actor:     AuditActor{UserID: *session.ImpersonatorID},
+			TargetID:  session.UserID,
+			Action:    AuditActionImpersonationStop,
+			TableName: "user_sessions",
+			RowID:     session.ID,
+			After:     map[string]string{"reason": "expired"},
+		})
+	}
+	return len(sessions), nil
+}
+
+// Run ends expired impersonation sessions every interval until ctx is cancelled.
+func (svc *ImpersonationService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := svc.ExpireImpersonations(ctx); err != nil {
+				logging.FromContext(ctx).Error("Error ending expired impersonation sessions", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/models"
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// AuthServiceInterface registers users and logs them in.
+type AuthServiceInterface interface {
+	RegisterUser(ctx context.Context, req models.RegistrationRequest, ipAddress, browser, device string) (models.AuthResponse, error)
+	AuthenticateUser(ctx context.Context, identifier, password, ipAddress, browser, device string, c *gin.Context) (models.AuthResponse, error)
+	RevokeSession(ctx context.Context, sessionID string, actor AuditActor) error
+}
+
+// SessionServiceInterface manages the sessions users are logged in with.
+type SessionServiceInterface interface {
+	InsertSession(ctx context.Context, userID int, ipAddress, browser, device string) (*entities.Session, error)
+	GetActiveSessions(ctx context.Context, userID int) ([]models.SessionResponse, error)
+	RevokeCurrentSessionToken(ctx context.Context, tokenString string, actor AuditActor) error
+	RevokeSession(ctx context.Context, sessionID string, actor AuditActor) error
+	GetUserIDFromTokenOrSource(c *gin.Context) (int, error)
+}
+
+// AuditServiceInterface records audit events and queries the audit log.
+type AuditServiceInterface interface {
+	Record(event AuditEvent) error
+	RecordBestEffort(event AuditEvent)
+	QueryAuditLogs(req models.AuditLogQuery) (models.AuditLogPage, error)
+}
+
+// PermissionServiceInterface resolves the permissions of users.
+type PermissionServiceInterface interface {
+	HasPermission(userID int, permission string) (bool, error)
+	GetPermissions(userID int) ([]string, error)
+	InvalidateUser(userID int)
+}
+
+// ImpersonationServiceInterface lets support staff act as other users.
+type ImpersonationServiceInterface interface {
+	StartImpersonation(ctx context.Context, impersonator AuditActor, targetUserID int, browser, device string) (string, models.UserData, error)
+	StopImpersonation(ctx context.Context, impersonator AuditActor, userID, sessionID int) error
+	ExpireImpersonations(ctx context.Context) (int, error)
+}
+
+// WebhookServiceInterface manages webhook endpoints and their deliveries.
+type WebhookServiceInterface interface {
+	RegisterEndpoint(req models.WebhookEndpointRequest, createdBy int) (models.WebhookEndpointResponse, error)
+	GetEndpoints() ([]entities.WebhookEndpoint, error)
+	DeleteEndpoint(endpointID int) error
+	GetDeliveries(status string) ([]entities.WebhookDelivery, error)
+	ReplayDelivery(deliveryID int64) error
+}
+
+// ProductServiceInterface manages and searches the catalog.
+type ProductServiceInterface interface {
+	SearchProducts(req models.ProductSearchQuery) (models.ProductSearchPage, error)
+	SuggestProducts(input string) ([]string, error)
+	ListProducts(req models.ProductQuery) (models.ProductPage, error)
+	GetProduct(productID int) (*entities.Product, error)
+	GetCategories() ([]entities.Category, error)
+	CreateProduct(userID int, req models.ProductRequest) (*entities.Product, error)
+	UpdateProduct(userID, productID int, req models.ProductRequest) (*entities.Product, error)
+	DeleteProduct(userID, productID int) error
+}
+
+// InventoryServiceInterface manages the stock of products.
+type InventoryServiceInterface interface {
+	SetStock(userID, productID int, req models.StockRequest) (*entities.Product, error)
+	GetLowStockProducts(vendorID int) ([]entities.Product, error)
+}
+
+// CartServiceInterface manages shopping carts.
+type CartServiceInterface interface {
+	GetCart(userID int) (*entities.Cart, error)
+	AddItem(userID int, req models.CartItemRequest, expectedVersion *int) (*entities.Cart, error)
+	UpdateItem(userID, productID, quantity int, expectedVersion *int) (*entities.Cart, error)
+	RemoveItem(userID, productID int, expectedVersion *int) (*entities.Cart, error)
+	ClearCart(userID int, expectedVersion *int) (*entities.Cart, error)
+	MergeGuestCart(userID int, items []models.CartItemRequest) (*entities.Cart, error)
+}
+
+// AddressServiceInterface manages address books.
+type AddressServiceInterface interface {
+	GetAddresses(userID int) ([]entities.Address, error)
+	GetAddress(userID, addressID int) (*entities.Address, error)
+	CreateAddress(userID int, req models.AddressRequest) (*entities.Address, error)
+	UpdateAddress(userID, addressID int, req models.AddressRequest) (*entities.Address, error)
+	DeleteAddress(userID, addressID int) (bool, error)
+}
+
+// OrderServiceInterface places orders and moves them through their statuses.
+type OrderServiceInterface interface {
+	Checkout(userID int, req models.CheckoutRequest) (*entities.Order, error)
+	GetOrderHistory(userID int, req models.OrderQuery) (models.OrderPage, error)
+	GetVendorOrders(vendorID int, req models.OrderQuery) (models.OrderPage, error)
+	GetOrder(userID, orderID int) (*entities.Order, error)
+	TransitionOrder(userID, orderID int, status string) (*entities.Order, error)
+}
+
+// PaymentServiceInterface charges and refunds orders.
+type PaymentServiceInterface interface {
+	PayOrder(ctx context.Context, userID, orderID int, req models.PaymentRequest, idempotencyKey string) (*entities.Payment, error)
+	HandleWebhook(ctx context.Context, header http.Header, body []byte) error
+	RefundOrder(ctx context.Context, userID, orderID int) (*entities.Payment, error)
+	GetOrderPayments(userID, orderID int) ([]entities.Payment, error)
+}
+
+// VendorServiceInterface reviews vendor applications and reports on vendors' sales.
+type VendorServiceInterface interface {
+	SubmitApplication(userID int, req models.VendorApplicationRequest) (*entities.VendorApplication, error)
+	GetUserApplications(userID int) ([]entities.VendorApplication, error)
+	GetApplications(status string) ([]entities.VendorApplication, error)
+	ApproveApplication(reviewer AuditActor, applicationID int, req models.VendorApplicationReviewRequest) (*entities.VendorApplication, error)
+	RejectApplication(reviewer AuditActor, applicationID int, req models.VendorApplicationReviewRequest) (*entities.VendorApplication, error)
+	GetDashboard(vendorID int) (models.VendorDashboard, error)
+}
+
+// PrivacyServiceInterface exports and erases the personal data of users.
+type PrivacyServiceInterface interface {
+	ExportUserData(actor AuditActor) (models.UserDataExport, error)
+	GetErasureRequest(userID int) (*entities.ErasureRequest, error)
+	RequestErasure(actor AuditActor) (*entities.ErasureRequest, error)
+	CancelErasure(actor AuditActor) error
+	EraseUser(actor AuditActor, userID int) error
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/repositories"
+)
+
+// InventoryService manages product stock.
+type InventoryService struct {
+	InventoryRepo  repositories.InventoryRepositoryInterface
+	ProductService *ProductService
+}
+
+// NewInventoryService creates a new instance of InventoryService.
+func NewInventoryService(inventoryRepo repositories.InventoryRepositoryInterface, productService *ProductService) *InventoryService {
+	return &InventoryService{
+		InventoryRepo:  inventoryRepo,
+		ProductService: productService,
+	}
+}
+
+// SetStock sets the stock on hand of one of the user's products.
+func (s *InventoryService) SetStock(userID, productID int, req models.StockRequest) (*entities.Product, error) {
+	product, err := s.ProductService.getOwnedProduct(userID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	threshold := product.LowStockThreshold
+	if req.LowStockThreshold != nil {
+		threshold = *req.LowStockThreshold
+	}
+	updated, err := s.InventoryRepo.SetStock(productID, req.StockQuantity, threshold)
+	if err != nil {
+		return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to update stock")
+	}
+	if !updated {
+		return nil, goAuthException.NewCustomError(goAuthException.ConflictCode, "Stock can't be lower than the quantity reserved by unpaid orders")
+	}
+
+	return s.ProductService.GetProduct(productID)
+}
+
+// GetLowStockProducts returns the vendor's products at or below their low-stock threshold.
+func (s *InventoryService) GetLowStockProducts(vendorID int) ([]entities.Product, error) {
+	products, err := s.InventoryRepo.GetLowStockProducts(vendorID)
+	if err != nil {
+		return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve products")
+	}
+	return products, nil
+}
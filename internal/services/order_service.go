@@ -0,0 +1,331 @@
+package services
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/repositories"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	defaultOrderPageSize = 20
+	maxOrderPageSize     = 100
+
+	// DefaultStockReservationTTL is how long checkout holds stock for an unpaid order.
+	DefaultStockReservationTTL = 15 * time.Minute
+)
+
+// OrderService provides checkout and the order lifecycle.
+type OrderService struct {
+	OrderRepo         repositories.OrderRepositoryInterface
+	CartRepo          repositories.CartRepositoryInterface
+	AddressRepo       repositories.AddressRepositoryInterface
+	InventoryRepo     repositories.InventoryRepositoryInterface
+	PermissionService *PermissionService
+	ReservationTTL    time.Duration // unpaid orders are canceled and their stock released after this
+}
+
+// NewOrderService creates a new instance of OrderService.
+func NewOrderService(orderRepo repositories.OrderRepositoryInterface, cartRepo repositories.CartRepositoryInterface, addressRepo repositories.AddressRepositoryInterface,
+	inventoryRepo repositories.InventoryRepositoryInterface, permissionService *PermissionService) *OrderService {
+	return &OrderService{
+		OrderRepo:         orderRepo,
+		CartRepo:          cartRepo,
+		AddressRepo:       addressRepo,
+		InventoryRepo:     inventoryRepo,
+		PermissionService: permissionService,
+		ReservationTTL:    DefaultStockReservationTTL,
+	}
+}
+
+// Checkout turns the user's cart into a PENDING order, snapshotting the current product
+// prices, reserving their stock, and empties the cart. The order ships to the requested
+// address, or the user's default shipping address. The cart stays locked throughout, so a double submit
+// finds an empty cart instead of creating a second order.
+func (s *OrderService) Checkout(userID int, req models.CheckoutRequest) (*entities.Order, error) {
+	order := entities.Order{
+		UserID:        userID,
+		Status:        OrderStatusPending,
+		PaymentStatus: PaymentStatusUnpaid,
+	}
+	if method := strings.TrimSpace(req.PaymentMethod); method != "" {
+		order.PaymentMethod = &method
+	}
+
+	err := repositories.RunInTx(s.OrderRepo.DB(), func(tx *sql.Tx) error {
+		cart, err := s.CartRepo.LockCart(tx, userID)
+		if err != nil {
+			return err
+		}
+		cartItems, err := s.CartRepo.GetCartItems(tx, cart.ID)
+		if err != nil {
+			return err
+		}
+		if len(cartItems) == 0 {
+			return goAuthException.NewCustomError(goAuthException.BadRequestCode, "Cart is empty")
+		}
+
+		// Keep the address from being deleted before the order references it
+		if err := s.AddressRepo.LockAddressBook(tx, userID); err != nil {
+			return err
+		}
+		var address *entities.Address
+		if req.ShippingAddressID != 0 {
+			address, err = s.AddressRepo.GetAddress(tx, userID, req.ShippingAddressID)
+			if err == nil && address == nil {
+				return goAuthException.NewCustomError(goAuthException.BadRequestCode, "Shipping address not found")
+			}
+		} else {
+			address, err = s.AddressRepo.GetDefaultShippingAddress(tx, userID)
+		}
+		if err != nil {
+			return err
+		}
+		if address != nil {
+			order.ShippingAddressID = &address.ID
+		}
+
+		for _, cartItem := range cartItems {
+			order.Items = append(order.Items, entities.OrderItem{
+				ProductID:    cartItem.ProductID,
+				Name:         cartItem.Name,
+				Quantity:     cartItem.Quantity,
+				PriceAtOrder: cartItem.UnitPrice,
+			})
+		}
+		order.TotalAmount = cartTotal(cartItems)
+
+		if err := s.OrderRepo.InsertOrder(tx, &order); err != nil {
+			return err
+		}
+		err = s.InventoryRepo.ReserveStock(tx, order.ID, order.Items, time.Now().Add(s.ReservationTTL))
+		var stockErr *repositories.InsufficientStockError
+		if errors.As(err, &stockErr) {
+			return goAuthException.NewCustomError(goAuthException.ConflictCode, "Not enough stock for "+stockErr.Name).WithErrorCode(goAuthException.ErrorCodeInsufficientStock)
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.CartRepo.ClearCart(tx, cart.ID); err != nil {
+			return err
+		}
+		return s.CartRepo.TouchCart(tx, &cart)
+	})
+	if err != nil {
+		if _, ok := err.(*goAuthException.CustomError); ok {
+			return nil, err
+		}
+		slog.Error("Error checking out cart for user", "user_id", userID, "error", err)
+		return nil, goAuthException.NewCustomError(goAuthException.InternalServerErrorCode, "Failed to place order")
+	}
+
+	return s.OrderRepo.GetOrderByID(order.ID)
+}
+
+// GetOrderHistory returns a page of the user's own orders.
+func (s *OrderService) GetOrderHistory(userID int, req models.OrderQuery) (models.OrderPage, error) {
+	return s.listOrders(repositories.OrderFilter{UserID: userID}, req)
+}
+
+// GetVendorOrders returns a page of the orders containing the vendor's products, showing
+// only the vendor's own items.
+func (s *OrderService) GetVendorOrders(vendorID int, req models.OrderQuery) (models.OrderPage, error) {
+	return s.listOrders(repositories.OrderFilter{VendorID: vendorID}, req)
+}
+
+// GetOrder returns an order visible to the user: their own, any order for admins, or an
+// order containing their products for vendors, who only see their own items.
+func (s *OrderService) GetOrder(userID, orderID int) (*entities.Order, error) {
+	order, access, err := s.getOrderWithAccess(userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if access == orderAccessVendor {
+		order.Items = vendorItems(order.Items, userID)
+	}
+	return order, nil
+}
+
+// TransitionOrder moves an order to a new status. Customers may cancel their pending orders,
+// vendors may ship and complete orders made only of their products, and admins may make any
+// transition the state machine allows. PAID and REFUNDED are only reached through PaymentService.
+func (s *OrderService) TransitionOrder(userID, orderID int, status string) (*entities.Order, error) {
+	status = strings.ToUpper(strings.TrimSpace(status))
+	if !IsOrderStatus(status) {
+		return nil, goAuthException.NewCustomError(goAuthException.BadRequestCode, "Unknown order status")
+	}
+	if status == OrderStatusPaid || status == OrderStatusRefunded {
+		return nil, goAuthException.NewCustomError(goAuthException.BadRequestCode, "Orders are paid and refunded through the payment endpoints")
+	}
+
+	order, access, err := s.getOrderWithAccess(userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !access.mayTransitionTo(status) {
+		return nil, goAuthException.NewCustomError(goAuthException.ForbiddenCode, "You are not allowed to change this order to "+status)
+	}
+	// Shipping moves the whole order, so one vendor can't ship the other vendors' items
+	if access == orderAccessVendor && len(vendorItems(order.Items, userID)) != len(order.Items) {
+		return nil, goAuthException.NewCustomError(goAuthException.ForbiddenCode, "Orders with other vendors' products can only be changed by an admin")
+	}
+
+	err = repositories.RunInTx(s.OrderRepo.DB(), func(tx *sql.Tx) error {
+		return s.transition(tx, order, status, userID)
+	})
+	if err != nil {
+		if _, ok := err.(*goAuthException.CustomError); ok {
+			return nil, err
+		}
+		slog.Error("Error changing order status", "order_id", orderID, "status", status, "error", err)
+		return nil, goAuthException.NewCustomError(goAuthException.InternalServerErrorCode, "Failed to update order")
+	}
+
+	return s.GetOrder(userID, orderID)
+}
+
+// ExpireOrder cancels an unpaid order whose stock reservation expired, releasing the stock.
+// Orders that moved on in the meantime are left alone.
+func (s *OrderService) ExpireOrder(orderID int) error {
+	order, err := s.OrderRepo.GetOrderByID(orderID)
+	if err != nil || order == nil || order.Status != OrderStatusPending {
+		return err
+	}
+
+	err = repositories.RunInTx(s.OrderRepo.DB(), func(tx *sql.Tx) error {
+		return s.transition(tx, order, OrderStatusCanceled, 0)
+	})
+	if customErr, ok := goAuthException.AsCustomError(err); ok && customErr.Code == goAuthException.ConflictCode {
+		return nil
+	}
+	return err
+}
+
+// transition applies a state machine transition and its effect on stock: paying commits the
+// reserved stock, canceling releases it and refunding an order that hasn't shipped restocks it.
+// It fails with a conflict if the transition is illegal or the order changed status since it was read.
+func (s *OrderService) transition(tx *sql.Tx, order *entities.Order, status string, changedBy int) error {
+	if !CanTransitionOrder(order.Status, status) {
+		return goAuthException.NewCustomError(goAuthException.ConflictCode,
+			fmt.Sprintf("Cannot change order from %s to %s", order.Status, status))
+	}
+
+	updated, err := s.OrderRepo.UpdateOrderStatus(tx, order.ID, order.Status, status, paymentStatusForOrder(status), changedBy)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return goAuthException.NewCustomError(goAuthException.ConflictCode, "Order was modified by another request").WithErrorCode(goAuthException.ErrorCodeOrderModified)
+	}
+	from := order.Status
+	order.Status = status
+
+	switch {
+	case status == OrderStatusPaid:
+		return s.InventoryRepo.CommitReservations(tx, order.ID)
+	case status == OrderStatusCanceled:
+		return s.InventoryRepo.ReleaseReservations(tx, order.ID)
+	case status == OrderStatusRefunded && from == OrderStatusPaid:
+		return s.InventoryRepo.RestockOrder(tx, order.ID)
+	}
+	return nil
+}
+
+func (s *OrderService) listOrders(filter repositories.OrderFilter, req models.OrderQuery) (models.OrderPage, error) {
+	page, pageSize := normalizePage(req.Page, req.PageSize, defaultOrderPageSize, maxOrderPageSize)
+	filter.Status = strings.ToUpper(strings.TrimSpace(req.Status))
+	if filter.Status != "" && !IsOrderStatus(filter.Status) {
+		return models.OrderPage{}, goAuthException.NewCustomError(goAuthException.BadRequestCode, "Unknown order status")
+	}
+	filter.Limit = pageSize
+	filter.Offset = (page - 1) * pageSize
+
+	orders, total, err := s.OrderRepo.ListOrders(filter)
+	if err != nil {
+		return models.OrderPage{}, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve orders")
+	}
+
+	orderIDs := make([]int, len(orders))
+	for i, order := range orders {
+		orderIDs[i] = order.ID
+	}
+	items, err := s.OrderRepo.GetOrderItems(orderIDs, filter.VendorID)
+	if err != nil {
+		return models.OrderPage{}, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve orders")
+	}
+	for i := range orders {
+		if orderItems, ok := items[orders[i].ID]; ok {
+			orders[i].Items = orderItems
+		}
+	}
+
+	return models.OrderPage{
+		Items:    orders,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	}, nil
+}
+
+// orderAccess is how a user is related to an order.
+type orderAccess int
+
+const (
+	orderAccessOwner orderAccess = iota
+	orderAccessVendor
+	orderAccessAdmin
+)
+
+func (a orderAccess) mayTransitionTo(status string) bool {
+	switch a {
+	case orderAccessAdmin:
+		return true
+	case orderAccessVendor:
+		return status == OrderStatusShipped || status == OrderStatusCompleted
+	default:
+		return status == OrderStatusCanceled
+	}
+}
+
+// getOrderWithAccess returns an order and how the user may access it. Orders the user can't
+// see are reported as not found.
+func (s *OrderService) getOrderWithAccess(userID, orderID int) (*entities.Order, orderAccess, error) {
+	order, err := s.OrderRepo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, 0, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve order")
+	}
+	if order == nil {
+		return nil, 0, goAuthException.NewCustomError(goAuthException.NotFoundCode, "Order not found")
+	}
+
+	isAdmin, err := s.PermissionService.HasPermission(userID, PermissionManageUsers)
+	if err != nil {
+		return nil, 0, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to check permissions")
+	}
+	switch {
+	case isAdmin:
+		return order, orderAccessAdmin, nil
+	case order.UserID == userID:
+		return order, orderAccessOwner, nil
+	case len(vendorItems(order.Items, userID)) > 0:
+		return order, orderAccessVendor, nil
+	}
+	return nil, 0, goAuthException.NewCustomError(goAuthException.NotFoundCode, "Order not found")
+}
+
+// vendorItems returns the order items for the vendor's products.
+func vendorItems(items []entities.OrderItem, vendorID int) []entities.OrderItem {
+	filtered := []entities.OrderItem{}
+	for _, item := range items {
+		if item.VendorID == vendorID {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
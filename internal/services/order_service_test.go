@@ -0,0 +1,43 @@
+package services_test
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/repositories/memory"
+	"backendGoAuth/internal/services"
+	"testing"
+)
+
+func TestVendorsOnlyTransitionOrdersOfTheirOwn(t *testing.T) {
+	tests := []struct {
+		name       string
+		vendorIDs  []int
+		wantStatus string
+		wantCode   int
+	}{
+		{name: "only the vendor's products", vendorIDs: []int{2, 2}, wantStatus: services.OrderStatusShipped},
+		{name: "another vendor's products too", vendorIDs: []int{2, 3}, wantStatus: services.OrderStatusPaid, wantCode: goAuthException.ForbiddenCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders := &orderRepo{order: entities.Order{ID: 1, UserID: 1, Status: services.OrderStatusPaid}}
+			for i, vendorID := range tt.vendorIDs {
+				orders.order.Items = append(orders.order.Items, entities.OrderItem{ID: i + 1, OrderID: 1, VendorID: vendorID})
+			}
+			permissions := services.NewPermissionService(memory.NewPermissionRepository(memory.NewUserRepository()))
+			service := services.NewOrderService(orders, nil, nil, inventoryRepo{}, permissions)
+
+			_, err := service.TransitionOrder(2, 1, services.OrderStatusShipped)
+			if customErr, ok := goAuthException.AsCustomError(err); tt.wantCode != 0 && (!ok || customErr.Code != tt.wantCode) {
+				t.Fatalf("got error %v, want status %d", err, tt.wantCode)
+			}
+			if tt.wantCode == 0 && err != nil {
+				t.Fatal(err)
+			}
+			if orders.order.Status != tt.wantStatus {
+				t.Fatalf("got order status %s, want %s", orders.order.Status, tt.wantStatus)
+			}
+		})
+	}
+}
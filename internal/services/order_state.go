@@ -0,0 +1,60 @@
+package services
+
+// Order statuses
+const (
+	OrderStatusPending   = "PENDING"
+	OrderStatusPaid      = "PAID"
+	OrderStatusShipped   = "SHIPPED"
+	OrderStatusCompleted = "COMPLETED"
+	OrderStatusCanceled  = "CANCELED"
+	OrderStatusRefunded  = "REFUNDED"
+)
+
+// Payment statuses
+const (
+	PaymentStatusUnpaid     = "UNPAID"
+	PaymentStatusAuthorized = "AUTHORIZED"
+	PaymentStatusPaid       = "PAID"
+	PaymentStatusRefunded   = "REFUNDED"
+	PaymentStatusFailed     = "FAILED"
+)
+
+// orderTransitions lists the statuses each order status may move to. CANCELED and
+// REFUNDED are final.
+var orderTransitions = map[string][]string{
+	OrderStatusPending:   {OrderStatusPaid, OrderStatusCanceled},
+	OrderStatusPaid:      {OrderStatusShipped, OrderStatusRefunded},
+	OrderStatusShipped:   {OrderStatusCompleted, OrderStatusRefunded},
+	OrderStatusCompleted: {OrderStatusRefunded},
+}
+
+// CanTransitionOrder reports whether an order may move from one status to another.
+func CanTransitionOrder(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOrderStatus reports whether status is a known order status.
+func IsOrderStatus(status string) bool {
+	switch status {
+	case OrderStatusPending, OrderStatusPaid, OrderStatusShipped, OrderStatusCompleted, OrderStatusCanceled, OrderStatusRefunded:
+		return true
+	}
+	return false
+}
+
+// paymentStatusForOrder returns the payment status implied by moving an order to the given
+// status, or "" if the payment status doesn't change.
+func paymentStatusForOrder(status string) string {
+	switch status {
+	case OrderStatusPaid:
+		return PaymentStatusPaid
+	case OrderStatusRefunded:
+		return PaymentStatusRefunded
+	}
+	return ""
+}
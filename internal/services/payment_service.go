@@ -0,0 +1,365 @@
+package services
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/logging"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/payments"
+	"backendGoAuth/internal/repositories"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+)
+
+// Kinds of payment records
+const (
+	PaymentKindCharge = "CHARGE"
+	PaymentKindRefund = "REFUND"
+)
+
+// Statuses of payment records
+const (
+	PaymentProcessing = "PROCESSING" // a provider call is in flight
+	PaymentPending    = "PENDING"    // waiting for the provider's webhook
+	PaymentSucceeded  = "SUCCEEDED"
+	PaymentDeclined   = "DECLINED"
+	PaymentFailed     = "FAILED"
+	PaymentRefunded   = "REFUNDED" // taken, then given back as the order couldn't be marked paid
+)
+
+// PaymentCurrency is the currency of all prices.
+const PaymentCurrency = "EUR"
+
+// PaymentService charges and refunds orders through a payment provider.
+type PaymentService struct {
+	PaymentRepo  repositories.PaymentRepositoryInterface
+	OrderService *OrderService
+	Provider     payments.Provider
+}
+
+// NewPaymentService creates a new instance of PaymentService.
+func NewPaymentService(paymentRepo repositories.PaymentRepositoryInterface, orderService *OrderService, provider payments.Provider) *PaymentService {
+	return &PaymentService{
+		PaymentRepo:  paymentRepo,
+		OrderService: orderService,
+		Provider:     provider,
+	}
+}
+
+// PayOrder charges one of the user's pending orders. Retrying with the same idempotency key
+// returns the original payment instead of charging again, unless the provider couldn't be
+// reached or declined it, in which case the charge is attempted again with the new payment
+// method. Each attempt uses its own provider idempotency keys, see providerKey.
+func (s *PaymentService) PayOrder(ctx context.Context, userID, orderID int, req models.PaymentRequest, idempotencyKey string) (*entities.Payment, error) {
+	order, access, err := s.OrderService.getOrderWithAccess(userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID && access != orderAccessAdmin {
+		return nil, goAuthException.NewCustomError(goAuthException.NotFoundCode, "Order not found")
+	}
+
+	// Keys are scoped to the user so clients can't collide with each other
+	payment := &entities.Payment{
+		OrderID:        order.ID,
+		Provider:       s.Provider.Name(),
+		IdempotencyKey: fmt.Sprintf("%d:%s", userID, idempotencyKey),
+		Kind:           PaymentKindCharge,
+		Amount:         order.TotalAmount,
+		PaymentMethod:  req.PaymentMethod,
+		Status:         PaymentProcessing,
+	}
+	existing, err := s.PaymentRepo.GetPaymentByIdempotencyKey(payment.IdempotencyKey)
+	if err != nil {
+		return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve payment")
+	}
+	if existing == nil {
+		if order.Status != OrderStatusPending {
+			return nil, goAuthException.NewCustomError(goAuthException.ConflictCode, "Order is not awaiting payment")
+		}
+		created, err := s.PaymentRepo.InsertPayment(payment)
+		if err != nil {
+			return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to record payment")
+		}
+		if !created {
+			// A concurrent request with the same key won the insert
+			existing, err = s.PaymentRepo.GetPaymentByIdempotencyKey(payment.IdempotencyKey)
+			if err != nil || existing == nil {
+				return nil, goAuthException.NewCustomError(goAuthException.InternalServerErrorCode, "Failed to retrieve payment")
+			}
+		}
+	}
+
+	if existing != nil {
+		// Only charges the provider took are replayed; failed and declined ones are attempted again
+		if existing.OrderID != order.ID || (existing.Status != PaymentFailed && existing.Status != PaymentDeclined) {
+			return replayPayment(existing, order.ID)
+		}
+		if order.Status != OrderStatusPending {
+			return nil, goAuthException.NewCustomError(goAuthException.ConflictCode, "Order is not awaiting payment")
+		}
+		claimed, err := s.PaymentRepo.RetryPayment(existing, existing.Status, req.PaymentMethod)
+		if err != nil {
+			return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to update payment")
+		}
+		if !claimed {
+			return nil, goAuthException.NewCustomError(goAuthException.ConflictCode, "Payment is being processed").WithErrorCode(goAuthException.ErrorCodePaymentInProgress)
+		}
+		payment = existing
+		payment.Status = PaymentProcessing
+		payment.PaymentMethod = req.PaymentMethod
+		payment.Error = ""
+	}
+
+	result, err := s.Provider.Authorize(ctx, payments.AuthorizeRequest{
+		IdempotencyKey: providerKey(payment, "authorize"),
+		OrderID:        order.ID,
+		AmountCents:    toCents(payment.Amount),
+		Currency:       PaymentCurrency,
+		PaymentMethod:  payment.PaymentMethod,
+	})
+	if err != nil {
+		return nil, s.failPayment(payment, err)
+	}
+
+	return payment, s.applyResult(ctx, payment, order, result, userID)
+}
+
+// HandleWebhook applies an asynchronous payment outcome reported by the provider. Webhooks
+// for payments that were already settled are acknowledged without effect.
+func (s *PaymentService) HandleWebhook(ctx context.Context, header http.Header, body []byte) error {
+	event, err := s.Provider.VerifyWebhook(header, body)
+	if errors.Is(err, payments.ErrInvalidSignature) {
+		return goAuthException.NewCustomError(goAuthException.UnauthorizedCode, "Invalid webhook signature").WithErrorCode(goAuthException.ErrorCodeInvalidWebhookSignature)
+	}
+	if err != nil {
+		return goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid webhook")
+	}
+
+	payment, err := s.PaymentRepo.GetChargeByReference(s.Provider.Name(), event.Reference)
+	if err != nil {
+		return goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve payment")
+	}
+	if payment == nil {
+		return goAuthException.NewCustomError(goAuthException.NotFoundCode, "Payment not found")
+	}
+
+	claimed, err := s.PaymentRepo.ClaimPayment(payment.ID, PaymentPending, PaymentProcessing)
+	if err != nil {
+		return goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to update payment")
+	}
+	if !claimed {
+		logging.FromContext(ctx).Warn("Ignoring webhook for payment in its current status", "event_id", event.ID, "payment_id", payment.ID, "status", payment.Status)
+		return nil
+	}
+	payment.Status = PaymentProcessing
+
+	order, err := s.OrderService.OrderRepo.GetOrderByID(payment.OrderID)
+	if err != nil || order == nil {
+		return goAuthException.NewCustomError(goAuthException.InternalServerErrorCode, "Failed to retrieve order")
+	}
+	return s.applyResult(ctx, payment, order, payments.Result{
+		Reference:     event.Reference,
+		Status:        event.Status,
+		DeclineReason: event.DeclineReason,
+	}, 0)
+}
+
+// RefundOrder refunds the charge that paid for an order and marks the order REFUNDED.
+func (s *PaymentService) RefundOrder(ctx context.Context, userID, orderID int) (*entities.Payment, error) {
+	order, err := s.OrderService.OrderRepo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve order")
+	}
+	if order == nil {
+		return nil, goAuthException.NewCustomError(goAuthException.NotFoundCode, "Order not found")
+	}
+	if !CanTransitionOrder(order.Status, OrderStatusRefunded) {
+		return nil, goAuthException.NewCustomError(goAuthException.ConflictCode, "Order "+order.Status+" can't be refunded")
+	}
+
+	charge, err := s.PaymentRepo.GetSucceededCharge(orderID)
+	if err != nil {
+		return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve payment")
+	}
+	if charge == nil {
+		return nil, goAuthException.NewCustomError(goAuthException.ConflictCode, "Order has no captured payment")
+	}
+
+	// One full refund per charge, whoever asks for it
+	refund := &entities.Payment{
+		OrderID:        orderID,
+		Provider:       charge.Provider,
+		ProviderRef:    charge.ProviderRef,
+		IdempotencyKey: fmt.Sprintf("refund:%d", charge.ID),
+		Kind:           PaymentKindRefund,
+		Amount:         charge.Amount,
+		Status:         PaymentProcessing,
+	}
+	created, err := s.PaymentRepo.InsertPayment(refund)
+	if err != nil {
+		return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to record refund")
+	}
+	if !created {
+		existing, err := s.PaymentRepo.GetPaymentByIdempotencyKey(refund.IdempotencyKey)
+		if err != nil || existing == nil {
+			return nil, goAuthException.NewCustomError(goAuthException.InternalServerErrorCode, "Failed to retrieve refund")
+		}
+		if existing.Status != PaymentFailed {
+			return replayPayment(existing, orderID)
+		}
+		// The provider couldn't be reached last time; retrying is safe with the same key
+		refund = existing
+	}
+
+	result, err := s.Provider.Refund(ctx, payments.RefundRequest{
+		IdempotencyKey: refund.IdempotencyKey,
+		Reference:      charge.ProviderRef,
+		AmountCents:    toCents(charge.Amount),
+	})
+	if err != nil {
+		return nil, s.failPayment(refund, err)
+	}
+	if result.Status != payments.StatusRefunded {
+		refund.Status = PaymentDeclined
+		refund.Error = result.DeclineReason
+		return refund, s.PaymentRepo.UpdatePayment(nil, refund)
+	}
+
+	refund.Status = PaymentSucceeded
+	err = repositories.RunInTx(s.PaymentRepo.DB(), func(tx *sql.Tx) error {
+		if err := s.PaymentRepo.UpdatePayment(tx, refund); err != nil {
+			return err
+		}
+		return s.OrderService.transition(tx, order, OrderStatusRefunded, userID)
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("Refund succeeded at the provider but recording it failed", "refund_id", refund.ID, "error", err)
+		return nil, goAuthException.NewCustomError(goAuthException.InternalServerErrorCode, "Failed to record refund")
+	}
+	return refund, nil
+}
+
+// GetOrderPayments returns the charges and refunds of an order visible to the user.
+func (s *PaymentService) GetOrderPayments(userID, orderID int) ([]entities.Payment, error) {
+	order, access, err := s.OrderService.getOrderWithAccess(userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID && access != orderAccessAdmin {
+		return nil, goAuthException.NewCustomError(goAuthException.NotFoundCode, "Order not found")
+	}
+
+	orderPayments, err := s.PaymentRepo.GetPaymentsByOrder(orderID)
+	if err != nil {
+		return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve payments")
+	}
+	return orderPayments, nil
+}
+
+// applyResult moves a charge forward from the provider's answer: authorized charges are captured
+// and mark the order PAID, pending ones wait for the webhook, declines leave the order unpaid.
+func (s *PaymentService) applyResult(ctx context.Context, payment *entities.Payment, order *entities.Order, result payments.Result, actorID int) error {
+	payment.ProviderRef = result.Reference
+
+	switch result.Status {
+	case payments.StatusPending:
+		payment.Status = PaymentPending
+		return s.PaymentRepo.UpdatePayment(nil, payment)
+	case payments.StatusAuthorized:
+		captured, err := s.Provider.Capture(ctx, payments.CaptureRequest{
+			IdempotencyKey: providerKey(payment, "capture"),
+			Reference:      result.Reference,
+			AmountCents:    toCents(payment.Amount),
+		})
+		if err != nil {
+			return s.failPayment(payment, err)
+		}
+		result = captured
+	}
+
+	if result.Status != payments.StatusCaptured {
+		payment.Status = PaymentDeclined
+		payment.Error = result.DeclineReason
+		return repositories.RunInTx(s.PaymentRepo.DB(), func(tx *sql.Tx) error {
+			if err := s.PaymentRepo.UpdatePayment(tx, payment); err != nil {
+				return err
+			}
+			return s.OrderService.OrderRepo.SetPaymentStatus(tx, order.ID, PaymentStatusFailed, payment.PaymentMethod)
+		})
+	}
+
+	payment.Status = PaymentSucceeded
+	err := repositories.RunInTx(s.PaymentRepo.DB(), func(tx *sql.Tx) error {
+		if err := s.PaymentRepo.UpdatePayment(tx, payment); err != nil {
+			return err
+		}
+		if err := s.OrderService.OrderRepo.SetPaymentStatus(tx, order.ID, PaymentStatusPaid, payment.PaymentMethod); err != nil {
+			return err
+		}
+		return s.OrderService.transition(tx, order, OrderStatusPaid, actorID)
+	})
+	if err == nil {
+		return nil
+	}
+
+	// The money was taken but the order can't be marked paid (e.g. it was canceled meanwhile), so give it back
+	logging.FromContext(ctx).Warn("Refunding payment for order", "payment_id", payment.ID, "order_id", order.ID, "error", err)
+	refunded, refundErr := s.Provider.Refund(ctx, payments.RefundRequest{
+		IdempotencyKey: providerKey(payment, "refund"),
+		Reference:      payment.ProviderRef,
+		AmountCents:    toCents(payment.Amount),
+	})
+	if refundErr != nil || refunded.Status != payments.StatusRefunded {
+		// Still SUCCEEDED, so retrying the request can't take the money a second time
+		logging.FromContext(ctx).Error("Error refunding payment", "payment_id", payment.ID, "error", refundErr, "decline_reason", refunded.DeclineReason)
+		payment.Error = "order could not be marked paid, refunding the payment failed"
+	} else {
+		payment.Status = PaymentRefunded
+		payment.Error = "order could not be marked paid, payment refunded"
+	}
+	if updateErr := s.PaymentRepo.UpdatePayment(nil, payment); updateErr != nil {
+		logging.FromContext(ctx).Error("Error updating payment", "payment_id", payment.ID, "error", updateErr)
+	}
+	if _, ok := err.(*goAuthException.CustomError); ok {
+		return err
+	}
+	return goAuthException.NewCustomError(goAuthException.InternalServerErrorCode, "Failed to record payment")
+}
+
+// failPayment records that the provider couldn't be reached.
+func (s *PaymentService) failPayment(payment *entities.Payment, cause error) error {
+	slog.Warn("Payment provider error for payment", "payment_id", payment.ID, "error", cause)
+	payment.Status = PaymentFailed
+	payment.Error = "payment provider unavailable"
+	if err := s.PaymentRepo.UpdatePayment(nil, payment); err != nil {
+		slog.Error("Error updating payment", "payment_id", payment.ID, "error", err)
+	}
+	return goAuthException.NewCustomError(goAuthException.InternalServerErrorCode, "Payment provider unavailable")
+}
+
+// providerKey returns the idempotency key of a provider call for the payment's current
+// attempt, so a retried charge isn't answered with the outcome of the previous attempt.
+func providerKey(payment *entities.Payment, call string) string {
+	return fmt.Sprintf("%s:attempt-%d:%s", payment.IdempotencyKey, payment.Attempt, call)
+}
+
+// replayPayment answers a retried request with the payment its idempotency key created.
+func replayPayment(payment *entities.Payment, orderID int) (*entities.Payment, error) {
+	if payment.OrderID != orderID {
+		return nil, goAuthException.NewCustomError(goAuthException.ConflictCode, "Idempotency key was already used for another order").WithErrorCode(goAuthException.ErrorCodeIdempotencyKeyReused)
+	}
+	if payment.Status == PaymentProcessing {
+		return nil, goAuthException.NewCustomError(goAuthException.ConflictCode, "Payment is being processed").WithErrorCode(goAuthException.ErrorCodePaymentInProgress)
+	}
+	return payment, nil
+}
+
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
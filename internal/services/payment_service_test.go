@@ -0,0 +1,179 @@
+package services_test
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/payments"
+	"backendGoAuth/internal/repositories"
+	"backendGoAuth/internal/repositories/memory"
+	"backendGoAuth/internal/services"
+	"context"
+	"database/sql"
+	"testing"
+)
+
+// paymentRepo keeps payments in a map, claiming and retrying them like the Postgres repository.
+type paymentRepo struct {
+	payments map[int]*entities.Payment
+}
+
+func (r *paymentRepo) DB() *sql.DB {
+	return nil
+}
+
+func (r *paymentRepo) InsertPayment(payment *entities.Payment) (bool, error) {
+	if existing, _ := r.GetPaymentByIdempotencyKey(payment.IdempotencyKey); existing != nil {
+		return false, nil
+	}
+	payment.ID, payment.Attempt = len(r.payments)+1, 1
+	stored := *payment
+	r.payments[payment.ID] = &stored
+	return true, nil
+}
+
+func (r *paymentRepo) GetPaymentByIdempotencyKey(key string) (*entities.Payment, error) {
+	for _, payment := range r.payments {
+		if payment.IdempotencyKey == key {
+			found := *payment
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *paymentRepo) GetChargeByReference(provider, reference string) (*entities.Payment, error) {
+	return nil, nil
+}
+
+func (r *paymentRepo) GetSucceededCharge(orderID int) (*entities.Payment, error) {
+	return nil, nil
+}
+
+func (r *paymentRepo) GetPaymentsByOrder(orderID int) ([]entities.Payment, error) {
+	return nil, nil
+}
+
+func (r *paymentRepo) ClaimPayment(paymentID int, from, to string) (bool, error) {
+	payment := r.payments[paymentID]
+	if payment.Status != from {
+		return false, nil
+	}
+	payment.Status = to
+	return true, nil
+}
+
+func (r *paymentRepo) RetryPayment(payment *entities.Payment, from, paymentMethod string) (bool, error) {
+	stored := r.payments[payment.ID]
+	if stored.Status != from {
+		return false, nil
+	}
+	stored.Status, stored.PaymentMethod, stored.Error = services.PaymentProcessing, paymentMethod, ""
+	stored.Attempt++
+	payment.Attempt = stored.Attempt
+	return true, nil
+}
+
+func (r *paymentRepo) UpdatePayment(exec repositories.DBExecutor, payment *entities.Payment) error {
+	stored := *payment
+	r.payments[payment.ID] = &stored
+	return nil
+}
+
+// orderRepo holds a single order. Once canceled is set, marking it paid fails like a
+// concurrent cancellation would.
+type orderRepo struct {
+	repositories.OrderRepositoryInterface
+	order    entities.Order
+	canceled bool
+}
+
+func (r *orderRepo) DB() *sql.DB {
+	return nil
+}
+
+func (r *orderRepo) GetOrderByID(orderID int) (*entities.Order, error) {
+	order := r.order
+	return &order, nil
+}
+
+func (r *orderRepo) UpdateOrderStatus(tx *sql.Tx, orderID int, from, to, paymentStatus string, changedBy int) (bool, error) {
+	if r.canceled || r.order.Status != from {
+		return false, nil
+	}
+	r.order.Status, r.order.PaymentStatus = to, paymentStatus
+	return true, nil
+}
+
+func (r *orderRepo) SetPaymentStatus(exec repositories.DBExecutor, orderID int, paymentStatus, paymentMethod string) error {
+	r.order.PaymentStatus = paymentStatus
+	r.order.PaymentMethod = &paymentMethod
+	return nil
+}
+
+// inventoryRepo has no stock to commit.
+type inventoryRepo struct {
+	repositories.InventoryRepositoryInterface
+}
+
+func (inventoryRepo) CommitReservations(tx *sql.Tx, orderID int) error {
+	return nil
+}
+
+// newPaymentService returns a service charging a pending order of user 1 through a fake provider.
+func newPaymentService() (*services.PaymentService, *paymentRepo, *orderRepo) {
+	repo := &paymentRepo{payments: make(map[int]*entities.Payment)}
+	orders := &orderRepo{order: entities.Order{ID: 1, UserID: 1, TotalAmount: 42.5, Status: services.OrderStatusPending}}
+	users := memory.NewUserRepository()
+	permissions := services.NewPermissionService(memory.NewPermissionRepository(users))
+	orderService := services.NewOrderService(orders, nil, nil, inventoryRepo{}, permissions)
+	return services.NewPaymentService(repo, orderService, payments.NewFakeProvider("test secret", "", 0)), repo, orders
+}
+
+func TestPayOrderRetriesDeclinedCharge(t *testing.T) {
+	service, _, orders := newPaymentService()
+	ctx := context.Background()
+
+	payment, err := service.PayOrder(ctx, 1, 1, models.PaymentRequest{PaymentMethod: payments.FakeMethodDecline}, "key")
+	if err != nil || payment.Status != services.PaymentDeclined {
+		t.Fatalf("got payment %+v (%v), want it declined", payment, err)
+	}
+
+	// The retry is charged with the new payment method, not answered with the decline
+	retried, err := service.PayOrder(ctx, 1, 1, models.PaymentRequest{PaymentMethod: payments.FakeMethodSuccess}, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retried.ID != payment.ID || retried.Status != services.PaymentSucceeded || retried.PaymentMethod != payments.FakeMethodSuccess || retried.Attempt != 2 {
+		t.Fatalf("got retried payment %+v, want the second attempt of payment %d to succeed", retried, payment.ID)
+	}
+	if orders.order.Status != services.OrderStatusPaid {
+		t.Fatalf("got order status %s, want %s", orders.order.Status, services.OrderStatusPaid)
+	}
+}
+
+func TestPayOrderDoesNotReopenRefundedCharge(t *testing.T) {
+	service, repo, orders := newPaymentService()
+	ctx := context.Background()
+
+	// The charge is taken but the order is canceled meanwhile, so the money is given back
+	orders.canceled = true
+	if _, err := service.PayOrder(ctx, 1, 1, models.PaymentRequest{PaymentMethod: payments.FakeMethodSuccess}, "key"); err == nil {
+		t.Fatal("paying a canceled order succeeded")
+	}
+	payment, _ := repo.GetPaymentByIdempotencyKey("1:key")
+	if payment == nil || payment.Status != services.PaymentRefunded {
+		t.Fatalf("got payment %+v, want it refunded", payment)
+	}
+
+	orders.canceled = false
+	replayed, err := service.PayOrder(ctx, 1, 1, models.PaymentRequest{PaymentMethod: payments.FakeMethodSuccess}, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Status != services.PaymentRefunded || replayed.Attempt != 1 {
+		t.Fatalf("got payment %+v, want the refunded payment replayed", replayed)
+	}
+	if orders.order.Status != services.OrderStatusPending {
+		t.Fatalf("got order status %s, want it still %s", orders.order.Status, services.OrderStatusPending)
+	}
+}
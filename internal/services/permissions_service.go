@@ -1,58 +1,73 @@
 package services
 
-//this is a nth after everything works ig but to declare the structure ig
-//
-//import (
-//	"database/sql"
-//	"fmt"
-//	"log"
-//)
-//
-//package main
-//
-//import (
-//"database/sql"
-//"fmt"
-//"log"
-//)
-//
-//// CheckPermission checks if a user has the specified permission
-//func CheckPermission(db *sql.DB, userID int, permission string) (bool, error) {
-//	query := `
-//    SELECT 1
-//    FROM user_roles ur
-//    JOIN role_permissions rp ON ur.role_id = rp.role_id
-//    JOIN permissions p ON rp.permission_id = p.id
-//    WHERE ur.user_id = $1 AND p.name = $2;
-//	`
-//	var exists int
-//	err := db.QueryRow(query, userID, permission).Scan(&exists)
-//	if err != nil {
-//		if err == sql.ErrNoRows {
-//			// Permission not found
-//			return false, nil
-//		}
-//		return false, err
-//	}
-//	return exists == 1, nil
-//}
-//
-//func main() {
-//	// Assume db is your database connection
-//	var db *sql.DB // Replace with actual db connection
-//
-//	// Simulate user check (e.g., guest user with ID 4)
-//	userID := 4
-//	permission := "PLACE_ORDER"
-//
-//	hasPermission, err := CheckPermission(db, userID, permission)
-//	if err != nil {
-//		log.Fatalf("Error checking permission: %v", err)
-//	}
-//
-//	if hasPermission {
-//		fmt.Println("User has permission to place an order.")
-//	} else {
-//		fmt.Println("User does NOT have permission to place an order.")
-//	}
-//}
+import (
+	"backendGoAuth/internal/cache"
+	"backendGoAuth/internal/repositories"
+)
+
+// Permission names seeded in the permissions table
+const (
+	PermissionCreateProduct    = "CREATE_PRODUCT"
+	PermissionViewProduct      = "VIEW_PRODUCT"
+	PermissionDeleteProduct    = "DELETE_PRODUCT"
+	PermissionManageUsers      = "MANAGE_USERS"
+	PermissionPlaceOrder       = "PLACE_ORDER"
+	PermissionImpersonateUsers = "IMPERSONATE_USERS"
+)
+
+// PermissionService provides role-based access control checks.
+type PermissionService struct {
+	PermissionRepo repositories.PermissionRepositoryInterface
+	Cache          *cache.PermissionCache // optional; must be invalidated through InvalidateUser
+}
+
+// NewPermissionService creates a new instance of PermissionService.
+func NewPermissionService(permissionRepo repositories.PermissionRepositoryInterface) *PermissionService {
+	return &PermissionService{
+		PermissionRepo: permissionRepo,
+	}
+}
+
+// HasPermission checks if a user has the specified permission.
+func (s *PermissionService) HasPermission(userID int, permission string) (bool, error) {
+	if s.Cache == nil {
+		return s.PermissionRepo.UserHasPermission(userID, permission)
+	}
+
+	permissions, err := s.getPermissions(userID)
+	if err != nil {
+		return false, err
+	}
+	return permissions[permission], nil
+}
+
+// GetPermissions returns the names of all permissions a user has.
+func (s *PermissionService) GetPermissions(userID int) ([]string, error) {
+	return s.PermissionRepo.GetUserPermissions(userID)
+}
+
+// InvalidateUser makes every instance reload a user's permissions on their next request.
+// Call it after changing the user's roles.
+func (s *PermissionService) InvalidateUser(userID int) {
+	if s.Cache != nil {
+		s.Cache.Invalidate(userID)
+	}
+	s.PermissionRepo.NotifyPermissionsChanged(userID)
+}
+
+func (s *PermissionService) getPermissions(userID int) (map[string]bool, error) {
+	if permissions, ok := s.Cache.Get(userID); ok {
+		return permissions, nil
+	}
+
+	names, err := s.PermissionRepo.GetUserPermissions(userID)
+	if err != nil {
+		return nil, err
+	}
+	permissions := make(map[string]bool, len(names))
+	for _, name := range names {
+		permissions[name] = true
+	}
+	s.Cache.Set(userID, permissions)
+	return permissions, nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+)
+
+// postalCodePatterns holds the postal code formats of the countries we ship to most, keyed by
+// ISO 3166-1 alpha-2 code. Other countries get a lenient generic check.
+var postalCodePatterns = map[string]*regexp.Regexp{
+	"AT": regexp.MustCompile(`^\d{4}$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"BE": regexp.MustCompile(`^\d{4}$`),
+	"BR": regexp.MustCompile(`^\d{5}-?\d{3}$`),
+	"CA": regexp.MustCompile(`^[ABCEGHJ-NPRSTVXY]\d[ABCEGHJ-NPRSTV-Z] ?\d[ABCEGHJ-NPRSTV-Z]\d$`),
+	"CH": regexp.MustCompile(`^\d{4}$`),
+	"CZ": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"DK": regexp.MustCompile(`^\d{4}$`),
+	"ES": regexp.MustCompile(`^(0[1-9]|[1-4]\d|5[0-2])\d{3}$`),
+	"FI": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"GB": regexp.MustCompile(`^([A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}|GIR ?0AA)$`),
+	"IE": regexp.MustCompile(`^[AC-FHKNPRTV-Y]\d[\dW] ?[\dAC-FHKNPRTV-Y]{4}$`),
+	"IN": regexp.MustCompile(`^[1-9]\d{5}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+	"NL": regexp.MustCompile(`^[1-9]\d{3} ?[A-Z]{2}$`),
+	"NO": regexp.MustCompile(`^\d{4}$`),
+	"PL": regexp.MustCompile(`^\d{2}-\d{3}$`),
+	"PT": regexp.MustCompile(`^\d{4}-\d{3}$`),
+	"SE": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+}
+
+// countriesWithoutPostalCodes don't use postal codes, so none is required.
+var countriesWithoutPostalCodes = map[string]bool{
+	"AE": true, "AO": true, "BS": true, "BZ": true, "FJ": true, "GH": true, "HK": true,
+	"JM": true, "MO": true, "QA": true, "TZ": true, "UG": true, "ZW": true,
+}
+
+var (
+	countryCodePattern       = regexp.MustCompile(`^[A-Z]{2}$`)
+	genericPostalCodePattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9 -]{1,9}$`)
+)
+
+// normalizeCountry returns the upper-case country code, and whether it looks like an ISO 3166-1 alpha-2 code.
+func normalizeCountry(country string) (string, bool) {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	return country, countryCodePattern.MatchString(country)
+}
+
+// normalizePostalCode returns the upper-case postal code, and whether it is valid for the country.
+func normalizePostalCode(country, postalCode string) (string, bool) {
+	postalCode = strings.ToUpper(strings.Join(strings.Fields(postalCode), " "))
+	if postalCode == "" {
+		return "", countriesWithoutPostalCodes[country]
+	}
+	if pattern, ok := postalCodePatterns[country]; ok {
+		return postalCode, pattern.MatchString(postalCode)
+	}
+	return postalCode, genericPostalCodePattern.MatchString(postalCode)
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/metrics"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/repositories"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// exportOrderPageSize is the number of orders loaded per query when exporting a user's data.
+const exportOrderPageSize = 100
+
+// openOrderStatuses are the statuses of orders still being fulfilled, which block erasure
+// until they complete or are canceled.
+var openOrderStatuses = []string{OrderStatusPending, OrderStatusPaid, OrderStatusShipped}
+
+// PrivacyService exports a user's personal data and erases it on request.
+type PrivacyService struct {
+	PrivacyRepo       repositories.PrivacyRepositoryInterface
+	OrderRepo         repositories.OrderRepositoryInterface
+	VendorRepo        repositories.VendorRepositoryInterface
+	SessionRepo       repositories.SessionRepositoryInterface
+	PermissionService *PermissionService
+	AuditService      *AuditService
+	Outbox            repositories.OutboxRepositoryInterface
+	Metrics           *metrics.Metrics
+	GracePeriod       time.Duration // how long a user has to cancel an erasure request
+}
+
+// NewPrivacyService creates a new instance of PrivacyService.
+func NewPrivacyService(privacyRepo repositories.PrivacyRepositoryInterface, orderRepo repositories.OrderRepositoryInterface, vendorRepo repositories.VendorRepositoryInterface,
+	sessionRepo repositories.SessionRepositoryInterface, permissionService *PermissionService, auditService *AuditService, outbox repositories.OutboxRepositoryInterface, m *metrics.Metrics) *PrivacyService {
+	return &PrivacyService{
+		PrivacyRepo:       privacyRepo,
+		OrderRepo:         orderRepo,
+		VendorRepo:        vendorRepo,
+		SessionRepo:       sessionRepo,
+		PermissionService: permissionService,
+		AuditService:      auditService,
+		Outbox:            outbox,
+		Metrics:           m,
+		GracePeriod:       30 * 24 * time.Hour,
+	}
+}
+
+// ExportUserData gathers everything stored about the user into a single archive.
+func (s *PrivacyService) ExportUserData(actor AuditActor) (models.UserDataExport, error) {
+	userID := actor.UserID
+	user, err := s.PrivacyRepo.GetProfile(userID)
+	if err != nil {
+		return models.UserDataExport{}, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to export data")
+	}
+	if user == nil {
+		return models.UserDataExport{}, goAuthException.NewCustomError(goAuthException.NotFoundCode, "User not found").WithErrorCode(goAuthException.ErrorCodeUserNotFound)
+	}
+
+	export := models.UserDataExport{
+		ExportedAt: time.Now().UTC(),
+		Profile: models.AccountProfile{
+			ID:        user.ID,
+			Username:  user.Username,
+			Email:     user.Email,
+			IsActive:  user.IsActive,
+			IsBlocked: user.IsBlocked,
+			CreatedAt: user.CreatedAt,
+			UpdatedAt: user.UpdatedAt,
+		},
+	}
+	if !user.LastLogin.IsZero() {
+		export.Profile.LastLogin = &user.LastLogin
+	}
+
+	if err := s.loadExport(userID, &export); err != nil {
+		slog.Error("Error exporting data of user", "user_id", userID, "error", err)
+		return models.UserDataExport{}, goAuthException.NewCustomError(goAuthException.InternalServerErrorCode, "Failed to export data")
+	}
+
+	s.AuditService.RecordBestEffort(AuditEvent{
+		Actor:    actor,
+		TargetID: userID,
+		Action:   AuditActionDataExported,
+	})
+	return export, nil
+}
+
+// loadExport fills in the parts of the export stored outside the users table.
+func (s *PrivacyService) loadExport(userID int, export *models.UserDataExport) error {
+	var err error
+	if export.Permissions, err = s.PermissionService.GetPermissions(userID); err != nil {
+		return err
+	}
+	if export.Sessions, err = s.PrivacyRepo.GetSessions(userID); err != nil {
+		return err
+	}
+	if export.Addresses, err = s.PrivacyRepo.GetAddresses(userID); err != nil {
+		return err
+	}
+	if export.Orders, err = s.exportOrders(userID); err != nil {
+		return err
+	}
+	if export.VendorApplications, err = s.VendorRepo.GetApplications(userID, ""); err != nil {
+		return err
+	}
+	if export.AuditEntries, err = s.PrivacyRepo.GetAuditLogs(userID); err != nil {
+		return err
+	}
+	export.Erasure, err = s.PrivacyRepo.GetErasureRequest(userID)
+	return err
+}
+
+// exportOrders loads all of a user's orders with their items, newest first.
+func (s *PrivacyService) exportOrders(userID int) ([]entities.Order, error) {
+	orders := []entities.Order{}
+	for offset := 0; ; offset += exportOrderPageSize {
+		page, total, err := s.OrderRepo.ListOrders(repositories.OrderFilter{UserID: userID, Limit: exportOrderPageSize, Offset: offset})
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, page...)
+		if len(page) == 0 || len(orders) >= total {
+			break
+		}
+	}
+	if len(orders) == 0 {
+		return orders, nil
+	}
+
+	orderIDs := make([]int, len(orders))
+	for i, order := range orders {
+		orderIDs[i] = order.ID
+	}
+	items, err := s.OrderRepo.GetOrderItems(orderIDs, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range orders {
+		orders[i].Items = items[orders[i].ID]
+	}
+	return orders, nil
+}
+
+// GetErasureRequest returns the user's erasure request, or nil if they haven't asked to be erased.
+func (s *PrivacyService) GetErasureRequest(userID int) (*entities.ErasureRequest, error) {
+	request, err := s.PrivacyRepo.GetErasureRequest(userID)
+	if err != nil {
+		return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve erasure request")
+	}
+	return request, nil
+}
+
+// RequestErasure schedules the user's erasure once the grace period is over. The user can
+// keep using their account and cancel until then.
+func (s *PrivacyService) RequestErasure(actor AuditActor) (*entities.ErasureRequest, error) {
+	request, err := s.PrivacyRepo.ScheduleErasure(actor.UserID, actor.UserID, time.Now().Add(s.GracePeriod))
+	if err != nil {
+		return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to request erasure")
+	}
+
+	s.AuditService.RecordBestEffort(AuditEvent{
+		Actor:    actor,
+		TargetID: actor.UserID,
+		Action:   AuditActionErasureRequested,
+		After:    map[string]time.Time{"scheduled_for": request.ScheduledFor},
+	})
+	return request, nil
+}
+
+// CancelErasure withdraws the user's pending erasure request.
+func (s *PrivacyService) CancelErasure(actor AuditActor) error {
+	canceled, err := s.PrivacyRepo.CancelErasure(actor.UserID)
+	if err != nil {
+		return goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to cancel erasure")
+	}
+	if !canceled {
+		return goAuthException.NewCustomError(goAuthException.NotFoundCode, "No pending erasure request")
+	}
+
+	s.AuditService.RecordBestEffort(AuditEvent{
+		Actor:    actor,
+		TargetID: actor.UserID,
+		Action:   AuditActionErasureCanceled,
+	})
+	return nil
+}
+
+// EraseUser anonymizes a user right away, ending their sessions and removing their roles.
+// Users with orders still being fulfilled can't be erased yet.
+func (s *PrivacyService) EraseUser(actor AuditActor, userID int) error {
+	var sessionIDs []int
+	err := repositories.RunInTx(s.PrivacyRepo.DB(), func(tx *sql.Tx) error {
+		// Checking out under the same lock can't slip an order in after the check
+		if err := s.PrivacyRepo.LockCheckout(tx, userID); err != nil {
+			return err
+		}
+		hasOpenOrders, err := s.PrivacyRepo.HasOrdersInStatus(tx, userID, openOrderStatuses)
+		if err != nil {
+			return err
+		}
+		if hasOpenOrders {
+			return goAuthException.NewCustomError(goAuthException.ConflictCode, "User has orders that are still being fulfilled").WithErrorCode(goAuthException.ErrorCodeOpenOrders)
+		}
+
+		var redacted []entities.AuditLog
+		if sessionIDs, redacted, err = s.PrivacyRepo.EraseUser(tx, userID, actor.UserID); err != nil {
+			return err
+		}
+		// Redacted entries no longer match their sealed digest; the chain checks them against this record
+		if err := s.AuditService.RecordRedactions(tx, AuditActor{UserID: actor.UserID}, userID, redacted); err != nil {
+			return err
+		}
+		// Events already written carry the user's personal data to the webhooks too
+		if err := s.Outbox.RedactUser(tx, userID); err != nil {
+			return err
+		}
+		return s.Outbox.Enqueue(tx, EventUserErased, map[string]interface{}{
+			"user_id":   userID,
+			"erased_by": actor.UserID,
+		})
+	})
+	if _, ok := err.(*goAuthException.CustomError); ok {
+		return err
+	}
+	if errors.Is(err, repositories.ErrAlreadyErased) {
+		return goAuthException.NewCustomError(goAuthException.ConflictCode, "User was already erased").WithErrorCode(goAuthException.ErrorCodeUserAlreadyErased)
+	}
+	if err != nil {
+		slog.Error("Error erasing user", "user_id", userID, "error", err)
+		return goAuthException.NewCustomError(goAuthException.InternalServerErrorCode, "Failed to erase user")
+	}
+
+	for _, sessionID := range sessionIDs {
+		s.SessionRepo.InvalidateSession(context.Background(), sessionID)
+	}
+	s.Metrics.SessionsRevoked("erased", len(sessionIDs))
+	s.PermissionService.InvalidateUser(userID)
+
+	// Only the fact that the user was erased is recorded, with no personal data
+	s.AuditService.RecordBestEffort(AuditEvent{
+		Actor:     AuditActor{UserID: actor.UserID},
+		TargetID:  userID,
+		Action:    AuditActionUserErased,
+		TableName: "users",
+		RowID:     userID,
+	})
+	return nil
+}
@@ -0,0 +1,81 @@
+package services_test
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/metrics"
+	"backendGoAuth/internal/repositories"
+	"backendGoAuth/internal/repositories/memory"
+	"backendGoAuth/internal/services"
+	"database/sql"
+	"encoding/json"
+	"github.com/prometheus/client_golang/prometheus"
+	"testing"
+)
+
+// privacyRepo erases users without touching anything, for users with no open orders.
+type privacyRepo struct {
+	repositories.PrivacyRepositoryInterface
+}
+
+func (privacyRepo) DB() *sql.DB {
+	return nil
+}
+
+func (privacyRepo) LockCheckout(tx *sql.Tx, userID int) error {
+	return nil
+}
+
+func (privacyRepo) HasOrdersInStatus(exec repositories.DBExecutor, userID int, statuses []string) (bool, error) {
+	return false, nil
+}
+
+func (privacyRepo) EraseUser(tx *sql.Tx, userID, requestedBy int) ([]int, []entities.AuditLog, error) {
+	return nil, nil, nil
+}
+
+func TestEraseUserRedactsOutboxEvents(t *testing.T) {
+	outbox := memory.NewOutboxRepository()
+	events := []struct {
+		eventType string
+		payload   map[string]interface{}
+	}{
+		{services.EventUserLogin, map[string]interface{}{"user_id": 2, "session_id": 1, "ip_address": "203.0.113.7", "browser": "Firefox", "device": "Desktop"}},
+		{services.EventUserLoginFailed, map[string]interface{}{"user_id": 2, "identifier": "alice@example.com", "reason": "invalid_password", "ip_address": "203.0.113.7", "user_agent": "curl"}},
+		{services.EventSessionRevoked, map[string]interface{}{"user_id": 3, "session_id": 2, "revoked_by": 2, "ip_address": "203.0.113.7"}},
+		{services.EventUserLogin, map[string]interface{}{"user_id": 3, "session_id": 3, "ip_address": "198.51.100.1", "browser": "Safari", "device": "iPhone"}},
+	}
+	for _, event := range events {
+		if err := outbox.Enqueue(nil, event.eventType, event.payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sessions := memory.NewSessionRepository()
+	permissions := services.NewPermissionService(memory.NewPermissionRepository(memory.NewUserRepository()))
+	m := metrics.New(prometheus.NewRegistry(), nil, sessions.CountActiveSessions)
+	service := services.NewPrivacyService(privacyRepo{}, nil, nil, sessions, permissions, services.NewAuditService(memory.NewAuditRepository()), outbox, m)
+	if err := service.EraseUser(services.AuditActor{UserID: 1}, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`{"session_id":1,"user_id":2}`,
+		`{"reason":"invalid_password","user_id":2}`,
+		`{"revoked_by":2,"session_id":2,"user_id":3}`,
+		`{"browser":"Safari","device":"iPhone","ip_address":"198.51.100.1","session_id":3,"user_id":3}`,
+		`{"erased_by":1,"user_id":2}`,
+	}
+	got := outbox.Events()
+	if len(got) != len(want) {
+		t.Fatalf("got %d outbox events, want %d", len(got), len(want))
+	}
+	for i, event := range got {
+		var payload map[string]interface{}
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := json.Marshal(payload); string(data) != want[i] {
+			t.Errorf("got %s event %s, want %s", event.EventType, data, want[i])
+		}
+	}
+}
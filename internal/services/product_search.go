@@ -0,0 +1,117 @@
+package services
+
+import (
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/repositories"
+	"regexp"
+	"strings"
+)
+
+const (
+	maxSearchTerms     = 8
+	maxSuggestions     = 10
+	minSuggestionInput = 2
+)
+
+// priceFacetBounds delimit the price ranges counted by product searches.
+var priceFacetBounds = []float64{25, 50, 100, 250}
+
+var searchTermPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// SearchProducts runs a ranked full-text search over product names, descriptions and categories.
+// Every term matches as a prefix, so partial words find results while typing. The response
+// includes category and price range counts for the matching products.
+func (s *ProductService) SearchProducts(req models.ProductSearchQuery) (models.ProductSearchPage, error) {
+	if req.MaxPrice > 0 && req.MinPrice > req.MaxPrice {
+		return models.ProductSearchPage{}, goAuthException.NewCustomError(goAuthException.BadRequestCode, "min_price can't exceed max_price")
+	}
+	page, pageSize := normalizePage(req.Page, req.PageSize, defaultProductPageSize, maxProductPageSize)
+
+	filter := repositories.ProductSearchFilter{
+		TSQuery:    prefixTSQuery(req.Query),
+		CategoryID: req.CategoryID,
+		MinPrice:   req.MinPrice,
+		MaxPrice:   req.MaxPrice,
+		Limit:      pageSize,
+		Offset:     (page - 1) * pageSize,
+	}
+	searchFailed := goAuthException.NewCustomError(goAuthException.InternalServerErrorCode, "Failed to search products")
+
+	hits, total, err := s.ProductRepo.SearchProducts(filter)
+	if err != nil {
+		return models.ProductSearchPage{}, searchFailed
+	}
+	categoryFacets, err := s.ProductRepo.GetCategoryFacets(filter)
+	if err != nil {
+		return models.ProductSearchPage{}, searchFailed
+	}
+	priceFacets, err := s.ProductRepo.GetPriceFacets(filter, priceFacetBounds)
+	if err != nil {
+		return models.ProductSearchPage{}, searchFailed
+	}
+
+	result := models.ProductSearchPage{
+		Items:    make([]models.ProductSearchHit, len(hits)),
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+		Facets: models.ProductSearchFacets{
+			Categories:  make([]models.CategoryFacet, len(categoryFacets)),
+			PriceRanges: priceRanges(priceFacets),
+		},
+	}
+	for i, hit := range hits {
+		result.Items[i] = models.ProductSearchHit{Product: hit.Product, Rank: hit.Rank}
+	}
+	for i, facet := range categoryFacets {
+		result.Facets.Categories[i] = models.CategoryFacet{ID: facet.CategoryID, Name: facet.Name, Count: facet.Count}
+	}
+	return result, nil
+}
+
+// SuggestProducts returns product names completing the user's input, for autocomplete.
+func (s *ProductService) SuggestProducts(input string) ([]string, error) {
+	tsQuery := prefixTSQuery(input)
+	if len([]rune(strings.TrimSpace(input))) < minSuggestionInput || tsQuery == "" {
+		return []string{}, nil
+	}
+
+	names, err := s.ProductRepo.SuggestProductNames(tsQuery, maxSuggestions)
+	if err != nil {
+		return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to suggest products")
+	}
+	return names, nil
+}
+
+// prefixTSQuery turns user input into a to_tsquery expression requiring every term as a prefix.
+// Only letters and digits are kept, so the input can't inject tsquery operators.
+func prefixTSQuery(input string) string {
+	terms := searchTermPattern.FindAllString(strings.ToLower(input), maxSearchTerms)
+	for i, term := range terms {
+		terms[i] = term + ":*"
+	}
+	return strings.Join(terms, " & ")
+}
+
+// priceRanges lists every price range with its number of matches, including empty ones.
+func priceRanges(facets []repositories.PriceFacet) []models.PriceRangeFacet {
+	counts := make(map[int]int, len(facets))
+	for _, facet := range facets {
+		counts[facet.Bucket] = facet.Count
+	}
+
+	ranges := make([]models.PriceRangeFacet, 0, len(priceFacetBounds)+1)
+	for bucket := 0; bucket <= len(priceFacetBounds); bucket++ {
+		priceRange := models.PriceRangeFacet{Count: counts[bucket]}
+		if bucket > 0 {
+			priceRange.Min = priceFacetBounds[bucket-1]
+		}
+		if bucket < len(priceFacetBounds) {
+			max := priceFacetBounds[bucket]
+			priceRange.Max = &max
+		}
+		ranges = append(ranges, priceRange)
+	}
+	return ranges
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/repositories"
+	"strings"
+)
+
+const (
+	defaultProductPageSize = 20
+	maxProductPageSize     = 100
+)
+
+// ProductService provides the product catalog.
+type ProductService struct {
+	ProductRepo       repositories.ProductRepositoryInterface
+	PermissionService *PermissionService
+}
+
+// NewProductService creates a new instance of ProductService.
+func NewProductService(productRepo repositories.ProductRepositoryInterface, permissionService *PermissionService) *ProductService {
+	return &ProductService{
+		ProductRepo:       productRepo,
+		PermissionService: permissionService,
+	}
+}
+
+// ListProducts returns a page of products matching the query.
+func (s *ProductService) ListProducts(req models.ProductQuery) (models.ProductPage, error) {
+	page, pageSize := normalizePage(req.Page, req.PageSize, defaultProductPageSize, maxProductPageSize)
+
+	products, total, err := s.ProductRepo.ListProducts(repositories.ProductFilter{
+		CategoryID: req.CategoryID,
+		UserID:     req.VendorID,
+		Query:      strings.TrimSpace(req.Query),
+		Limit:      pageSize,
+		Offset:     (page - 1) * pageSize,
+	})
+	if err != nil {
+		return models.ProductPage{}, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve products")
+	}
+
+	return models.ProductPage{
+		Items:    products,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	}, nil
+}
+
+// GetProduct returns a product by its ID.
+func (s *ProductService) GetProduct(productID int) (*entities.Product, error) {
+	product, err := s.ProductRepo.GetProductByID(productID)
+	if err != nil {
+		return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve product")
+	}
+	if product == nil {
+		return nil, goAuthException.NewCustomError(goAuthException.NotFoundCode, "Product not found")
+	}
+	return product, nil
+}
+
+// GetCategories returns all product categories.
+func (s *ProductService) GetCategories() ([]entities.Category, error) {
+	return s.ProductRepo.GetCategories()
+}
+
+// CreateProduct creates a product owned by the given user.
+func (s *ProductService) CreateProduct(userID int, req models.ProductRequest) (*entities.Product, error) {
+	if err := s.validateCategory(req.CategoryID); err != nil {
+		return nil, err
+	}
+
+	product := entities.Product{
+		Name:        req.Name,
+		Description: req.Description,
+		Price:       req.Price,
+		CategoryID:  req.CategoryID,
+		UserID:      userID,
+	}
+	productID, err := s.ProductRepo.InsertProduct(product)
+	if err != nil {
+		return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to create product")
+	}
+
+	return s.GetProduct(productID)
+}
+
+// UpdateProduct updates a product. Vendors may only update their own products.
+func (s *ProductService) UpdateProduct(userID, productID int, req models.ProductRequest) (*entities.Product, error) {
+	product, err := s.getOwnedProduct(userID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateCategory(req.CategoryID); err != nil {
+		return nil, err
+	}
+
+	product.Name = req.Name
+	product.Description = req.Description
+	product.Price = req.Price
+	product.CategoryID = req.CategoryID
+	if err := s.ProductRepo.UpdateProduct(*product); err != nil {
+		return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to update product")
+	}
+
+	return s.GetProduct(productID)
+}
+
+// DeleteProduct deletes a product. Vendors may only delete their own products.
+func (s *ProductService) DeleteProduct(userID, productID int) error {
+	if _, err := s.getOwnedProduct(userID, productID); err != nil {
+		return err
+	}
+
+	if err := s.ProductRepo.DeleteProduct(productID); err != nil {
+		return goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to delete product")
+	}
+	return nil
+}
+
+// getOwnedProduct returns a product the user may modify: their own, or any product for admins.
+func (s *ProductService) getOwnedProduct(userID, productID int) (*entities.Product, error) {
+	product, err := s.GetProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+	if product.UserID == userID {
+		return product, nil
+	}
+
+	isAdmin, err := s.PermissionService.HasPermission(userID, PermissionManageUsers)
+	if err != nil {
+		return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to check permissions")
+	}
+	if !isAdmin {
+		return nil, goAuthException.NewCustomError(goAuthException.ForbiddenCode, "You can only modify your own products")
+	}
+	return product, nil
+}
+
+func (s *ProductService) validateCategory(categoryID int) error {
+	if categoryID == 0 {
+		return nil
+	}
+	exists, err := s.ProductRepo.CategoryExists(categoryID)
+	if err != nil {
+		return goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to check category")
+	}
+	if !exists {
+		return goAuthException.NewCustomError(goAuthException.BadRequestCode, "Category not found")
+	}
+	return nil
+}
+
+// normalizePage clamps pagination parameters to sane values.
+func normalizePage(page, pageSize, defaultPageSize, maxPageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	return page, pageSize
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"backendGoAuth/internal/logging"
+	"backendGoAuth/internal/repositories"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SessionActivityTracker accumulates per-session request activity in memory and
+// periodically writes it to the database in one batch, instead of issuing an
+// UPDATE for every request.
+type SessionActivityTracker struct {
+	SessionRepo   repositories.SessionRepositoryInterface
+	FlushInterval time.Duration
+	HistoryLimit  int
+
+	mu      sync.Mutex
+	pending map[int]*repositories.SessionActivityUpdate
+}
+
+// NewSessionActivityTracker creates a new instance of SessionActivityTracker.
+func NewSessionActivityTracker(sessionRepo repositories.SessionRepositoryInterface, flushInterval time.Duration, historyLimit int) *SessionActivityTracker {
+	return &SessionActivityTracker{
+		SessionRepo:   sessionRepo,
+		FlushInterval: flushInterval,
+		HistoryLimit:  historyLimit,
+		pending:       make(map[int]*repositories.SessionActivityUpdate),
+	}
+}
+
+// Record registers a request made with the given session.
+func (t *SessionActivityTracker) Record(sessionID int, ipAddress, userAgent string) {
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	update, ok := t.pending[sessionID]
+	if !ok {
+		update = &repositories.SessionActivityUpdate{SessionID: sessionID}
+		t.pending[sessionID] = update
+	}
+	update.RequestCount++
+	update.LastSeenAt = now
+
+	// Only keep fingerprints that differ from the previous one
+	if n := len(update.Fingerprints); n > 0 {
+		last := update.Fingerprints[n-1]
+		if last.IPAddress == ipAddress && last.UserAgent == userAgent {
+			return
+		}
+	}
+	if t.HistoryLimit <= 0 {
+		return
+	}
+	// Keep the newest fingerprints, like the history trimmed in the database
+	if len(update.Fingerprints) >= t.HistoryLimit {
+		update.Fingerprints = update.Fingerprints[len(update.Fingerprints)-t.HistoryLimit+1:]
+	}
+	update.Fingerprints = append(update.Fingerprints, repositories.SessionFingerprint{
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		SeenAt:    now,
+	})
+}
+
+// Flush writes all pending activity to the database.
+func (t *SessionActivityTracker) Flush() error {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = make(map[int]*repositories.SessionActivityUpdate)
+	t.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	updates := make([]repositories.SessionActivityUpdate, 0, len(pending))
+	for _, update := range pending {
+		updates = append(updates, *update)
+	}
+	// Lock sessions in a consistent order so concurrent flushes from several instances can't deadlock
+	sort.Slice(updates, func(i, j int) bool { return updates[i].SessionID < updates[j].SessionID })
+
+	// The last flush runs after the workers' context is cancelled, so it can't be used here
+	return t.SessionRepo.FlushSessionActivity(context.Background(), updates, t.HistoryLimit)
+}
+
+// Run flushes pending activity every FlushInterval until ctx is cancelled,
+// then flushes one last time.
+func (t *SessionActivityTracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := t.Flush(); err != nil {
+				logging.FromContext(ctx).Error("Error flushing session activity", "error", err)
+			}
+			return
+		case <-ticker.C:
+			if err := t.Flush(); err != nil {
+				logging.FromContext(ctx).Error("Error flushing session activity", "error", err)
+			}
+		}
+	}
+}
@@ -2,24 +2,40 @@ package services
 
 import (
 	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/metrics"
 	"backendGoAuth/internal/models"
 	"backendGoAuth/internal/repositories"
+	"backendGoAuth/internal/tracing"
 	"backendGoAuth/internal/utils"
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
-	"log"
+	"strconv"
 	"time"
 )
 
 type SessionService struct {
-	SessionRepo *repositories.SessionRepository
+	SessionRepo       repositories.SessionRepositoryInterface
+	AuditService      *AuditService
+	PermissionService *PermissionService
+	Outbox            repositories.OutboxRepositoryInterface
+	Tokens            *utils.TokenManager
+	Metrics           *metrics.Metrics
 }
 
 // NewSessionService creates a new instance of SessionService.
-func NewSessionService(sessionRepo *repositories.SessionRepository) *SessionService {
+func NewSessionService(sessionRepo repositories.SessionRepositoryInterface, auditService *AuditService, permissionService *PermissionService,
+	outbox repositories.OutboxRepositoryInterface, tokens *utils.TokenManager, m *metrics.Metrics) *SessionService {
 	return &SessionService{
-		SessionRepo: sessionRepo,
+		SessionRepo:       sessionRepo,
+		AuditService:      auditService,
+		PermissionService: permissionService,
+		Outbox:            outbox,
+		Tokens:            tokens,
+		Metrics:           m,
 	}
 }
 
@@ -29,7 +45,22 @@ func getLocationFromIPAddress(ipAddress string) (string, error) {
 	return "Location", nil // Placeholder
 }
 
-func (s *SessionService) InsertSession(userID int, ipAddress, browser, device string) (*entities.Session, error) {
+func (s *SessionService) InsertSession(ctx context.Context, userID int, ipAddress, browser, device string) (session *entities.Session, err error) {
+	ctx, span := tracing.Start(ctx, "SessionService.InsertSession")
+	defer func() { tracing.End(span, err) }()
+
+	return s.insertSession(ctx, userID, nil, ipAddress, browser, device)
+}
+
+// InsertImpersonationSession creates a session for a user on behalf of the impersonating admin.
+func (s *SessionService) InsertImpersonationSession(ctx context.Context, userID, impersonatorID int, ipAddress, browser, device string) (session *entities.Session, err error) {
+	ctx, span := tracing.Start(ctx, "SessionService.InsertImpersonationSession")
+	defer func() { tracing.End(span, err) }()
+
+	return s.insertSession(ctx, userID, &impersonatorID, ipAddress, browser, device)
+}
+
+func (s *SessionService) insertSession(ctx context.Context, userID int, impersonatorID *int, ipAddress, browser, device string) (*entities.Session, error) {
 	now := time.Now()
 	location, err := getLocationFromIPAddress(ipAddress)
 	if err != nil {
@@ -45,49 +76,45 @@ func (s *SessionService) InsertSession(userID int, ipAddress, browser, device st
 		DeviceConnected: device,
 		BrowserUsed:     browser,
 		IsActive:        true,
+		ImpersonatorID:  impersonatorID,
 	}
 
-	// Insert the session and get the session ID
-	sessionID, err := s.SessionRepo.InsertSession(session)
+	// Insert the session and its login event together
+	err = repositories.RunInTx(s.SessionRepo.DB(), func(tx *sql.Tx) error {
+		sessionID, err := s.SessionRepo.InsertSession(ctx, tx, session)
+		if err != nil {
+			return err
+		}
+		session.ID = sessionID
+
+		return s.Outbox.Enqueue(tx, EventUserLogin, map[string]interface{}{
+			"user_id":         session.UserID,
+			"session_id":      session.ID,
+			"ip_address":      session.IPAddress,
+			"browser":         session.BrowserUsed,
+			"device":          session.DeviceConnected,
+			"impersonator_id": session.ImpersonatorID,
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Retrieve the full session with the generated ID
-	session.ID = sessionID
 	return &session, nil
 }
 
-func (s *SessionService) GetActiveSessions(userID int) ([]models.SessionResponse, error) {
+func (s *SessionService) GetActiveSessions(ctx context.Context, userID int) (sessionResponses []models.SessionResponse, err error) {
+	ctx, span := tracing.Start(ctx, "SessionService.GetActiveSessions")
+	defer func() { tracing.End(span, err) }()
+
 	// Retrieve active sessions from the repository
-	rows, err := s.SessionRepo.GetActiveSessions(userID)
+	sessions, err := s.SessionRepo.GetActiveSessions(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve active sessions: %w", err)
 	}
-	defer func() {
-		if closeErr := rows.Close(); closeErr != nil {
-			log.Printf("Error closing rows: %v\n", closeErr)
-		}
-	}()
-
-	var sessionResponses []models.SessionResponse
-
-	for rows.Next() {
-		var session entities.Session
-		if err := rows.Scan(
-			&session.ID,
-			&session.IPAddress,
-			&session.CreatedAt,
-			&session.UpdatedAt,
-			&session.Location,
-			&session.DeviceConnected,
-			&session.BrowserUsed,
-			&session.IsActive, // Ensure this is included as it is in the SELECT statement
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan session row: %w", err)
-		}
 
-		sessionResponse := models.SessionResponse{
+	for _, session := range sessions {
+		sessionResponses = append(sessionResponses, models.SessionResponse{
 			ID:              session.ID,
 			UserID:          session.UserID,
 			IPAddress:       session.IPAddress,
@@ -97,39 +124,181 @@ func (s *SessionService) GetActiveSessions(userID int) ([]models.SessionResponse
 			Location:        session.Location,
 			DeviceConnected: session.DeviceConnected,
 			BrowserUsed:     session.BrowserUsed,
-		}
+			LastSeenAt:      session.LastSeenAt,
+			LastIP:          session.LastIP,
+			RequestCount:    session.RequestCount,
+		})
+	}
 
-		sessionResponses = append(sessionResponses, sessionResponse)
+	if len(sessionResponses) == 0 {
+		return sessionResponses, nil
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating over session rows: %w", err)
+	// Attach the IP / user agent history so users can spot hijacked sessions
+	sessionIDs := make([]int, len(sessionResponses))
+	for i, sessionResponse := range sessionResponses {
+		sessionIDs[i] = sessionResponse.ID
+	}
+	activity, err := s.SessionRepo.GetSessionActivity(ctx, sessionIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve session activity: %w", err)
+	}
+	for i := range sessionResponses {
+		for _, entry := range activity[sessionResponses[i].ID] {
+			sessionResponses[i].Activity = append(sessionResponses[i].Activity, models.SessionActivityResponse{
+				IPAddress: entry.IPAddress,
+				UserAgent: entry.UserAgent,
+				SeenAt:    entry.CreatedAt,
+			})
+		}
 	}
 
 	return sessionResponses, nil
 }
 
-func (s *SessionService) RevokeCurrentSessionToken(tokenString string) error {
-	claims, err := utils.ValidateToken(tokenString)
+// RevokeCurrentSessionToken logs out the session the token belongs to.
+func (s *SessionService) RevokeCurrentSessionToken(ctx context.Context, tokenString string, actor AuditActor) (err error) {
+	ctx, span := tracing.Start(ctx, "SessionService.RevokeCurrentSessionToken")
+	defer func() { tracing.End(span, err) }()
+
+	claims, err := s.Tokens.ValidateToken(ctx, tokenString)
 	if err != nil {
 		return err
 	}
 
-	return s.SessionRepo.RevokeCurrentSession(int(claims["session_id"].(float64)))
+	sessionID, ok := claims["session_id"].(float64)
+	if !ok {
+		return errors.New("session ID not found in claims or not of the expected type")
+	}
+	if err := s.revokeSession(ctx, int(sessionID), actor, "logout"); err != nil {
+		return err
+	}
+
+	s.AuditService.RecordBestEffort(AuditEvent{
+		Actor:     actor,
+		TargetID:  actor.UserID,
+		Action:    AuditActionLogout,
+		TableName: "user_sessions",
+		RowID:     int(sessionID),
+	})
+	return nil
 }
 
-func (s *SessionService) RevokeSession(sessionID string) error {
+func (s *SessionService) RevokeSession(ctx context.Context, sessionID string, actor AuditActor) (err error) {
+	ctx, span := tracing.Start(ctx, "SessionService.RevokeSession")
+	defer func() { tracing.End(span, err) }()
+
+	id, err := strconv.Atoi(sessionID)
+	if err != nil {
+		return goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid session ID")
+	}
+
+	// Other users' sessions look the same as ones that don't exist, unless the actor manages users
+	session, err := s.SessionRepo.GetSessionOwners(ctx, id)
+	if err != nil {
+		return goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve session")
+	}
+	if session == nil || !ownsSession(session, actor.UserID) {
+		canManage, err := s.PermissionService.HasPermission(actor.UserID, PermissionManageUsers)
+		if err != nil {
+			return goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to check permissions")
+		}
+		if session == nil || !canManage {
+			return goAuthException.NewCustomError(goAuthException.NotFoundCode, "Session not found").WithErrorCode(goAuthException.ErrorCodeSessionNotFound)
+		}
+	}
+
 	// Mark the session as inactive using the repository
-	err := s.SessionRepo.RevokeSession(sessionID)
+	if err := s.revokeSession(ctx, id, actor, "revoked"); err != nil {
+		return err
+	}
+
+	s.AuditService.RecordBestEffort(AuditEvent{
+		Actor:     actor,
+		Action:    AuditActionSessionRevoked,
+		TableName: "user_sessions",
+		RowID:     id,
+	})
+	return nil
+}
+
+// ownsSession checks if a session belongs to the user, or is one they impersonate someone with.
+func ownsSession(session *entities.Session, userID int) bool {
+	return session.UserID == userID || (session.ImpersonatorID != nil && *session.ImpersonatorID == userID)
+}
+
+// revokeSession marks a session inactive and records the session.revoked event in the
+// same transaction, then drops the session from every instance's cache.
+func (s *SessionService) revokeSession(ctx context.Context, sessionID int, actor AuditActor, reason string) error {
+	err := repositories.RunInTx(s.SessionRepo.DB(), func(tx *sql.Tx) error {
+		userID, err := s.SessionRepo.RevokeSession(ctx, tx, sessionID)
+		if err != nil {
+			return err
+		}
+
+		return s.Outbox.Enqueue(tx, EventSessionRevoked, map[string]interface{}{
+			"user_id":    userID,
+			"session_id": sessionID,
+			"revoked_by": actor.UserID,
+			"reason":     reason,
+			"ip_address": actor.IPAddress,
+		})
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return goAuthException.NewCustomError(goAuthException.NotFoundCode, "Session not found").WithErrorCode(goAuthException.ErrorCodeSessionNotFound)
+	}
 	if err != nil {
-		// Handle any errors
 		return err
 	}
+
+	s.SessionRepo.InvalidateSession(ctx, sessionID)
+	s.Metrics.SessionsRevoked(reason, 1)
 	return nil
 }
 
-func (s *SessionService) UpdateSessionUpdatedAt(userID int) error {
-	err := s.SessionRepo.UpdateSessionUpdatedAt(userID)
+// ExpireImpersonationSessions revokes the impersonation sessions older than maxAge, whose
+// tokens have expired, recording their session.revoked events in the same transaction.
+func (s *SessionService) ExpireImpersonationSessions(ctx context.Context, maxAge time.Duration) (sessions []entities.Session, err error) {
+	ctx, span := tracing.Start(ctx, "SessionService.ExpireImpersonationSessions")
+	defer func() { tracing.End(span, err) }()
+
+	err = repositories.RunInTx(s.SessionRepo.DB(), func(tx *sql.Tx) error {
+		sessions, err = s.SessionRepo.ExpireImpersonationSessions(ctx, tx, time.Now().Add(-maxAge))
+		if err != nil {
+			return err
+		}
+
+		for _, session := range sessions {
+			err := s.Outbox.Enqueue(tx, EventSessionRevoked, map[string]interface{}{
+				"user_id":    session.UserID,
+				"session_id": session.ID,
+				"revoked_by": session.ImpersonatorID,
+				"reason":     "impersonation_expired",
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		s.SessionRepo.InvalidateSession(ctx, session.ID)
+	}
+	if len(sessions) > 0 {
+		s.Metrics.SessionsRevoked("impersonation_expired", len(sessions))
+	}
+	return sessions, nil
+}
+
+func (s *SessionService) UpdateSessionUpdatedAt(ctx context.Context, userID int) (err error) {
+	ctx, span := tracing.Start(ctx, "SessionService.UpdateSessionUpdatedAt")
+	defer func() { tracing.End(span, err) }()
+
+	err = s.SessionRepo.UpdateSessionUpdatedAt(ctx, userID)
 	if err != nil {
 		// Handle any errors
 		return err
@@ -137,7 +306,10 @@ func (s *SessionService) UpdateSessionUpdatedAt(userID int) error {
 	return nil
 }
 
-func (svc *SessionService) GetUserIDFromTokenOrSource(c *gin.Context) (int, error) {
+func (svc *SessionService) GetUserIDFromTokenOrSource(c *gin.Context) (userID int, err error) {
+	ctx, span := tracing.Start(c.Request.Context(), "SessionService.GetUserIDFromTokenOrSource")
+	defer func() { tracing.End(span, err) }()
+
 	// Extract the JWT token from the request headers
 	tokenString, err := utils.ExtractToken(c)
 	if err != nil {
@@ -145,13 +317,13 @@ func (svc *SessionService) GetUserIDFromTokenOrSource(c *gin.Context) (int, erro
 	}
 
 	// Validate the token and retrieve the claims
-	claims, err := utils.ValidateToken(tokenString)
+	claims, err := svc.Tokens.ValidateToken(ctx, tokenString)
 	if err != nil {
 		return 0, err
 	}
 
 	// Check the session using the CheckSession method
-	sessionValid, err := svc.SessionRepo.CheckSession(int(claims["session_id"].(float64)))
+	sessionValid, err := svc.SessionRepo.CheckSession(ctx, int(claims["session_id"].(float64)))
 	if err != nil {
 		return 0, err
 	}
@@ -168,9 +340,12 @@ func (svc *SessionService) GetUserIDFromTokenOrSource(c *gin.Context) (int, erro
 	return int(userIDFloat64), nil
 }
 
-func (svc *SessionService) GetSessionByID(sessionID int) (*entities.Session, error) {
+func (svc *SessionService) GetSessionByID(ctx context.Context, sessionID int) (session *entities.Session, err error) {
+	ctx, span := tracing.Start(ctx, "SessionService.GetSessionByID")
+	defer func() { tracing.End(span, err) }()
+
 	// Fetch the session by ID using the repository
-	session, err := svc.SessionRepo.GetSessionByID(sessionID)
+	session, err = svc.SessionRepo.GetSessionByID(ctx, sessionID)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching session: %v", err)
 	}
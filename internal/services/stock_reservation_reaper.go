@@ -0,0 +1,68 @@
+package services
+
+import (
+	"backendGoAuth/internal/logging"
+	"backendGoAuth/internal/repositories"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// stockReaperBatchSize bounds how many expired orders are canceled per pass.
+const stockReaperBatchSize = 100
+
+// StockReservationReaper cancels unpaid orders whose stock reservation expired, so the stock
+// becomes available again.
+type StockReservationReaper struct {
+	OrderService  *OrderService
+	InventoryRepo repositories.InventoryRepositoryInterface
+	Interval      time.Duration
+}
+
+// NewStockReservationReaper creates a new instance of StockReservationReaper.
+func NewStockReservationReaper(orderService *OrderService, inventoryRepo repositories.InventoryRepositoryInterface, interval time.Duration) *StockReservationReaper {
+	return &StockReservationReaper{
+		OrderService:  orderService,
+		InventoryRepo: inventoryRepo,
+		Interval:      interval,
+	}
+}
+
+// ReapOnce cancels one batch of expired orders and returns how many it canceled.
+func (r *StockReservationReaper) ReapOnce() (int, error) {
+	orderIDs, err := r.InventoryRepo.GetOrdersWithExpiredReservations(stockReaperBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	canceled := 0
+	for _, orderID := range orderIDs {
+		if err := r.OrderService.ExpireOrder(orderID); err != nil {
+			slog.Error("Error expiring order", "order_id", orderID, "error", err)
+			continue
+		}
+		canceled++
+	}
+	return canceled, nil
+}
+
+// Run reaps expired reservations every Interval until ctx is cancelled.
+func (r *StockReservationReaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			canceled, err := r.ReapOnce()
+			if err != nil {
+				logging.FromContext(ctx).Error("Error reaping stock reservations", "error", err)
+			}
+			if canceled > 0 {
+				logging.FromContext(ctx).Info("Canceled unpaid orders with expired stock reservations", "count", canceled)
+			}
+		}
+	}
+}
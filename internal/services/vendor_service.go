@@ -0,0 +1,190 @@
+package services
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/repositories"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"math"
+	"strings"
+)
+
+// VendorRole is the role granted to customers whose vendor application is approved.
+const VendorRole = "Vendor"
+
+// Vendor application statuses
+const (
+	VendorApplicationPending  = "PENDING"
+	VendorApplicationApproved = "APPROVED"
+	VendorApplicationRejected = "REJECTED"
+)
+
+// VendorService handles vendor onboarding and the vendor dashboard.
+type VendorService struct {
+	VendorRepo        repositories.VendorRepositoryInterface
+	UserRepo          repositories.UserRepositoryInterface
+	PermissionService *PermissionService
+	AuditService      *AuditService
+	Outbox            repositories.OutboxRepositoryInterface
+}
+
+// NewVendorService creates a new instance of VendorService.
+func NewVendorService(vendorRepo repositories.VendorRepositoryInterface, userRepo repositories.UserRepositoryInterface, permissionService *PermissionService,
+	auditService *AuditService, outbox repositories.OutboxRepositoryInterface) *VendorService {
+	return &VendorService{
+		VendorRepo:        vendorRepo,
+		UserRepo:          userRepo,
+		PermissionService: permissionService,
+		AuditService:      auditService,
+		Outbox:            outbox,
+	}
+}
+
+// SubmitApplication files a store profile for review by the admins.
+func (s *VendorService) SubmitApplication(userID int, req models.VendorApplicationRequest) (*entities.VendorApplication, error) {
+	isVendor, err := s.PermissionService.PermissionRepo.UserHasRole(userID, VendorRole)
+	if err != nil {
+		return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to check roles")
+	}
+	if isVendor {
+		return nil, goAuthException.NewCustomError(goAuthException.ConflictCode, "You are already a vendor")
+	}
+
+	application := &entities.VendorApplication{
+		UserID:       userID,
+		StoreName:    strings.TrimSpace(req.StoreName),
+		Description:  strings.TrimSpace(req.Description),
+		ContactEmail: strings.TrimSpace(req.ContactEmail),
+	}
+	if application.StoreName == "" {
+		return nil, goAuthException.NewCustomError(goAuthException.BadRequestCode, "Store name is required")
+	}
+
+	err = s.VendorRepo.InsertApplication(application)
+	if errors.Is(err, repositories.ErrPendingApplicationExists) {
+		return nil, goAuthException.NewCustomError(goAuthException.ConflictCode, "You already have an application waiting for review")
+	}
+	if err != nil {
+		return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to submit application")
+	}
+	return application, nil
+}
+
+// GetUserApplications returns a user's applications, oldest first.
+func (s *VendorService) GetUserApplications(userID int) ([]entities.VendorApplication, error) {
+	return s.getApplications(userID, "")
+}
+
+// GetApplications returns all applications, optionally only those in a status, for admins.
+func (s *VendorService) GetApplications(status string) ([]entities.VendorApplication, error) {
+	status = strings.ToUpper(strings.TrimSpace(status))
+	switch status {
+	case "", VendorApplicationPending, VendorApplicationApproved, VendorApplicationRejected:
+	default:
+		return nil, goAuthException.NewCustomError(goAuthException.BadRequestCode, "Unknown application status")
+	}
+	return s.getApplications(0, status)
+}
+
+// ApproveApplication grants the applicant the Vendor role. The role change is announced through
+// the outbox, and every instance drops the applicant's cached permissions, so it applies to their
+// sessions from the next request on.
+func (s *VendorService) ApproveApplication(reviewer AuditActor, applicationID int, req models.VendorApplicationReviewRequest) (*entities.VendorApplication, error) {
+	var application *entities.VendorApplication
+	err := repositories.RunInTx(s.VendorRepo.DB(), func(tx *sql.Tx) error {
+		var err error
+		application, err = s.VendorRepo.ReviewApplication(tx, applicationID, VendorApplicationApproved, reviewer.UserID, strings.TrimSpace(req.Note))
+		if err != nil || application == nil {
+			return err
+		}
+		if err := s.UserRepo.AssignRole(context.Background(), tx, application.UserID, VendorRole); err != nil {
+			return err
+		}
+		return s.Outbox.Enqueue(tx, EventRoleChanged, map[string]interface{}{
+			"user_id":    application.UserID,
+			"role":       VendorRole,
+			"change":     "granted",
+			"changed_by": reviewer.UserID,
+		})
+	})
+	if err != nil {
+		slog.Error("Error approving vendor application", "application_id", applicationID, "error", err)
+		return nil, goAuthException.NewCustomError(goAuthException.InternalServerErrorCode, "Failed to approve application")
+	}
+	if application == nil {
+		return nil, goAuthException.NewCustomError(goAuthException.NotFoundCode, "No pending application found")
+	}
+
+	s.PermissionService.InvalidateUser(application.UserID)
+	s.AuditService.RecordBestEffort(AuditEvent{
+		Actor:     reviewer,
+		TargetID:  application.UserID,
+		Action:    AuditActionVendorApproved,
+		TableName: "vendor_applications",
+		RowID:     application.ID,
+		Before:    map[string]string{"status": VendorApplicationPending},
+		After:     map[string]string{"status": VendorApplicationApproved, "role": VendorRole},
+	})
+	return application, nil
+}
+
+// RejectApplication closes an application without changing the applicant's roles.
+func (s *VendorService) RejectApplication(reviewer AuditActor, applicationID int, req models.VendorApplicationReviewRequest) (*entities.VendorApplication, error) {
+	var application *entities.VendorApplication
+	err := repositories.RunInTx(s.VendorRepo.DB(), func(tx *sql.Tx) error {
+		var err error
+		application, err = s.VendorRepo.ReviewApplication(tx, applicationID, VendorApplicationRejected, reviewer.UserID, strings.TrimSpace(req.Note))
+		return err
+	})
+	if err != nil {
+		return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to reject application")
+	}
+	if application == nil {
+		return nil, goAuthException.NewCustomError(goAuthException.NotFoundCode, "No pending application found")
+	}
+
+	s.AuditService.RecordBestEffort(AuditEvent{
+		Actor:     reviewer,
+		TargetID:  application.UserID,
+		Action:    AuditActionVendorRejected,
+		TableName: "vendor_applications",
+		RowID:     application.ID,
+		Before:    map[string]string{"status": VendorApplicationPending},
+		After:     map[string]string{"status": VendorApplicationRejected},
+	})
+	return application, nil
+}
+
+// GetDashboard summarizes the vendor's store, products and orders.
+func (s *VendorService) GetDashboard(vendorID int) (models.VendorDashboard, error) {
+	store, err := s.VendorRepo.GetApprovedApplication(vendorID)
+	if err != nil {
+		return models.VendorDashboard{}, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve store")
+	}
+	dashboard, err := s.VendorRepo.GetDashboard(vendorID)
+	if err != nil {
+		return models.VendorDashboard{}, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve dashboard")
+	}
+
+	return models.VendorDashboard{
+		Store:           store,
+		ProductCount:    dashboard.ProductCount,
+		LowStockCount:   dashboard.LowStockCount,
+		OutOfStockCount: dashboard.OutOfStockCount,
+		OrdersByStatus:  dashboard.OrdersByStatus,
+		UnitsSold:       dashboard.UnitsSold,
+		Revenue:         math.Round(dashboard.Revenue*100) / 100,
+	}, nil
+}
+
+func (s *VendorService) getApplications(userID int, status string) ([]entities.VendorApplication, error) {
+	applications, err := s.VendorRepo.GetApplications(userID, status)
+	if err != nil {
+		return nil, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to retrieve applications")
+	}
+	return applications, nil
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"backendGoAuth/internal/logging"
+	"backendGoAuth/internal/repositories"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	webhookBatchSize    = 100
+	webhookMaxBackoff   = time.Hour
+	webhookErrorMaxSize = 1024
+)
+
+// webhookPayload is the body POSTed to webhook endpoints.
+type webhookPayload struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// WebhookDispatcher delivers outbox events to the subscribed webhook endpoints.
+// Several instances can run concurrently; deliveries are leased with SKIP LOCKED.
+type WebhookDispatcher struct {
+	WebhookRepo  repositories.WebhookRepositoryInterface
+	Client       *http.Client
+	PollInterval time.Duration
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+}
+
+// NewWebhookDispatcher creates a new instance of WebhookDispatcher.
+func NewWebhookDispatcher(webhookRepo repositories.WebhookRepositoryInterface, pollInterval time.Duration, maxAttempts int) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		WebhookRepo:  webhookRepo,
+		Client:       &http.Client{Timeout: 10 * time.Second},
+		PollInterval: pollInterval,
+		MaxAttempts:  maxAttempts,
+		BaseBackoff:  30 * time.Second,
+	}
+}
+
+// Run dispatches events every PollInterval until ctx is cancelled.
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.DispatchOnce(ctx)
+		}
+	}
+}
+
+// DispatchOnce fans out new outbox events and sends the deliveries that are due.
+func (d *WebhookDispatcher) DispatchOnce(ctx context.Context) {
+	if _, err := d.WebhookRepo.FanOutOutbox(webhookBatchSize); err != nil {
+		logging.FromContext(ctx).Error("Error fanning out outbox events", "error", err)
+	}
+
+	// Lease deliveries for longer than a request can take so no other dispatcher picks them up
+	deliveries, err := d.WebhookRepo.ClaimDueDeliveries(webhookBatchSize, 2*d.Client.Timeout)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error claiming webhook deliveries", "error", err)
+		return
+	}
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			// Unsent deliveries become due again when their lease expires
+			return
+		}
+		d.deliver(ctx, delivery)
+	}
+}
+
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery repositories.ClaimedDelivery) {
+	statusCode, err := d.send(ctx, delivery)
+	if err == nil {
+		if err := d.WebhookRepo.MarkDelivered(delivery.ID, statusCode); err != nil {
+			logging.FromContext(ctx).Error("Error marking webhook delivery as delivered", "delivery_id", delivery.ID, "error", err)
+		}
+		return
+	}
+
+	attempts := delivery.Attempts + 1
+	var nextAttemptAt *time.Time
+	if attempts < d.MaxAttempts {
+		next := time.Now().Add(d.backoff(attempts))
+		nextAttemptAt = &next
+	} else {
+		logging.FromContext(ctx).Warn("Webhook delivery dead-lettered", "delivery_id", delivery.ID, "attempts", attempts, "error", err)
+	}
+
+	errMessage := err.Error()
+	if len(errMessage) > webhookErrorMaxSize {
+		errMessage = errMessage[:webhookErrorMaxSize]
+	}
+	_ = d.WebhookRepo.MarkFailed(delivery.ID, statusCode, errMessage, nextAttemptAt)
+}
+
+// send POSTs the signed event and returns the response status code.
+func (d *WebhookDispatcher) send(ctx context.Context, delivery repositories.ClaimedDelivery) (int, error) {
+	body, err := json.Marshal(webhookPayload{
+		ID:        delivery.Event.ID,
+		Type:      delivery.Event.EventType,
+		CreatedAt: delivery.Event.CreatedAt,
+		Data:      delivery.Event.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GoAuth-Event", delivery.Event.EventType)
+	req.Header.Set("X-GoAuth-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-GoAuth-Timestamp", timestamp)
+	req.Header.Set("X-GoAuth-Signature", "sha256="+SignWebhookPayload(delivery.Secret, timestamp, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+		if err := resp.Body.Close(); err != nil {
+			logging.FromContext(ctx).Error("Error closing webhook response body", "error", err)
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the exponential delay before the given retry attempt.
+func (d *WebhookDispatcher) backoff(attempt int) time.Duration {
+	delay := d.BaseBackoff
+	for i := 1; i < attempt && delay < webhookMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > webhookMaxBackoff {
+		delay = webhookMaxBackoff
+	}
+	return delay
+}
+
+// SignWebhookPayload computes the hex HMAC-SHA256 of "timestamp.body" with the endpoint secret.
+// Receivers recompute it to authenticate the payload and reject stale timestamps to prevent replays.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/goAuthException"
+	"backendGoAuth/internal/models"
+	"backendGoAuth/internal/repositories"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+// Security events streamed to webhook endpoints
+const (
+	EventUserLogin       = "user.login"
+	EventUserLoginFailed = "user.login_failed"
+	EventUserLocked      = "user.locked"
+	EventSessionRevoked  = "session.revoked"
+	EventRoleChanged     = "role.changed"
+	EventUserErased      = "user.erased"
+)
+
+// webhookEventTypes are the event types endpoints can subscribe to; "*" subscribes to all.
+var webhookEventTypes = map[string]bool{
+	EventUserLogin:       true,
+	EventUserLoginFailed: true,
+	EventUserLocked:      true,
+	EventSessionRevoked:  true,
+	EventRoleChanged:     true,
+	EventUserErased:      true,
+	"*":                  true,
+}
+
+const maxWebhookDeliveriesListed = 200
+
+// WebhookService manages webhook endpoints and deliveries.
+type WebhookService struct {
+	WebhookRepo repositories.WebhookRepositoryInterface
+}
+
+// NewWebhookService creates a new instance of WebhookService.
+func NewWebhookService(webhookRepo repositories.WebhookRepositoryInterface) *WebhookService {
+	return &WebhookService{
+		WebhookRepo: webhookRepo,
+	}
+}
+
+// RegisterEndpoint registers a webhook endpoint. The signing secret is generated when
+// not provided and is only ever returned here.
+func (s *WebhookService) RegisterEndpoint(req models.WebhookEndpointRequest, createdBy int) (models.WebhookEndpointResponse, error) {
+	parsed, err := url.Parse(req.URL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return models.WebhookEndpointResponse{}, goAuthException.NewCustomError(goAuthException.BadRequestCode, "Invalid webhook URL")
+	}
+	for _, eventType := range req.EventTypes {
+		if !webhookEventTypes[eventType] {
+			return models.WebhookEndpointResponse{}, goAuthException.NewCustomError(goAuthException.BadRequestCode, "Unknown event type: "+eventType)
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		secret, err = generateWebhookSecret()
+		if err != nil {
+			return models.WebhookEndpointResponse{}, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, goAuthException.InternalErrorMessage)
+		}
+	}
+
+	endpoint := entities.WebhookEndpoint{
+		URL:        req.URL,
+		Secret:     secret,
+		EventTypes: req.EventTypes,
+		IsActive:   true,
+		CreatedBy:  optionalID(createdBy),
+	}
+	endpoint.ID, err = s.WebhookRepo.InsertEndpoint(endpoint)
+	if err != nil {
+		return models.WebhookEndpointResponse{}, goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to register webhook endpoint")
+	}
+
+	return models.WebhookEndpointResponse{WebhookEndpoint: endpoint, Secret: secret}, nil
+}
+
+// GetEndpoints lists the active webhook endpoints.
+func (s *WebhookService) GetEndpoints() ([]entities.WebhookEndpoint, error) {
+	return s.WebhookRepo.GetEndpoints()
+}
+
+// DeleteEndpoint stops deliveries to a webhook endpoint.
+func (s *WebhookService) DeleteEndpoint(endpointID int) error {
+	found, err := s.WebhookRepo.DeactivateEndpoint(endpointID)
+	if err != nil {
+		return goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to delete webhook endpoint")
+	}
+	if !found {
+		return goAuthException.NewCustomError(goAuthException.NotFoundCode, "Webhook endpoint not found")
+	}
+	return nil
+}
+
+// GetDeliveries lists the most recent deliveries, optionally filtered by status.
+func (s *WebhookService) GetDeliveries(status string) ([]entities.WebhookDelivery, error) {
+	return s.WebhookRepo.GetDeliveries(strings.ToUpper(status), maxWebhookDeliveriesListed)
+}
+
+// ReplayDelivery schedules a delivery, typically a dead-lettered one, to be sent again.
+func (s *WebhookService) ReplayDelivery(deliveryID int64) error {
+	found, err := s.WebhookRepo.ReplayDelivery(deliveryID)
+	if err != nil {
+		return goAuthException.Wrap(err, goAuthException.InternalServerErrorCode, "Failed to replay webhook delivery")
+	}
+	if !found {
+		return goAuthException.NewCustomError(goAuthException.NotFoundCode, "Webhook delivery not found")
+	}
+	return nil
+}
+
+func generateWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
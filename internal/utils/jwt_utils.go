@@ -3,49 +3,46 @@ package utils
 //JwtUtils
 
 import (
-	"backendGoAuth/internal/repositories"
+	"backendGoAuth/internal/config"
+	"backendGoAuth/internal/entities"
+	"backendGoAuth/internal/logging"
+	"backendGoAuth/internal/metrics"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"log"
-	"os"
+	"log/slog"
 	"time"
-
-	"github.com/joho/godotenv"
 )
 
 var (
 	errNoToken = errors.New("no token provided")
 )
 
-var (
-	JwtSecret   string
-	jwtDuration time.Duration
-	sessionSvc  *repositories.SessionRepository
-)
-
-func init() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatalf("Error loading .env file: %s", err)
-	}
-
-	JwtSecret = os.Getenv("JWT_SECRET")
-	jwtDurationStr := os.Getenv("JWT_DURATION_HOURS")
-
-	log.Printf("JWT_SECRET: %s", JwtSecret)
-	log.Printf("JWT_DURATION_HOURS: %s", jwtDurationStr)
+// SessionGetter looks up sessions, so validated tokens of revoked sessions are rejected.
+type SessionGetter interface {
+	GetSessionByID(ctx context.Context, sessionID int) (*entities.Session, error)
+}
 
-	jwtDuration, err = time.ParseDuration(jwtDurationStr + "h")
-	if err != nil {
-		log.Fatalf("Error parsing JWT duration: %s", err)
-	}
-	log.Printf("JWT duration: %s", jwtDuration)
+// TokenManager issues and validates JWTs and the cookies carrying them.
+type TokenManager struct {
+	Secret                string
+	Duration              time.Duration
+	ImpersonationDuration time.Duration
+	Sessions              SessionGetter
+	Metrics               *metrics.Metrics
 }
 
-func SetSessionService(repo *repositories.SessionRepository) {
-	sessionSvc = repo
+// NewTokenManager creates a new instance of TokenManager.
+func NewTokenManager(cfg config.JWTConfig, sessions SessionGetter, m *metrics.Metrics) *TokenManager {
+	return &TokenManager{
+		Secret:                cfg.Secret,
+		Duration:              cfg.Duration,
+		ImpersonationDuration: min(cfg.ImpersonationDuration, config.MaxImpersonationDuration),
+		Sessions:              sessions,
+		Metrics:               m,
+	}
 }
 
 // ExtractToken extracts the JWT token from the cookies.
@@ -59,13 +56,21 @@ func ExtractToken(c *gin.Context) (string, error) {
 }
 
 // GenerateJWT generates a new JWT token with the specified claims and token type.
-func GenerateJWT(claims jwt.MapClaims, tokenType string, sessionID int) (string, error) {
+func (m *TokenManager) GenerateJWT(claims jwt.MapClaims, tokenType string, sessionID int) (string, error) {
 	// Set the expiration time for the token
 	if tokenType == "access" {
-		claims["exp"] = time.Now().Add(jwtDuration).Unix()
+		claims["exp"] = time.Now().Add(m.Duration).Unix()
 	} else if tokenType == "refresh" {
 		// Longer duration for refresh tokens
 		claims["exp"] = time.Now().Add(7 * 24 * time.Hour).Unix() // 7 days for refresh token
+	} else if tokenType == "impersonation" {
+		// Short-lived token for an admin acting as another user, identified by the act claim
+		impersonatorID, ok := claims["impersonator_id"]
+		if !ok {
+			return "", errors.New("impersonation token requires an impersonator_id claim")
+		}
+		claims["act"] = map[string]interface{}{"sub": impersonatorID}
+		claims["exp"] = time.Now().Add(m.ImpersonationDuration).Unix()
 	}
 
 	// Include the session_id claim
@@ -75,9 +80,9 @@ func GenerateJWT(claims jwt.MapClaims, tokenType string, sessionID int) (string,
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	// Sign the token with the secret key
-	tokenString, err := token.SignedString([]byte(JwtSecret))
+	tokenString, err := token.SignedString([]byte(m.Secret))
 	if err != nil {
-		fmt.Println("Error signing token:", err) // Log error if signing fails
+		slog.Error("Error signing token", "error", err)
 		return "", err
 	}
 
@@ -85,69 +90,103 @@ func GenerateJWT(claims jwt.MapClaims, tokenType string, sessionID int) (string,
 }
 
 // SetJWTTokenCookies sets JWT access and refresh tokens as HttpOnly cookies.
-func SetJWTTokenCookies(c *gin.Context, accessToken string) {
+func (m *TokenManager) SetJWTTokenCookies(c *gin.Context, accessToken string) {
 	// Set access token as HttpOnly cookie
-	c.SetCookie("access_token", accessToken, int(jwtDuration.Seconds()), "/", "", false, true) //if we use prodlike https secure should be true
+	c.SetCookie("access_token", accessToken, int(m.Duration.Seconds()), "/", "", false, true) //if we use prodlike https secure should be true
 	// Optionally set a refresh token cookie if you have one
 	// c.SetCookie("refresh_token", refreshToken, 7*24*60*60, "/", "", true, true) // 7 days for refresh token
 }
 
+// SetImpersonationCookies replaces the access token with an impersonation token,
+// keeping the admin's own token aside so it can be restored when impersonation stops.
+func (m *TokenManager) SetImpersonationCookies(c *gin.Context, impersonationToken, originalToken string) {
+	c.SetCookie("impersonator_token", originalToken, int(m.Duration.Seconds()), "/", "", false, true)
+	c.SetCookie("access_token", impersonationToken, int(m.ImpersonationDuration.Seconds()), "/", "", false, true)
+}
+
+// ClearImpersonationCookies restores the admin's own access token, returning it.
+func (m *TokenManager) ClearImpersonationCookies(c *gin.Context) string {
+	originalToken, err := c.Cookie("impersonator_token")
+	c.SetCookie("impersonator_token", "", -1, "/", "", false, true)
+	if err != nil || originalToken == "" {
+		c.SetCookie("access_token", "", -1, "/", "", false, true)
+		return ""
+	}
+	c.SetCookie("access_token", originalToken, int(m.Duration.Seconds()), "/", "", false, true)
+	return originalToken
+}
+
+// ImpersonatorIDFromClaims returns the ID of the admin impersonating the token's user, if any.
+func ImpersonatorIDFromClaims(claims jwt.MapClaims) (int, bool) {
+	impersonatorID, ok := claims["impersonator_id"].(float64)
+	if !ok {
+		return 0, false
+	}
+	return int(impersonatorID), true
+}
+
+// CheckSigningKey returns an error if tokens can't be signed, such as when no secret is configured.
+func (m *TokenManager) CheckSigningKey() error {
+	if m.Secret == "" {
+		return errors.New("JWT secret is not set")
+	}
+	_, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{}).SignedString([]byte(m.Secret))
+	return err
+}
+
 // ValidateToken validates a JWT token and returns the claims.
-func ValidateToken(tokenString string) (jwt.MapClaims, error) {
+func (m *TokenManager) ValidateToken(ctx context.Context, tokenString string) (claims jwt.MapClaims, err error) {
+	start := time.Now()
+	defer func() { m.Metrics.ObserveTokenValidation(ctx, start, err) }()
+
+	logger := logging.FromContext(ctx)
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		// Ensure the token uses the correct signing method
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			fmt.Println("Unexpected signing method:", token.Header["alg"])
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 
 		// Return the secret key as a byte slice
-		return []byte(JwtSecret), nil
+		return []byte(m.Secret), nil
 	})
 
 	if err != nil {
-		fmt.Println("Error validating token:", err) // Log the error for debugging
+		logger.Debug("Error validating token", "error", err)
 		return nil, err
 	}
 
 	if !token.Valid {
-		fmt.Println("Token is invalid")
+		logger.Debug("Token is invalid")
 		return nil, errors.New("invalid token") // Token is invalid
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		fmt.Println("Invalid claims type")
+		logger.Debug("Invalid claims type")
 		return nil, errors.New("invalid claims") // Claims are not of expected type
 	}
 
-	// Print the token claims for debugging
-	fmt.Println("Token claims:", claims)
-	fmt.Println("JwtSecret:", JwtSecret)
-
 	//fixme something wrong happends here after user sucesfully logged in when trying to get these
 	// Extract session ID from token (ensure `getSessionIDFromToken` works correctly)
 	sessionID, err := getSessionIDFromToken(tokenString)
 	if err != nil {
-		fmt.Println("Session not found:", err)
+		logger.Debug("Session not found", "error", err)
 		return nil, errors.New("session not found") // Session ID could not be retrieved
 	}
 
-	if sessionSvc == nil {
-		fmt.Println("sessionSvc is nil")
-	}
-
 	// Check if the session is revoked
-	session, err := sessionSvc.GetSessionByID(sessionID)
+	session, err := m.Sessions.GetSessionByID(ctx, sessionID)
 	if err != nil {
-		fmt.Println("Error fetching session:", err)
+		logger.Error("Error fetching session", "session_id", sessionID, "error", err)
 		return nil, errors.New("error fetching session") // Error fetching session
 	}
-
-	println(session)
+	if session == nil {
+		logger.Debug("Session not found", "session_id", sessionID)
+		return nil, errors.New("session not found")
+	}
 
 	if !session.IsActive {
-		fmt.Println("Session is revoked")
+		logger.Debug("Session is revoked", "session_id", sessionID)
 		return nil, errors.New("session revoked") // Session is revoked
 	}
 
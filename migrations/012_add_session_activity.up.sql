@@ -0,0 +1,21 @@
+-- 012_add_session_activity.up.sql
+
+-- Track when and from where a session was last used
+ALTER TABLE user_sessions
+    ADD COLUMN last_seen_at    TIMESTAMP,
+    ADD COLUMN last_ip         VARCHAR(255),
+    ADD COLUMN last_user_agent TEXT,
+    ADD COLUMN request_count   BIGINT DEFAULT 0;
+
+-- History of IP / user agent changes during a session, capped per session by the application
+CREATE TABLE user_session_activity
+(
+    id         SERIAL PRIMARY KEY,
+    session_id INT          NOT NULL,
+    ip_address VARCHAR(255) NOT NULL,
+    user_agent TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (session_id) REFERENCES user_sessions (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_user_session_activity_session_id ON user_session_activity (session_id, id);
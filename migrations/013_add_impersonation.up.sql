@@ -0,0 +1,13 @@
+-- 013_add_impersonation.up.sql
+
+-- Sessions created by a support admin acting as another user
+ALTER TABLE user_sessions
+    ADD COLUMN impersonator_id INT,
+    ADD CONSTRAINT fk_user_sessions_impersonator_id FOREIGN KEY (impersonator_id) REFERENCES users (id);
+
+INSERT INTO permissions (name, description)
+VALUES ('IMPERSONATE_USERS', 'Permission to log in as another user for support purposes');
+
+-- Admin can IMPERSONATE_USERS
+INSERT INTO role_permissions (role_id, permission_id)
+VALUES ((SELECT id FROM roles WHERE name = 'Admin'), (SELECT id FROM permissions WHERE name = 'IMPERSONATE_USERS'));
@@ -0,0 +1,57 @@
+-- 014_fix_user_audit_logs.up.sql
+
+-- Migration 007 expects table_name, row_id, old_data and new_data columns that 003 never created.
+-- Audit rows must also outlive the users they mention, so user_id becomes nullable.
+ALTER TABLE user_audit_logs
+    DROP CONSTRAINT IF EXISTS user_audit_logs_user_id_fkey,
+    DROP CONSTRAINT IF EXISTS fk_user_audit_logs_user_id,
+    ALTER COLUMN user_id DROP NOT NULL,
+    ADD COLUMN table_name VARCHAR(255),
+    ADD COLUMN row_id     INT,
+    ADD COLUMN old_data   JSONB,
+    ADD COLUMN new_data   JSONB,
+    ADD COLUMN actor_id   INT,
+    ADD COLUMN target_id  INT,
+    ADD COLUMN ip_address VARCHAR(255),
+    ADD COLUMN user_agent TEXT;
+
+ALTER TABLE user_audit_logs
+    ADD CONSTRAINT fk_user_audit_logs_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL;
+
+-- Existing rows were written by the acting user
+UPDATE user_audit_logs SET actor_id = user_id WHERE actor_id IS NULL;
+
+CREATE INDEX idx_user_audit_logs_created_at ON user_audit_logs (created_at);
+CREATE INDEX idx_user_audit_logs_actor_id ON user_audit_logs (actor_id);
+CREATE INDEX idx_user_audit_logs_target_id ON user_audit_logs (target_id);
+CREATE INDEX idx_user_audit_logs_action ON user_audit_logs (action);
+
+-- Migrations 006 and 007 are not named *.up.sql so they were never applied, and trigger
+-- functions can't take row values as arguments anyway. Replace 007 with a working trigger.
+CREATE OR REPLACE FUNCTION audit_users_changes()
+    RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO user_audit_logs (user_id, action, table_name, row_id, old_data, new_data)
+        VALUES (NEW.id, 'INSERT', TG_TABLE_NAME, NEW.id, NULL, to_jsonb(NEW) - 'password');
+        RETURN NEW;
+    ELSIF TG_OP = 'UPDATE' THEN
+        INSERT INTO user_audit_logs (user_id, action, table_name, row_id, old_data, new_data)
+        VALUES (NEW.id, 'UPDATE', TG_TABLE_NAME, NEW.id, to_jsonb(OLD) - 'password', to_jsonb(NEW) - 'password');
+        RETURN NEW;
+    ELSE
+        INSERT INTO user_audit_logs (user_id, action, table_name, row_id, old_data, new_data)
+        VALUES (NULL, 'DELETE', TG_TABLE_NAME, OLD.id, to_jsonb(OLD) - 'password', NULL);
+        RETURN OLD;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_insert_trigger ON users;
+DROP TRIGGER IF EXISTS audit_update_trigger ON users;
+DROP TRIGGER IF EXISTS audit_delete_trigger ON users;
+
+CREATE TRIGGER audit_users_trigger
+    AFTER INSERT OR UPDATE OR DELETE ON users
+    FOR EACH ROW
+EXECUTE FUNCTION audit_users_changes();
@@ -0,0 +1,14 @@
+-- 015_add_audit_log_hash_chain.up.sql
+
+-- Each audit entry is sealed into an HMAC chain: hash = HMAC(key, prev_hash | content_digest | chain_seq).
+-- Rows are sealed by the application shortly after insertion, in chain_seq order.
+-- redacted_at marks entries whose PII was erased; their content is checked against the digest
+-- recorded for them by a later, sealed AUDIT_REDACTED entry.
+ALTER TABLE user_audit_logs
+    ADD COLUMN chain_seq      BIGINT UNIQUE,
+    ADD COLUMN content_digest CHAR(64),
+    ADD COLUMN prev_hash      CHAR(64),
+    ADD COLUMN hash           CHAR(64),
+    ADD COLUMN redacted_at    TIMESTAMP;
+
+CREATE INDEX idx_user_audit_logs_unsealed ON user_audit_logs (id) WHERE chain_seq IS NULL;
@@ -0,0 +1,47 @@
+-- 016_create_webhooks.up.sql
+
+-- Endpoints registered by admins to receive security events
+CREATE TABLE webhook_endpoints
+(
+    id          SERIAL PRIMARY KEY,
+    url         TEXT         NOT NULL,
+    secret      VARCHAR(255) NOT NULL, -- HMAC key used to sign payloads
+    event_types TEXT[]       NOT NULL, -- e.g. {'user.login', 'session.revoked'} or {'*'}
+    is_active   BOOLEAN   DEFAULT true,
+    created_by  INT,
+    created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+-- Transactional outbox: events are written in the same transaction as the change they describe
+CREATE TABLE event_outbox
+(
+    id            BIGSERIAL PRIMARY KEY,
+    event_type    VARCHAR(100) NOT NULL,
+    payload       JSONB        NOT NULL,
+    created_at    TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    dispatched_at TIMESTAMP -- set once deliveries were created for the matching endpoints
+);
+
+CREATE INDEX idx_event_outbox_undispatched ON event_outbox (id) WHERE dispatched_at IS NULL;
+
+-- One delivery per event and endpoint, retried with exponential backoff until delivered or dead
+CREATE TABLE webhook_deliveries
+(
+    id               BIGSERIAL PRIMARY KEY,
+    outbox_id        BIGINT      NOT NULL,
+    endpoint_id      INT         NOT NULL,
+    status           VARCHAR(20) DEFAULT 'PENDING', -- PENDING, DELIVERED, DEAD
+    attempts         INT         DEFAULT 0,
+    next_attempt_at  TIMESTAMP   DEFAULT CURRENT_TIMESTAMP,
+    last_status_code INT,
+    last_error       TEXT,
+    delivered_at     TIMESTAMP,
+    created_at       TIMESTAMP   DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (outbox_id, endpoint_id),
+    FOREIGN KEY (outbox_id) REFERENCES event_outbox (id) ON DELETE CASCADE,
+    FOREIGN KEY (endpoint_id) REFERENCES webhook_endpoints (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'PENDING';
@@ -0,0 +1,28 @@
+-- 017_cart_consistency.up.sql
+
+-- Merge duplicate lines so each product appears once per cart
+WITH merged AS (
+    SELECT MIN(id) AS keep_id, cart_id, product_id, SUM(quantity) AS quantity
+    FROM cart_items
+    GROUP BY cart_id, product_id
+    HAVING COUNT(*) > 1
+)
+UPDATE cart_items ci
+SET quantity = merged.quantity
+FROM merged
+WHERE ci.id = merged.keep_id;
+
+DELETE FROM cart_items ci
+USING cart_items other
+WHERE ci.cart_id = other.cart_id
+  AND ci.product_id = other.product_id
+  AND ci.id > other.id;
+
+ALTER TABLE cart_items
+    ADD CONSTRAINT uq_cart_items_cart_product UNIQUE (cart_id, product_id);
+
+-- Version is bumped on every change so clients can detect concurrent edits (If-Match)
+ALTER TABLE carts
+    ADD COLUMN version INT DEFAULT 0;
+
+CREATE INDEX idx_carts_user_id ON carts (user_id);
@@ -0,0 +1,27 @@
+-- 018_order_lifecycle.up.sql
+
+-- Only the states of the order state machine are allowed
+UPDATE orders SET status = UPPER(status) WHERE status IS NOT NULL;
+UPDATE orders SET payment_status = UPPER(payment_status) WHERE payment_status IS NOT NULL;
+
+ALTER TABLE orders
+    ALTER COLUMN status SET NOT NULL,
+    ADD CONSTRAINT chk_orders_status
+        CHECK (status IN ('PENDING', 'PAID', 'SHIPPED', 'COMPLETED', 'CANCELED', 'REFUNDED')),
+    ADD CONSTRAINT chk_orders_payment_status
+        CHECK (payment_status IN ('UNPAID', 'AUTHORIZED', 'PAID', 'REFUNDED', 'FAILED'));
+
+-- Every transition, for order history and disputes
+CREATE TABLE order_status_history (
+    id          SERIAL PRIMARY KEY,
+    order_id    INT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    from_status VARCHAR(50),
+    to_status   VARCHAR(50) NOT NULL,
+    changed_by  INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_orders_user_id ON orders (user_id, created_at DESC);
+CREATE INDEX idx_order_items_order_id ON order_items (order_id);
+CREATE INDEX idx_order_items_product_id ON order_items (product_id);
+CREATE INDEX idx_order_status_history_order_id ON order_status_history (order_id);
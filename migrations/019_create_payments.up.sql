@@ -0,0 +1,20 @@
+-- 019_create_payments.up.sql
+
+-- One row per charge or refund sent to the payment provider
+CREATE TABLE payments (
+    id              SERIAL PRIMARY KEY,
+    order_id        INT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    provider        VARCHAR(50) NOT NULL,
+    provider_ref    VARCHAR(255),
+    idempotency_key VARCHAR(255) NOT NULL UNIQUE, -- repeated requests with the same key are answered from this row
+    kind            VARCHAR(20) NOT NULL CHECK (kind IN ('CHARGE', 'REFUND')),
+    amount          DECIMAL(10, 2) NOT NULL,
+    payment_method  VARCHAR(50),
+    status          VARCHAR(20) NOT NULL CHECK (status IN ('PROCESSING', 'PENDING', 'SUCCEEDED', 'DECLINED', 'FAILED')),
+    error           TEXT,
+    created_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_payments_order_id ON payments (order_id);
+CREATE UNIQUE INDEX idx_payments_provider_ref ON payments (provider, provider_ref, kind) WHERE provider_ref IS NOT NULL;
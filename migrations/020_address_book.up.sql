@@ -0,0 +1,17 @@
+-- 020_address_book.up.sql
+
+-- Addresses referenced by orders are archived instead of deleted so order history keeps them
+ALTER TABLE addresses
+    ADD COLUMN is_default_shipping BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN is_default_billing BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN archived_at TIMESTAMP;
+
+CREATE INDEX idx_addresses_user_id ON addresses (user_id) WHERE archived_at IS NULL;
+
+-- At most one default of each kind per user
+CREATE UNIQUE INDEX uq_addresses_default_shipping ON addresses (user_id)
+    WHERE is_default_shipping AND archived_at IS NULL;
+CREATE UNIQUE INDEX uq_addresses_default_billing ON addresses (user_id)
+    WHERE is_default_billing AND archived_at IS NULL;
+
+CREATE INDEX idx_orders_shipping_address_id ON orders (shipping_address_id);
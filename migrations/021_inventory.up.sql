@@ -0,0 +1,24 @@
+-- 021_inventory.up.sql
+
+-- stock_quantity is on hand; reserved_quantity is held by unpaid orders
+ALTER TABLE products
+    ADD COLUMN stock_quantity INT NOT NULL DEFAULT 0,
+    ADD COLUMN reserved_quantity INT NOT NULL DEFAULT 0,
+    ADD COLUMN low_stock_threshold INT NOT NULL DEFAULT 5,
+    ADD CONSTRAINT chk_products_stock
+        CHECK (reserved_quantity >= 0 AND stock_quantity >= reserved_quantity AND low_stock_threshold >= 0);
+
+-- Stock held for an order until it is paid (COMMITTED) or canceled/expired (RELEASED)
+CREATE TABLE stock_reservations (
+    id         SERIAL PRIMARY KEY,
+    order_id   INT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    quantity   INT NOT NULL CHECK (quantity > 0),
+    status     VARCHAR(20) NOT NULL DEFAULT 'RESERVED' CHECK (status IN ('RESERVED', 'COMMITTED', 'RELEASED')),
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_stock_reservations_order_id ON stock_reservations (order_id);
+CREATE INDEX idx_stock_reservations_expires_at ON stock_reservations (expires_at) WHERE status = 'RESERVED';
@@ -0,0 +1,16 @@
+-- 022_product_search.up.sql
+
+-- Names weigh more than descriptions in ranking
+ALTER TABLE products
+    ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(name, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(description, '')), 'B')
+    ) STORED;
+
+CREATE INDEX idx_products_search_vector ON products USING GIN (search_vector);
+
+-- Category names are matched at query time since a generated column can't read another table
+CREATE INDEX idx_categories_search_vector ON categories USING GIN (to_tsvector('english', name));
+
+CREATE INDEX idx_products_price ON products (price);
+CREATE INDEX idx_products_category_id ON products (category_id);
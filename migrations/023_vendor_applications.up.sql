@@ -0,0 +1,20 @@
+-- 023_vendor_applications.up.sql
+
+-- Store profiles submitted by customers who want to sell; approval grants the Vendor role
+CREATE TABLE vendor_applications (
+    id            SERIAL PRIMARY KEY,
+    user_id       INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    store_name    VARCHAR(255) NOT NULL,
+    description   TEXT,
+    contact_email VARCHAR(255) NOT NULL,
+    status        VARCHAR(20) NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'APPROVED', 'REJECTED')),
+    reviewed_by   INT REFERENCES users(id) ON DELETE SET NULL,
+    review_note   TEXT,
+    reviewed_at   TIMESTAMP,
+    created_at    TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A user can only have one application waiting for review
+CREATE UNIQUE INDEX uq_vendor_applications_pending ON vendor_applications (user_id) WHERE status = 'PENDING';
+CREATE INDEX idx_vendor_applications_status ON vendor_applications (status, created_at);
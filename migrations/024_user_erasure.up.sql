@@ -0,0 +1,15 @@
+-- 024_user_erasure.up.sql
+
+-- Right-to-erasure requests. Accounts are anonymized once scheduled_for passes rather than deleted,
+-- since orders and their addresses must survive for accounting.
+CREATE TABLE user_erasure_requests (
+    user_id       INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    requested_by  INT REFERENCES users(id) ON DELETE SET NULL,
+    requested_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    scheduled_for TIMESTAMP NOT NULL,
+    erased_at     TIMESTAMP
+);
+
+CREATE INDEX idx_user_erasure_requests_due ON user_erasure_requests (scheduled_for) WHERE erased_at IS NULL;
+
+CREATE INDEX idx_user_sessions_user_id ON user_sessions (user_id);
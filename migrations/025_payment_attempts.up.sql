@@ -0,0 +1,9 @@
+-- 025_payment_attempts.up.sql
+
+-- Declined and failed charges are attempted again under the same idempotency key, so each
+-- attempt needs its own provider keys; REFUNDED charges were given back and are never reopened
+ALTER TABLE payments ADD COLUMN attempt INT NOT NULL DEFAULT 1;
+
+ALTER TABLE payments DROP CONSTRAINT payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check
+    CHECK (status IN ('PROCESSING', 'PENDING', 'SUCCEEDED', 'DECLINED', 'FAILED', 'REFUNDED'));